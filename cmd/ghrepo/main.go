@@ -1,26 +1,23 @@
 package main
 
 import (
-	"fmt"
 	"os"
 
 	"githubRAGCli/internal/cli"
-	clerrors "githubRAGCli/internal/exitcode"
 	"githubRAGCli/internal/output"
 )
 
 func main() {
 	root := cli.NewRootCmd()
-	if err := root.Execute(); err != nil {
+	err := root.Execute()
+	if cerr := cli.CloseLog(); cerr != nil && err == nil {
+		err = cerr
+	}
+	if err != nil {
 		// Print the error via the unified output path.
-		asJSON := cli.JSONFlag()
-		output.PrintError(os.Stderr, err.Error(), asJSON)
-
-		// Map CLIError to its exit code; default to 1 for unknown errors.
-		if ce, ok := err.(*clerrors.CLIError); ok {
-			os.Exit(ce.ExitCode())
-		}
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		// NewErrorData also maps CLIError to its exit code, defaulting to 1.
+		e := output.NewErrorData(err)
+		output.PrintError(os.Stderr, e, cli.OutputFormat())
+		os.Exit(e.ExitCode)
 	}
 }
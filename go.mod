@@ -2,9 +2,14 @@ module githubRAGCli
 
 go 1.22.6
 
-require github.com/spf13/cobra v1.10.2
+require (
+	github.com/itchyny/gojq v0.12.17
+	github.com/spf13/cobra v1.10.2
+	golang.org/x/crypto v0.33.0
+)
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/itchyny/timefmt-go v0.1.6 // indirect
 	github.com/spf13/pflag v1.0.9 // indirect
 )
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"githubRAGCli/internal/config"
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+func TestRepoArgument(t *testing.T) {
+	root := t.TempDir()
+	saved := &config.Project{Path: filepath.Join(root, config.ProjectFileName), Repo: "octo/saved"}
+	if err := saved.Save(); err != nil {
+		t.Fatal(err)
+	}
+	deep := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(deep, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	active := &config.Project{Repo: "octo/active"}
+
+	tests := []struct {
+		name        string
+		args        []string
+		project     *config.Project
+		dir         string
+		want        string
+		wantProject string // repo of the project returned, "" for none
+		wantErr     bool
+	}{
+		{name: "repo and path", args: []string{"octo/hello", "README.md"}, dir: deep, want: "octo/hello"},
+		{name: "url", args: []string{"https://github.com/octo/hello/blob/main/a.md"}, dir: deep, want: "https://github.com/octo/hello/blob/main/a.md"},
+		{name: "project file in a parent", args: []string{"README.md"}, dir: deep, want: "octo/saved", wantProject: "octo/saved"},
+		{name: "project already loaded", args: []string{"README.md"}, project: active, dir: deep, want: "octo/active", wantProject: "octo/active"},
+		{name: "no project file", args: []string{"README.md"}, dir: t.TempDir(), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, p, err := repoArgument(tt.args, tt.project, tt.dir)
+			if tt.wantErr {
+				var ce *clerrors.CLIError
+				if !errors.As(err, &ce) || ce.Cat != clerrors.CatBadArgs {
+					t.Fatalf("got %q, %v; want a bad-args error", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("repo = %q, want %q", got, tt.want)
+			}
+			var gotProject string
+			if p != nil {
+				gotProject = p.Repo
+			}
+			if gotProject != tt.wantProject {
+				t.Errorf("project = %q, want %q", gotProject, tt.wantProject)
+			}
+		})
+	}
+}
+
+func TestTargetArgs(t *testing.T) {
+	project := &config.Project{Repo: "ghe.example.com/octo/hello", PathPrefix: "docs"}
+	profile := &config.Profile{Owner: "acme"}
+
+	tests := []struct {
+		name    string
+		args    []string
+		repoArg string
+		project *config.Project
+		profile *config.Profile
+		want    [3]string
+		wantErr bool
+	}{
+		{name: "repo and path", args: []string{"octo/hello", "a/b.md"}, repoArg: "octo/hello", want: [3]string{"octo", "hello", "a/b.md"}},
+		{name: "host stripped", args: []string{"ghe.example.com/octo/hello", "b.md"}, repoArg: "ghe.example.com/octo/hello", want: [3]string{"octo", "hello", "b.md"}},
+		{name: "owner from profile", args: []string{"hello", "b.md"}, repoArg: "hello", profile: profile, want: [3]string{"acme", "hello", "b.md"}},
+		{name: "bare repo without profile", args: []string{"hello", "b.md"}, repoArg: "hello", wantErr: true},
+		{name: "path prefix", args: []string{"guide.md"}, repoArg: project.Repo, project: project, want: [3]string{"octo", "hello", "docs/guide.md"}},
+		{name: "leading slash skips prefix", args: []string{"/README.md"}, repoArg: project.Repo, project: project, want: [3]string{"octo", "hello", "README.md"}},
+		{name: "root with leading slash", args: []string{"/"}, repoArg: project.Repo, project: project, want: [3]string{"octo", "hello", "."}},
+		{name: "prefix only without repo argument", args: []string{"octo/hello", "guide.md"}, repoArg: "octo/hello", project: project, want: [3]string{"octo", "hello", "guide.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owner, repo, path, err := targetArgs(tt.args, tt.repoArg, tt.project, tt.profile)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %s/%s %s, want error", owner, repo, path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := [3]string{owner, repo, path}; got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectProfile(t *testing.T) {
+	f := &config.File{
+		DefaultProfile: "work",
+		Profiles: map[string]*config.Profile{
+			"work":   {Name: "work", Host: "ghe.example.com", APIBase: "https://ghe.example.com/api/v3"},
+			"public": {Name: "public", APIBase: "https://api.github.com"},
+			"other":  {Name: "other", Host: "git.example.org"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		profile     string
+		hostname    string
+		repoArg     string
+		wantProfile string // "" for none
+		wantAPIBase string
+		wantErr     bool
+	}{
+		{name: "default profile", wantProfile: "work", wantAPIBase: "https://ghe.example.com/api/v3"},
+		{name: "named profile", profile: "other", wantProfile: "other", wantAPIBase: "https://git.example.org/api/v3"},
+		{name: "hostname selects profile", hostname: "github.com", wantProfile: "public", wantAPIBase: "https://api.github.com"},
+		{name: "repo host selects profile", repoArg: "git.example.org/octo/hello", wantProfile: "other", wantAPIBase: "https://git.example.org/api/v3"},
+		{name: "url host selects profile", repoArg: "https://ghe.example.com/octo/hello", wantProfile: "work", wantAPIBase: "https://ghe.example.com/api/v3"},
+		{name: "hostname without profile", hostname: "new.example.com", wantAPIBase: "https://new.example.com/api/v3"},
+		{name: "hostname agrees with url", hostname: "ghe.example.com", repoArg: "https://ghe.example.com/octo/hello", wantProfile: "work", wantAPIBase: "https://ghe.example.com/api/v3"},
+		{name: "hostname conflicts with url", hostname: "github.com", repoArg: "https://ghe.example.com/octo/hello", wantErr: true},
+		{name: "named profile for another host", profile: "work", hostname: "git.example.org", wantProfile: "work", wantAPIBase: "https://git.example.org/api/v3"},
+		{name: "repo without host", repoArg: "octo/hello", wantProfile: "work", wantAPIBase: "https://ghe.example.com/api/v3"},
+		{name: "unknown profile", profile: "nope", wantErr: true},
+		{name: "invalid hostname", hostname: "ghe.example.com/x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, apiBase, err := selectProfile(f, tt.profile, tt.hostname, tt.repoArg)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, %q; want error", p, apiBase)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gotProfile string
+			if p != nil {
+				gotProfile = p.Name
+			}
+			if gotProfile != tt.wantProfile || apiBase != tt.wantAPIBase {
+				t.Errorf("got %q, %q; want %q, %q", gotProfile, apiBase, tt.wantProfile, tt.wantAPIBase)
+			}
+		})
+	}
+}
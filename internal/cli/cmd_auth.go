@@ -1,52 +1,370 @@
 package cli
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"githubRAGCli/internal/config"
+	"githubRAGCli/internal/credstore"
 	clerrors "githubRAGCli/internal/exitcode"
 	"githubRAGCli/internal/githubapi"
 	"githubRAGCli/internal/output"
+	"githubRAGCli/internal/service"
 )
 
+// defaultOAuthClientID is the OAuth App used by auth login when no client ID
+// is configured. Release builds set it from GHREPO_OAUTH_CLIENT_ID through
+// the ldflags in .goreleaser.yml.
+var defaultOAuthClientID = ""
+
 func newAuthCmd() *cobra.Command {
 	auth := &cobra.Command{
 		Use:   "auth",
 		Short: "Authentication commands",
 	}
 	auth.AddCommand(newAuthCheckCmd())
+	auth.AddCommand(newAuthLoginCmd())
+	auth.AddCommand(newAuthLogoutCmd())
+	auth.AddCommand(newAuthStatusCmd())
+	auth.AddCommand(newAuthTokenCmd())
 	return auth
 }
 
 func newAuthCheckCmd() *cobra.Command {
 	return &cobra.Command{
-		Use:   "check",
+		Use:   "check [<owner/repo>]",
 		Short: "Verify that the configured token is valid",
-		RunE:  runAuthCheck,
+		Long: `Verify that the configured token is valid and report what it can do:
+its type, the scopes of a classic or OAuth token (X-OAuth-Scopes) and the
+expiry of a token that expires.
+
+Given <owner/repo>, also report the permission level on that repository
+(admin, maintain, write, triage, read, or none when the token cannot see
+it) and whether its default branch is protected. Each problem found is
+printed as a hint naming the commands that will fail; an invisible
+repository exits with code 12.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runAuthCheck,
 	}
 }
 
 func runAuthCheck(cmd *cobra.Command, args []string) error {
 	cfg := resolveConfig()
 
-	if cfg.Token == "" {
-		return clerrors.NewAuthFailure("no token provided: use --token, GITHUB_TOKEN, or GH_TOKEN", nil)
+	if err := requireToken(&cfg); err != nil {
+		return err
 	}
 
-	verboseLog(cfg, "api-base: %s", cfg.APIBase)
-	verboseLog(cfg, "timeout: %s", cfg.Timeout)
 	// Token is intentionally never logged.
+	slog.Debug("auth check", "profile", cfg.Profile, "api_base", cfg.APIBase, "timeout", cfg.Timeout)
 
-	client := githubapi.NewClient(cfg.APIBase, cfg.Token, cfg.Timeout)
-	result, err := client.GetAuthenticatedUser()
+	var owner, repo string
+	if len(args) == 1 {
+		_, ownerRepo := splitRepoHost(args[0])
+		if !strings.Contains(ownerRepo, "/") && activeProfile != nil && activeProfile.Owner != "" {
+			ownerRepo = activeProfile.Owner + "/" + ownerRepo
+		}
+		var err error
+		if owner, repo, err = githubapi.ParseRepo(ownerRepo); err != nil {
+			return err
+		}
+	}
+
+	client := newClient(cfg, cfg.Token)
+	client.Budget = activeBudget
+	client.Server = serverInfo(cfg)
+	getIdentity := client.GetAuthenticatedUser
+	report := service.AccessReport{TokenType: githubapi.TokenType(cfg.Token)}
+	if activeApp != nil {
+		// Installation tokens cannot call GET /user.
+		activeApp.Owner, activeApp.Repo = owner, repo
+		client.App = activeApp
+		getIdentity = client.GetAuthenticatedApp
+		report.TokenType = githubapi.TokenInstallation
+	}
+	result, err := getIdentity()
 	if err != nil {
 		return err
 	}
+	report.Scopes = result.OAuthScopes
+	report.ExpiresAt = result.TokenExpiration
+	if repo != "" {
+		if report.Repo, err = client.GetRepoAccess(owner, repo); err != nil {
+			return err
+		}
+	}
 
-	return output.PrintAuth(os.Stdout, output.AuthResult{
+	r := output.AuthResult{
 		Status:             "ok",
 		User:               result.Login,
 		RateLimitRemaining: result.RateLimitRemaining,
-	}, cfg.JSON)
+		TokenType:          report.TokenType,
+		Scopes:             report.Scopes,
+		Hints:              report.Hints(time.Now()),
+		Server:             enterpriseServer(client.Server),
+	}
+	if !report.ExpiresAt.IsZero() {
+		r.ExpiresAt = report.ExpiresAt.Format(time.RFC3339)
+	}
+	if a := report.Repo; a != nil {
+		r.Repository = &output.RepoAccessData{
+			Name:          a.Repository,
+			Permission:    a.Permission,
+			DefaultBranch: a.DefaultBranch,
+			Protected:     a.Protected,
+		}
+	}
+	if err := output.PrintAuth(os.Stdout, r, outFormat); err != nil {
+		return err
+	}
+	if report.Repo != nil && report.Repo.Permission == githubapi.PermNone {
+		return clerrors.NewNotFound("repository "+report.Repo.Repository+" is not visible to this token", nil)
+	}
+	return nil
+}
+
+func newAuthLoginCmd() *cobra.Command {
+	var (
+		flagClientID string
+		flagScopes   []string
+		flagStorage  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "login",
+		Short: "Log in with the OAuth device flow and store the token",
+		Long: `Log in through the browser with the OAuth device flow.
+
+ghrepo prints a one-time code to enter at the host's device login page,
+waits for approval, and stores the token for the host: in the system
+keyring when one is available, else encrypted in credentials.json next to
+the config file. Commands then use it when no --token, token command,
+profile token, GITHUB_TOKEN or GH_TOKEN is set.
+
+File storage is obfuscation only unless GHREPO_CREDENTIALS_PASSPHRASE is
+set: the encryption key is kept in credentials.key in the same directory,
+so anyone who can read that directory can recover the token. With the
+passphrase set, the key is derived from it and never written to disk.
+
+The OAuth App is chosen with --client-id, GHREPO_OAUTH_CLIENT_ID or the
+profile's oauth_client_id, and defaults to the project's OAuth App in
+release builds; it must have the device flow enabled.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := resolveConfig()
+			host, webURL := authTarget(cfg)
+			storage := flagStorage
+			switch storage {
+			case "auto":
+				storage = credstore.StorageAuto
+			case credstore.StorageKeyring, credstore.StorageFile:
+			default:
+				return clerrors.NewBadArgs(fmt.Sprintf("--storage must be auto, keyring or file, got %q", flagStorage), nil)
+			}
+			clientID := flagClientID
+			for _, v := range []string{os.Getenv("GHREPO_OAUTH_CLIENT_ID"), profileOAuthClientID(), defaultOAuthClientID} {
+				if clientID == "" {
+					clientID = v
+				}
+			}
+			if clientID == "" {
+				return clerrors.NewBadArgs("no OAuth client ID: pass --client-id, or set GHREPO_OAUTH_CLIENT_ID or the profile's oauth_client_id", nil)
+			}
+			dir := config.CredentialsDir()
+			if dir == "" {
+				return clerrors.NewLocalWriteErr("cannot locate config directory: set GHREPO_CONFIG", nil)
+			}
+			slog.Debug("login", "host", host, "web", webURL, "api", cfg.APIBase)
+			server := serverInfo(cfg)
+			if err := server.Require(githubapi.FeatureDeviceFlow); err != nil {
+				return clerrors.NewBadArgs(err.Error()+": create a token on the host instead", nil)
+			}
+
+			flow := &githubapi.DeviceFlow{
+				WebURL:     webURL,
+				ClientID:   clientID,
+				Scopes:     flagScopes,
+				HTTPClient: httpClient(cfg),
+			}
+			dc, err := flow.Start()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "First copy your one-time code: %s\nThen open %s in your browser and enter it.\nWaiting for authorization...\n", dc.UserCode, dc.VerificationURI)
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			token, err := flow.Wait(ctx, dc)
+			if err != nil {
+				return err
+			}
+
+			client := newClient(cfg, token.AccessToken)
+			client.Server = server
+			user, err := client.GetAuthenticatedUser()
+			if err != nil {
+				return err
+			}
+			saved, err := credstore.New(dir).Save(credstore.Credential{
+				Host:      host,
+				User:      user.Login,
+				Token:     token.AccessToken,
+				Scopes:    token.Scope,
+				CreatedAt: time.Now().UTC(),
+			}, storage)
+			if err != nil {
+				return err
+			}
+			if saved.Storage == credstore.StorageFile && os.Getenv(credstore.PassphraseEnv) == "" {
+				fmt.Fprintf(os.Stderr, "warning: the token is stored in %s with its key in %s, which only obfuscates it; set %s to encrypt it with a passphrase\n", credstore.FileName, credstore.KeyFileName, credstore.PassphraseEnv)
+			}
+
+			// Warn when an earlier token source will shadow the new login.
+			if _, source, err := config.FindToken(config.TokenOptions{
+				Command: flagTokenCommand,
+				Profile: activeProfile,
+				APIBase: cfg.APIBase,
+			}); err == nil && source != "auth login" {
+				fmt.Fprintf(os.Stderr, "note: the token from %s takes precedence over this login\n", source)
+			}
+			return output.PrintLogin(os.Stdout, credentialData(saved), outFormat)
+		},
+	}
+
+	cmd.Flags().StringVar(&flagClientID, "client-id", "", "OAuth App client ID")
+	cmd.Flags().StringSliceVar(&flagScopes, "scopes", []string{"repo"}, "OAuth scopes to request")
+	cmd.Flags().StringVar(&flagStorage, "storage", "auto", "Where to store the token: auto, keyring or file")
+	return cmd
+}
+
+func newAuthLogoutCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "logout",
+		Short: "Remove the token stored by auth login",
+		Long: `Remove the token stored by auth login for a host. The token is not
+revoked; revoke it under Settings > Applications on the host if needed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := resolveConfig()
+			host, _ := authTarget(cfg)
+			removed, err := credstore.New(config.CredentialsDir()).Delete(host)
+			if err != nil {
+				return err
+			}
+			if !removed {
+				return clerrors.NewNotFound("not logged in to "+host, nil)
+			}
+			if outFormat.IsText() {
+				fmt.Fprintf(os.Stdout, "logged out of %s\n", host)
+				return nil
+			}
+			return output.Render(os.Stdout, outFormat, map[string]string{"host": host, "status": "logged_out"})
+		},
+	}
+}
+
+func newAuthStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show stored logins and where the token for a host comes from",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := resolveConfig()
+			host, _ := authTarget(cfg)
+			status := output.AuthStatusData{Host: host, TokenSource: cfg.TokenSource}
+			if cfg.AppID != "" {
+				status.TokenSource = "GitHub App " + cfg.AppID
+			} else if status.TokenSource == "" {
+				_, source, err := config.FindToken(config.TokenOptions{
+					Command: flagTokenCommand,
+					Profile: activeProfile,
+					APIBase: cfg.APIBase,
+				})
+				if err != nil {
+					return err
+				}
+				status.TokenSource = source
+			}
+
+			logins, err := credstore.New(config.CredentialsDir()).List()
+			if err != nil {
+				return err
+			}
+			for _, c := range logins {
+				status.Logins = append(status.Logins, credentialData(c))
+			}
+			return output.PrintAuthStatus(os.Stdout, status, outFormat)
+		},
+	}
+}
+
+func newAuthTokenCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "token",
+		Short: "Print the token ghrepo would use for a host",
+		Long: `Print the token ghrepo would use for a host, from the same sources as
+every other command, for use in scripts: GH_TOKEN=$(ghrepo auth token).
+With GitHub App authentication it prints an installation token.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := resolveConfig()
+			if err := requireToken(&cfg); err != nil {
+				return err
+			}
+			token := cfg.Token
+			if activeApp != nil {
+				client := newClient(cfg, "")
+				client.App = activeApp
+				client.Server = serverInfo(cfg)
+				var err error
+				if token, err = client.InstallationToken(); err != nil {
+					return err
+				}
+			}
+			fmt.Fprintln(os.Stdout, token)
+			return nil
+		},
+	}
+}
+
+// authTarget returns the host the auth subcommands act on and the web URL
+// of its login pages, both derived from the API base that --hostname, the
+// profile or --api-base selected.
+func authTarget(cfg config.Config) (host, webURL string) {
+	return config.HostFromAPIBase(cfg.APIBase), githubapi.APIEndpoints(cfg.APIBase).Web
+}
+
+// profileOAuthClientID returns the active profile's OAuth client ID, if any.
+func profileOAuthClientID() string {
+	if activeProfile == nil {
+		return ""
+	}
+	return activeProfile.OAuthClientID
+}
+
+// credentialData converts a stored login for output.
+func credentialData(c credstore.Credential) output.CredentialData {
+	return output.CredentialData{
+		Host:      c.Host,
+		User:      c.User,
+		Storage:   c.Storage,
+		Scopes:    c.Scopes,
+		CreatedAt: c.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// enterpriseServer names a GitHub Enterprise Server for output; it is empty
+// for GitHub.com.
+func enterpriseServer(s *githubapi.ServerInfo) string {
+	if s == nil || !s.Enterprise {
+		return ""
+	}
+	return s.String()
 }
@@ -1,35 +1,54 @@
 package cli
 
 import (
+	"log/slog"
 	"os"
 
 	"github.com/spf13/cobra"
+
+	"githubRAGCli/internal/service"
 )
 
 func newCatCmd() *cobra.Command {
-	var flagRef string
+	var (
+		flagRef       string
+		flagFollow    bool
+		flagSubmodule bool
+	)
 
 	cmd := &cobra.Command{
-		Use:   "cat <owner/repo> <path>",
+		Use:   "cat [<owner/repo>] <path>",
 		Short: "Output file content to stdout",
-		Args:  cobra.ExactArgs(2),
+		Args:  cobra.RangeArgs(1, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg := resolveConfig()
 
-			if err := requireToken(cfg); err != nil {
+			if err := requireToken(&cfg); err != nil {
 				return err
 			}
 
-			owner, repo, err := parseRepoArg(args)
+			owner, repo, path, err := parseTargetArgs(cfg, args)
 			if err != nil {
 				return err
 			}
-			path := args[1]
+			flagRef = defaultRef(flagRef)
 
-			verboseLog(cfg, "cat %s/%s %s (ref=%s)", owner, repo, path, flagRef)
+			slog.Debug("cat", "repo", owner+"/"+repo, "path", path, "ref", flagRef, "follow", flagFollow, "recurse_submodules", flagSubmodule)
 
 			svc := newService(cfg, owner, repo)
-			data, err := svc.ReadFile(flagRef, path)
+			ref := flagRef
+			if flagFollow || flagSubmodule {
+				svc, ref, path, err = svc.Resolve(ref, path, service.ResolveOptions{
+					FollowSymlinks: flagFollow,
+					IntoSubmodules: flagSubmodule,
+				})
+				if err != nil {
+					return err
+				}
+				slog.Info("resolved", "repo", svc.Owner+"/"+svc.Repo, "path", path, "ref", ref)
+			}
+
+			data, err := svc.ReadFile(ref, path)
 			if err != nil {
 				return err
 			}
@@ -40,6 +59,9 @@ func newCatCmd() *cobra.Command {
 	}
 
 	cmd.Flags().StringVar(&flagRef, "ref", "", "Git ref (branch, tag, or SHA)")
+	cmd.Flags().BoolVar(&flagFollow, "follow", false, "Follow symlinks, including symlinked parent directories")
+	cmd.Flags().BoolVar(&flagSubmodule, "recurse-submodules", false, "Read paths inside submodules at their pinned commit")
 
+	markRepoArg(cmd)
 	return cmd
 }
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"githubRAGCli/internal/config"
+	clerrors "githubRAGCli/internal/exitcode"
+	"githubRAGCli/internal/output"
+)
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Read and write the config file and its profiles",
+		Long: `Read and write the ghrepo config file ($GHREPO_CONFIG, or
+$XDG_CONFIG_HOME/ghrepo/config.yaml, default ~/.config/ghrepo/config.yaml).
+
+Keys are "default_profile" or "<profile>.<setting>". A bare "<setting>"
+refers to the profile chosen by --profile or GHREPO_PROFILE, else the
+default profile. Settings: ` + strings.Join(config.ProfileKeys(), ", "),
+		// Unlike other commands, config does not select a profile, so it
+		// can create one that --profile names.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			f, err := config.LoadFile(config.FilePath())
+			if err != nil {
+				return err
+			}
+			configFile = f
+			return parseFormatFlags()
+		},
+	}
+	cmd.AddCommand(newConfigGetCmd())
+	cmd.AddCommand(newConfigSetCmd())
+	cmd.AddCommand(newConfigListCmd())
+	return cmd
+}
+
+func newConfigGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print a config value",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			value, ok, err := configFile.Get(args[0], currentProfileName())
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return clerrors.NewNotFound(fmt.Sprintf("config key %q is not set", args[0]), nil)
+			}
+			if outFormat.IsText() {
+				fmt.Fprintln(os.Stdout, value)
+				return nil
+			}
+			return output.Render(os.Stdout, outFormat, output.SettingData{Key: args[0], Value: value})
+		},
+	}
+}
+
+func newConfigSetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: `Set a config value ("" clears it)`,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, value := args[0], args[1]
+			if key == "format" || strings.HasSuffix(key, ".format") {
+				if _, err := output.ParseFormat(value); err != nil {
+					return clerrors.NewBadArgs(err.Error(), nil)
+				}
+			}
+			if err := configFile.Set(key, value, currentProfileName()); err != nil {
+				return err
+			}
+			return configFile.Save()
+		},
+	}
+}
+
+func newConfigListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List all config values (tokens are redacted)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var settings []output.SettingData
+			for _, s := range configFile.List() {
+				settings = append(settings, output.SettingData{Key: s.Key, Value: s.Value})
+			}
+			return output.PrintSettings(os.Stdout, settings, outFormat)
+		},
+	}
+}
+
+// currentProfileName is the profile a bare config key refers to.
+func currentProfileName() string {
+	if flagProfile != "" {
+		return flagProfile
+	}
+	if name := os.Getenv("GHREPO_PROFILE"); name != "" {
+		return name
+	}
+	if configFile.DefaultProfile != "" {
+		return configFile.DefaultProfile
+	}
+	return config.DefaultProfileName
+}
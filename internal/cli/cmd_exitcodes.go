@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+// newExitCodesTopic returns the "exit-codes" help topic, shown by
+// "ghrepo help exit-codes". It has no Run, so cobra lists it under
+// additional help topics instead of commands.
+func newExitCodesTopic() *cobra.Command {
+	return &cobra.Command{
+		Use:   "exit-codes",
+		Short: "Exit codes and error categories",
+		Long:  exitCodesHelp(),
+	}
+}
+
+// exitCodesHelp renders the exit code table from the exitcode package.
+func exitCodesHelp() string {
+	var b strings.Builder
+	b.WriteString("ghrepo exits with one of these codes. With --json, errors on stderr\n")
+	b.WriteString("carry the same code in \"exit_code\" and the name in \"category\".\n\n")
+	fmt.Fprintf(&b, "  %-4s  %-20s  %s\n", "CODE", "CATEGORY", "MEANING")
+	for _, c := range clerrors.Codes() {
+		category := c.Category
+		if category == "" {
+			category = "-"
+		}
+		fmt.Fprintf(&b, "  %-4d  %-20s  %s\n", c.Code, category, c.Description)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
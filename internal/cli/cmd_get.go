@@ -1,12 +1,17 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	clerrors "githubRAGCli/internal/exitcode"
+	"githubRAGCli/internal/output"
+	"githubRAGCli/internal/service"
 )
 
 func newGetCmd() *cobra.Command {
@@ -14,37 +19,101 @@ func newGetCmd() *cobra.Command {
 		flagRef       string
 		flagOut       string
 		flagOverwrite bool
+		flagSubmodule bool
+		flagResume    bool
+		flagManifest  string
+		flagTimes     bool
+		flagFollow    bool
 	)
 
 	cmd := &cobra.Command{
-		Use:   "get <owner/repo> <path>",
+		Use:   "get [<owner/repo>] <path>",
 		Short: "Download a file or directory to the local filesystem",
-		Args:  cobra.ExactArgs(2),
+		Args:  cobra.RangeArgs(1, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg := resolveConfig()
 
-			if err := requireToken(cfg); err != nil {
+			if err := requireToken(&cfg); err != nil {
 				return err
 			}
 
-			owner, repo, err := parseRepoArg(args)
+			owner, repo, path, err := parseTargetArgs(cfg, args)
 			if err != nil {
 				return err
 			}
-			path := args[1]
+			flagRef = defaultRef(flagRef)
 
 			if flagOut == "" {
 				return clerrors.NewBadArgs("--out is required", nil)
 			}
 
-			verboseLog(cfg, "get %s/%s %s -> %s (ref=%s, overwrite=%v)", owner, repo, path, flagOut, flagRef, flagOverwrite)
+			slog.Debug("get", "repo", owner+"/"+repo, "path", path, "out", flagOut, "ref", flagRef, "overwrite", flagOverwrite, "recurse_submodules", flagSubmodule, "resume", flagResume, "follow", flagFollow)
 
 			svc := newService(cfg, owner, repo)
-			if err := svc.Download(flagRef, path, flagOut, flagOverwrite); err != nil {
+
+			// Pin the download to a commit so the manifest describes exactly what was fetched.
+			ref := flagRef
+			if flagManifest != "" {
+				commit, err := svc.ResolveCommit(flagRef)
+				if err != nil {
+					return err
+				}
+				slog.Info("resolved ref", "ref", flagRef, "commit", commit)
+				ref = commit
+			}
+
+			opts := service.DownloadOptions{
+				Overwrite:         flagOverwrite,
+				RecurseSubmodules: flagSubmodule,
+				Resume:            flagResume,
+				LastModified:      flagManifest != "",
+				PreserveTimes:     flagTimes,
+				Follow:            flagFollow,
+			}
+			// Structured formats list the files written; NDJSON as they are written.
+			stream := output.NewStream(os.Stdout, outFormat)
+			if output.Streams(outFormat) {
+				opts.OnEntry = func(f service.ManifestFile) {
+					stream.Add(downloadedToOutput(f))
+				}
+			}
+			result, err := svc.DownloadWithOptions(ref, path, flagOut, opts)
+			var ce *clerrors.CLIError
+			if activeBudget != nil && errors.As(err, &ce) && ce.Cat == clerrors.CatRateLimit {
+				fmt.Fprintf(os.Stderr, "download stopped after %d requests; rerun with --resume to continue\n", activeBudget.Requests())
+			}
+			if err != nil {
 				return err
 			}
 
+			for _, sm := range result.Submodules {
+				if !sm.Downloaded && !flagSubmodule {
+					fmt.Fprintf(os.Stderr, "submodule %s at %s not downloaded (use --recurse-submodules)\n", sm.Path, sm.SHA)
+				}
+			}
+			if result.Resumed > 0 {
+				fmt.Fprintf(os.Stderr, "resumed: %d files already downloaded\n", result.Resumed)
+			}
+			slog.Info("verified files against their blob SHA", "files", result.Verified)
+
+			if flagManifest != "" {
+				m := service.NewManifest(owner+"/"+repo, flagRef, ref, path, flagManifest, result)
+				if err := service.WriteManifest(flagManifest, m); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "manifest written to %s\n", flagManifest)
+			}
 			fmt.Fprintf(os.Stderr, "downloaded to %s\n", flagOut)
+			switch {
+			case output.Streams(outFormat):
+				return stream.Err()
+			case !outFormat.IsText():
+				files := make([]output.DownloadedData, 0, len(result.Entries))
+				for _, f := range result.Entries {
+					files = append(files, downloadedToOutput(f))
+				}
+				return output.PrintDownloaded(os.Stdout, files, outFormat)
+			}
 			return nil
 		},
 	}
@@ -52,6 +121,29 @@ func newGetCmd() *cobra.Command {
 	cmd.Flags().StringVar(&flagRef, "ref", "", "Git ref (branch, tag, or SHA)")
 	cmd.Flags().StringVar(&flagOut, "out", "", "Local output path (required)")
 	cmd.Flags().BoolVar(&flagOverwrite, "overwrite", false, "Overwrite existing files")
+	cmd.Flags().BoolVar(&flagSubmodule, "recurse-submodules", false, "Download submodules at their pinned commit")
+	cmd.Flags().BoolVar(&flagResume, "resume", false, "Resume an interrupted download, skipping files already written")
+	cmd.Flags().StringVar(&flagManifest, "manifest", "", "Write a provenance manifest (JSON) to this path")
+	cmd.Flags().BoolVar(&flagFollow, "follow", false, "Download symlink targets instead of recreating the links")
+	cmd.Flags().BoolVar(&flagTimes, "preserve-times", false, "Set file modification times to the date of the last commit touching each file")
 
+	markRepoArg(cmd)
 	return cmd
 }
+
+// downloadedToOutput converts a downloaded file to an output.DownloadedData.
+func downloadedToOutput(f service.ManifestFile) output.DownloadedData {
+	d := output.DownloadedData{
+		Type:       f.Type,
+		RemotePath: f.RemotePath,
+		LocalPath:  f.LocalPath,
+		SHA:        f.SHA,
+		Size:       f.Size,
+		Mode:       f.Mode,
+		Target:     f.Target,
+	}
+	if f.LastModified != nil {
+		d.LastModified = f.LastModified.Format(time.RFC3339)
+	}
+	return d
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"githubRAGCli/internal/config"
 	clerrors "githubRAGCli/internal/exitcode"
 	"githubRAGCli/internal/githubapi"
 )
@@ -70,12 +71,19 @@ Keep this managed block so 'openspec update' can refresh the instructions.
 <!-- OPENSPEC:END -->`
 
 func newInitCmd() *cobra.Command {
+	var (
+		flagRef        string
+		flagBranch     string
+		flagPathPrefix string
+	)
+
 	cmd := &cobra.Command{
 		Use:   "init <owner/repo>",
-		Short: "Initialize AGENTS.md and CLAUDE.md with repository and project configuration",
+		Short: "Initialize AGENTS.md, CLAUDE.md and .ghrepo.yaml with repository and project configuration",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			owner, repo, err := githubapi.ParseRepo(args[0])
+			_, ownerRepo := splitRepoHost(args[0])
+			owner, repo, err := githubapi.ParseRepo(ownerRepo)
 			if err != nil {
 				return err
 			}
@@ -144,9 +152,41 @@ func newInitCmd() *cobra.Command {
 				}
 			}
 
+			// Handle .ghrepo.yaml
+			projectPath := filepath.Join(cwd, config.ProjectFileName)
+			if _, err := os.Stat(projectPath); err == nil {
+				var ignored []string
+				for _, name := range []string{"ref", "branch", "path-prefix"} {
+					if cmd.Flags().Changed(name) {
+						ignored = append(ignored, "--"+name)
+					}
+				}
+				if len(ignored) > 0 {
+					fmt.Fprintf(os.Stderr, "%s already exists, ignoring %s; edit it to change the defaults\n", config.ProjectFileName, strings.Join(ignored, ", "))
+				} else {
+					fmt.Fprintf(os.Stderr, "%s already exists, skipping\n", config.ProjectFileName)
+				}
+				return nil
+			}
+			project := &config.Project{
+				Path:       projectPath,
+				Repo:       args[0],
+				Ref:        flagRef,
+				Branch:     flagBranch,
+				PathPrefix: strings.Trim(flagPathPrefix, "/"),
+			}
+			if err := project.Save(); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "created %s for %s\n", config.ProjectFileName, args[0])
+
 			return nil
 		},
 	}
 
+	cmd.Flags().StringVar(&flagRef, "ref", "", "Default ref for read commands, written to "+config.ProjectFileName)
+	cmd.Flags().StringVarP(&flagBranch, "branch", "b", "", "Default branch for put and rm, written to "+config.ProjectFileName)
+	cmd.Flags().StringVar(&flagPathPrefix, "path-prefix", "", "Directory that relative paths start from, written to "+config.ProjectFileName)
+
 	return cmd
 }
@@ -1,55 +1,101 @@
 package cli
 
 import (
+	"log/slog"
 	"os"
 
 	"github.com/spf13/cobra"
 
+	clerrors "githubRAGCli/internal/exitcode"
 	"githubRAGCli/internal/output"
+	"githubRAGCli/internal/service"
 )
 
 func newLsCmd() *cobra.Command {
 	var (
 		flagRef       string
 		flagRecursive bool
+		flagLong      bool
+		flagHuman     bool
+		flagSort      string
+		flagReverse   bool
 	)
 
 	cmd := &cobra.Command{
-		Use:   "ls <owner/repo> <path>",
+		Use:   "ls [<owner/repo>] <path>",
 		Short: "List directory contents",
-		Args:  cobra.ExactArgs(2),
+		Args:  cobra.RangeArgs(1, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg := resolveConfig()
 
-			if err := requireToken(cfg); err != nil {
+			if err := requireToken(&cfg); err != nil {
 				return err
 			}
 
-			owner, repo, err := parseRepoArg(args)
+			owner, repo, path, err := parseTargetArgs(cfg, args)
 			if err != nil {
 				return err
 			}
-			path := args[1]
+			flagRef = defaultRef(flagRef)
 
-			verboseLog(cfg, "ls %s/%s %s (ref=%s, recursive=%v)", owner, repo, path, flagRef, flagRecursive)
+			slog.Debug("ls", "repo", owner+"/"+repo, "path", path, "ref", flagRef, "recursive", flagRecursive, "long", flagLong, "sort", flagSort)
 
 			svc := newService(cfg, owner, repo)
+
+			// NDJSON without sorting or -l prints entries as they arrive.
+			if output.Streams(outFormat) && !flagLong && flagSort == "" && !flagReverse {
+				stream := output.NewStream(os.Stdout, outFormat)
+				if err := svc.ListEach(flagRef, path, flagRecursive, func(e service.Entry) {
+					stream.Add(serviceEntryToOutput(&e))
+				}); err != nil {
+					return err
+				}
+				return stream.Err()
+			}
+
 			entries, err := svc.List(flagRef, path, flagRecursive)
 			if err != nil {
 				return err
 			}
+			// -l adds modes, symlink targets and directory sizes in every format.
+			if flagLong {
+				svc.AnnotateLinks(flagRef, entries)
+				if flagRecursive {
+					service.SumDirSizes(entries)
+				} else if err := svc.FillModes(flagRef, path, entries); err != nil {
+					return err
+				}
+			}
 
 			outEntries := make([]output.EntryData, 0, len(entries))
 			for i := range entries {
 				outEntries = append(outEntries, serviceEntryToOutput(&entries[i]))
 			}
 
-			return output.PrintEntries(os.Stdout, outEntries, cfg.JSON)
+			if flagSort != "" || flagReverse {
+				key := flagSort
+				if key == "" {
+					key = "name"
+				}
+				if err := output.SortEntries(outEntries, key, flagReverse); err != nil {
+					return clerrors.NewBadArgs(err.Error(), nil)
+				}
+			}
+
+			if flagLong && outFormat.IsText() {
+				return output.PrintEntriesLong(os.Stdout, outEntries, output.LongOptions{Human: flagHuman})
+			}
+			return output.PrintEntries(os.Stdout, outEntries, outFormat)
 		},
 	}
 
 	cmd.Flags().StringVar(&flagRef, "ref", "", "Git ref (branch, tag, or SHA)")
 	cmd.Flags().BoolVar(&flagRecursive, "recursive", false, "List recursively using the Trees API")
+	cmd.Flags().BoolVarP(&flagLong, "long", "l", false, "Long format: mode, type, size, short SHA and name; with --format, adds modes, symlink targets and directory sizes")
+	cmd.Flags().BoolVar(&flagHuman, "human-readable", false, "With -l, print sizes like 1.5K and 12M")
+	cmd.Flags().StringVar(&flagSort, "sort", "", "Sort by name, size or type")
+	cmd.Flags().BoolVar(&flagReverse, "reverse", false, "Reverse the sort order")
 
+	markRepoArg(cmd)
 	return cmd
 }
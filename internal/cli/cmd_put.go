@@ -3,6 +3,7 @@ package cli
 import (
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -21,21 +22,21 @@ func newPutCmd() *cobra.Command {
 	)
 
 	cmd := &cobra.Command{
-		Use:   "put <owner/repo> <path>",
+		Use:   "put [<owner/repo>] <path>",
 		Short: "Create or update a file in a GitHub repository",
-		Args:  cobra.ExactArgs(2),
+		Args:  cobra.RangeArgs(1, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg := resolveConfig()
 
-			if err := requireToken(cfg); err != nil {
+			if err := requireToken(&cfg); err != nil {
 				return err
 			}
 
-			owner, repo, err := parseRepoArg(args)
+			owner, repo, path, err := parseTargetArgs(cfg, args)
 			if err != nil {
 				return err
 			}
-			path := args[1]
+			flagBranch = defaultBranch(flagBranch)
 
 			if flagMessage == "" {
 				return clerrors.NewBadArgs("--message / -m is required", nil)
@@ -79,7 +80,7 @@ func newPutCmd() *cobra.Command {
 				// (stdin is already consumed for content, can't read confirmation)
 			}
 
-			verboseLog(cfg, "put %s/%s %s (branch=%s)", owner, repo, path, flagBranch)
+			slog.Debug("put", "repo", owner+"/"+repo, "path", path, "branch", flagBranch)
 
 			svc := newService(cfg, owner, repo)
 			result, err := svc.CreateOrUpdateFile(flagBranch, path, flagMessage, content)
@@ -92,7 +93,7 @@ func newPutCmd() *cobra.Command {
 				Path:   result.Path,
 				SHA:    result.SHA,
 				Branch: result.Branch,
-			}, cfg.JSON)
+			}, outFormat)
 		},
 	}
 
@@ -102,5 +103,6 @@ func newPutCmd() *cobra.Command {
 	cmd.Flags().BoolVar(&flagStdin, "stdin", false, "Read content from stdin")
 	cmd.Flags().BoolVarP(&flagYes, "yes", "y", false, "Skip confirmation prompt")
 
+	markRepoArg(cmd)
 	return cmd
 }
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"githubRAGCli/internal/output"
+)
+
+func newRateLimitCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rate-limit",
+		Short: "Show the rate-limit quota of every API resource",
+		Long: `Show the rate-limit quota of every API resource (core, search, graphql
+and others) from GET /rate_limit, which does not count against the quota.
+
+Long runs can be kept within the quota with the global flags
+--max-requests, which stops after that many requests, and --reserve, which
+stops before a resource drops below that many remaining requests, or with
+--wait-for-reset pauses until it resets.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := resolveConfig()
+
+			if err := requireToken(&cfg); err != nil {
+				return err
+			}
+
+			client := newClient(cfg, cfg.Token)
+			client.App = activeApp
+			client.Budget = activeBudget
+			client.Server = serverInfo(cfg)
+			limits, err := client.GetRateLimits()
+			if err != nil {
+				return err
+			}
+
+			data := make([]output.RateLimitData, 0, len(limits))
+			for _, l := range limits {
+				data = append(data, output.RateLimitData{
+					Resource:  l.Resource,
+					Limit:     l.Limit,
+					Used:      l.Used,
+					Remaining: l.Remaining,
+					Reset:     l.Reset.Format(time.RFC3339),
+				})
+			}
+			return output.PrintRateLimits(os.Stdout, data, outFormat)
+		},
+	}
+}
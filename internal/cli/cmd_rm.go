@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -18,21 +19,21 @@ func newRmCmd() *cobra.Command {
 	)
 
 	cmd := &cobra.Command{
-		Use:   "rm <owner/repo> <path>",
+		Use:   "rm [<owner/repo>] <path>",
 		Short: "Delete a file from a GitHub repository",
-		Args:  cobra.ExactArgs(2),
+		Args:  cobra.RangeArgs(1, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg := resolveConfig()
 
-			if err := requireToken(cfg); err != nil {
+			if err := requireToken(&cfg); err != nil {
 				return err
 			}
 
-			owner, repo, err := parseRepoArg(args)
+			owner, repo, path, err := parseTargetArgs(cfg, args)
 			if err != nil {
 				return err
 			}
-			path := args[1]
+			flagBranch = defaultBranch(flagBranch)
 
 			if flagMessage == "" {
 				return clerrors.NewBadArgs("--message / -m is required", nil)
@@ -48,7 +49,7 @@ func newRmCmd() *cobra.Command {
 				return err
 			}
 
-			verboseLog(cfg, "rm %s/%s %s (branch=%s)", owner, repo, path, flagBranch)
+			slog.Debug("rm", "repo", owner+"/"+repo, "path", path, "branch", flagBranch)
 
 			svc := newService(cfg, owner, repo)
 			result, err := svc.DeleteFile(flagBranch, path, flagMessage)
@@ -61,7 +62,7 @@ func newRmCmd() *cobra.Command {
 				Path:   result.Path,
 				SHA:    result.SHA,
 				Branch: result.Branch,
-			}, cfg.JSON)
+			}, outFormat)
 		},
 	}
 
@@ -69,5 +70,6 @@ func newRmCmd() *cobra.Command {
 	cmd.Flags().StringVarP(&flagBranch, "branch", "b", "", "Target branch (defaults to repo default branch)")
 	cmd.Flags().BoolVarP(&flagYes, "yes", "y", false, "Skip confirmation prompt")
 
+	markRepoArg(cmd)
 	return cmd
 }
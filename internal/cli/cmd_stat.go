@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"log/slog"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -10,26 +11,31 @@ import (
 )
 
 func newStatCmd() *cobra.Command {
-	var flagRef string
+	var (
+		flagRef          string
+		flagLastModified bool
+		flagLastCommit   bool
+		flagPermalink    bool
+	)
 
 	cmd := &cobra.Command{
-		Use:   "stat <owner/repo> <path>",
+		Use:   "stat [<owner/repo>] <path>",
 		Short: "Show metadata for a file or directory",
-		Args:  cobra.ExactArgs(2),
+		Args:  cobra.RangeArgs(1, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg := resolveConfig()
 
-			if err := requireToken(cfg); err != nil {
+			if err := requireToken(&cfg); err != nil {
 				return err
 			}
 
-			owner, repo, err := parseRepoArg(args)
+			owner, repo, path, err := parseTargetArgs(cfg, args)
 			if err != nil {
 				return err
 			}
-			path := args[1]
+			flagRef = defaultRef(flagRef)
 
-			verboseLog(cfg, "stat %s/%s %s (ref=%s)", owner, repo, path, flagRef)
+			slog.Debug("stat", "repo", owner+"/"+repo, "path", path, "ref", flagRef)
 
 			svc := newService(cfg, owner, repo)
 			entry, err := svc.Stat(flagRef, path)
@@ -41,11 +47,33 @@ func newStatCmd() *cobra.Command {
 				return clerrors.NewNotFound("path not found: "+path, nil)
 			}
 
-			return output.PrintEntry(os.Stdout, serviceEntryToOutput(entry), cfg.JSON)
+			if flagLastModified || flagLastCommit {
+				commit, err := svc.LastCommit(flagRef, entry.Path)
+				if err != nil {
+					return err
+				}
+				date := commit.Date.UTC()
+				entry.LastModified = &date
+				if flagLastCommit {
+					entry.LastCommit = commit
+				}
+			}
+
+			if flagPermalink {
+				if entry.Permalink, err = svc.Permalink(flagRef, entry); err != nil {
+					return err
+				}
+			}
+
+			return output.PrintEntry(os.Stdout, serviceEntryToOutput(entry), outFormat)
 		},
 	}
 
 	cmd.Flags().StringVar(&flagRef, "ref", "", "Git ref (branch, tag, or SHA)")
+	cmd.Flags().BoolVar(&flagLastModified, "last-modified", false, "Include the date of the last commit touching the path")
+	cmd.Flags().BoolVar(&flagLastCommit, "last-commit", false, "Include the last commit (SHA, author, date, message) touching the path")
+	cmd.Flags().BoolVar(&flagPermalink, "permalink", false, "Include a web URL pinned to the resolved commit")
 
+	markRepoArg(cmd)
 	return cmd
 }
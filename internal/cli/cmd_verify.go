@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	clerrors "githubRAGCli/internal/exitcode"
+	"githubRAGCli/internal/output"
+	"githubRAGCli/internal/service"
+)
+
+func newVerifyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify <manifest.json>",
+		Short: "Check downloaded files against a manifest written by get --manifest",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manifestPath := args[0]
+
+			m, err := service.LoadManifest(manifestPath)
+			if err != nil {
+				return err
+			}
+
+			slog.Debug("verify", "manifest", manifestPath, "repo", m.Repository, "commit", m.Commit, "files", len(m.Files))
+			if m.Incomplete {
+				fmt.Fprintf(os.Stderr, "note: %s describes an incomplete download: %d entries were skipped\n", manifestPath, len(m.Skipped))
+			}
+
+			drift, err := service.VerifyManifest(manifestPath, m)
+			if err != nil {
+				return err
+			}
+
+			result := output.VerifyResultData{
+				Status:     "ok",
+				Repository: m.Repository,
+				Commit:     m.Commit,
+				Checked:    len(m.Files),
+			}
+			for _, d := range drift {
+				result.Drift = append(result.Drift, output.DriftData{
+					Path:     d.Path,
+					Status:   d.Status,
+					Expected: d.Expected,
+					Actual:   d.Actual,
+				})
+			}
+			if len(drift) > 0 {
+				result.Status = "drift"
+			}
+
+			if err := output.PrintVerifyResult(os.Stdout, result, outFormat); err != nil {
+				return err
+			}
+			if len(drift) > 0 {
+				return clerrors.NewVerifyFailed(fmt.Sprintf("%d of %d files drifted from %s", len(drift), len(m.Files), manifestPath), nil)
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
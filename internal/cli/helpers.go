@@ -3,46 +3,272 @@ package cli
 import (
 	"bufio"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
 	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
 
 	"githubRAGCli/internal/config"
+	"githubRAGCli/internal/credstore"
 	clerrors "githubRAGCli/internal/exitcode"
 	"githubRAGCli/internal/githubapi"
 	"githubRAGCli/internal/output"
 	"githubRAGCli/internal/service"
 )
 
-// requireToken validates that a token is available and returns an error if not.
-func requireToken(cfg config.Config) error {
+// annotRepoArg marks commands whose first argument is a repository, so
+// that the config profile can be chosen from its host.
+const annotRepoArg = "ghrepo:repo-arg"
+
+// markRepoArg records that cmd takes <owner/repo> as its first argument.
+func markRepoArg(cmd *cobra.Command) {
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+	cmd.Annotations[annotRepoArg] = "true"
+}
+
+// requireToken finds a token for cfg.APIBase, trying the sources listed by
+// config.FindToken, and returns an error if there is none. When a GitHub App
+// is configured it loads the App key into activeApp instead; installation
+// tokens are then fetched on the first request, or reused from the token
+// cache next to the stored logins. The source, never the
+// value, is logged with --verbose.
+func requireToken(cfg *config.Config) error {
+	if cfg.Token == "" && (cfg.AppID != "" || cfg.AppKeyFile != "") {
+		return setupApp(cfg)
+	}
+	if cfg.Token == "" {
+		token, source, err := config.FindToken(config.TokenOptions{
+			Command: flagTokenCommand,
+			Profile: activeProfile,
+			APIBase: cfg.APIBase,
+		})
+		if err != nil {
+			return err
+		}
+		cfg.Token, cfg.TokenSource = token, source
+	}
+	if cfg.Token == "" && flagOrEnv(flagReplay, "GHREPO_REPLAY") != "" {
+		// Recordings hold no token, so any value will do.
+		cfg.Token, cfg.TokenSource = "replay", "--replay"
+	}
 	if cfg.Token == "" {
-		return clerrors.NewAuthFailure("no token provided: use --token, GITHUB_TOKEN, or GH_TOKEN", nil)
+		return clerrors.NewAuthFailure("no token found (tried "+strings.Join(config.TokenSourceNames(), ", ")+"): run 'ghrepo auth login' or set GITHUB_TOKEN", nil)
 	}
+	slog.Info("token source", "source", cfg.TokenSource)
 	return nil
 }
 
-// parseRepoArg parses and validates the owner/repo positional argument.
-func parseRepoArg(args []string) (owner, repo string, err error) {
+// setupApp prepares GitHub App authentication from cfg.
+func setupApp(cfg *config.Config) error {
+	if cfg.AppID == "" {
+		return clerrors.NewBadArgs("--app-key-file needs --app-id", nil)
+	}
+	if cfg.AppKeyFile == "" {
+		return clerrors.NewBadArgs("--app-id needs --app-key-file", nil)
+	}
+	key, err := githubapi.LoadAppKey(cfg.AppKeyFile)
+	if err != nil {
+		return err
+	}
+	activeApp = &githubapi.AppAuth{AppID: cfg.AppID, Key: key, InstallationID: cfg.AppInstallationID}
+	if dir := config.CredentialsDir(); dir != "" {
+		activeApp.Cache = &credstore.Store{Dir: dir}
+	}
+	cfg.TokenSource = "GitHub App " + cfg.AppID
+	slog.Info("token source", "source", cfg.TokenSource)
+	return nil
+}
+
+// parseTargetArgs parses the "[<owner/repo>] <path>" arguments. Without
+// <owner/repo>, the repository and path prefix come from the nearest project
+// file. A GitHub URL may stand for <owner/repo>, or for both when it names a
+// path; its ref becomes activeURL.Ref. Other arguments are split by
+// targetArgs.
+func parseTargetArgs(cfg config.Config, args []string) (owner, repo, path string, err error) {
 	if len(args) < 1 {
-		return "", "", clerrors.NewBadArgs("missing required argument: <owner/repo>", nil)
+		return "", "", "", clerrors.NewBadArgs("missing required argument: <path>", nil)
+	}
+	repoArg, err := activeRepoArgument(args)
+	if err != nil {
+		return "", "", "", err
 	}
-	return githubapi.ParseRepo(args[0])
+	if githubapi.IsURL(repoArg) {
+		return parseURLArgs(cfg, args)
+	}
+	return targetArgs(args, repoArg, activeProject, activeProfile)
+}
+
+// targetArgs splits the non-URL repository argument repoArg and returns the
+// path, the last of args. A leading host ("ghe.example.com/owner/repo") is
+// stripped, as it only selects the profile; a bare "repo" takes the owner
+// from profile. When args holds only the path, project supplied repoArg and
+// its path prefix applies.
+func targetArgs(args []string, repoArg string, project *config.Project, profile *config.Profile) (owner, repo, path string, err error) {
+	path = args[len(args)-1]
+	if len(args) < 2 && project != nil {
+		path = project.ResolvePath(path)
+	}
+
+	_, ownerRepo := splitRepoHost(repoArg)
+	if !strings.Contains(ownerRepo, "/") && profile != nil && profile.Owner != "" {
+		ownerRepo = profile.Owner + "/" + ownerRepo
+	}
+	owner, repo, err = githubapi.ParseRepo(ownerRepo)
+	return owner, repo, path, err
+}
+
+// parseURLArgs is parseTargetArgs for a URL in args[0], which becomes
+// activeURL. A second argument is the path, and then all of the URL after
+// blob/ or tree/ is the ref. Otherwise a ref that may contain slashes is
+// resolved against the repository's branches.
+func parseURLArgs(cfg config.Config, args []string) (owner, repo, path string, err error) {
+	u, err := githubapi.ParseURL(args[0])
+	if err != nil {
+		return "", "", "", err
+	}
+	activeURL = u
+	if len(args) >= 2 {
+		if u.Path != "" {
+			return "", "", "", clerrors.NewBadArgs(fmt.Sprintf("%s already names a path; drop the <path> argument", args[0]), nil)
+		}
+		if u.RefPath != "" {
+			u.Ref, u.RefPath = u.RefPath, ""
+		}
+		return u.Owner, u.Repo, args[1], nil
+	}
+	if u.RefPath != "" {
+		u.Ref, u.Path, err = newService(cfg, u.Owner, u.Repo).Client.SplitRefPath(u.Owner, u.Repo, u.RefPath)
+		if err != nil {
+			return "", "", "", err
+		}
+		u.RefPath = ""
+		slog.Info("resolved URL", "ref", u.Ref, "path", u.Path)
+	}
+	return u.Owner, u.Repo, u.Path, nil
+}
+
+// activeRepoArgument is repoArgument for the working directory. The
+// project file it used, if any, becomes activeProject.
+func activeRepoArgument(args []string) (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", clerrors.NewLocalWriteErr("failed to get current directory", err)
+	}
+	repoArg, p, err := repoArgument(args, activeProject, cwd)
+	if p != nil {
+		activeProject = p
+	}
+	return repoArg, err
+}
+
+// repoArgument returns the repository argument: args[0] when both
+// <owner/repo> and <path> are given or args[0] is a URL, else the repo of
+// project, or when project is nil of the nearest project file to dir. It
+// also returns the project file the repository came from.
+func repoArgument(args []string, project *config.Project, dir string) (string, *config.Project, error) {
+	if len(args) >= 2 || (len(args) == 1 && githubapi.IsURL(args[0])) {
+		return args[0], nil, nil
+	}
+	if project != nil {
+		return project.Repo, project, nil
+	}
+	p, err := config.FindProject(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	if p == nil {
+		return "", nil, clerrors.NewBadArgs("missing <owner/repo>: pass it, or run 'ghrepo init <owner/repo>' to write "+config.ProjectFileName, nil)
+	}
+	return p.Repo, p, nil
+}
+
+// defaultRef returns ref, or when ref is empty the ref of the URL argument
+// or, if the repository came from the project file, the project file's ref.
+func defaultRef(ref string) string {
+	if ref == "" && activeURL != nil {
+		return activeURL.Ref
+	}
+	if ref == "" && activeProject != nil {
+		return activeProject.Ref
+	}
+	return ref
+}
+
+// defaultBranch is defaultRef for the --branch of write commands.
+func defaultBranch(branch string) string {
+	if branch == "" && activeURL != nil {
+		return activeURL.Ref
+	}
+	if branch == "" && activeProject != nil {
+		return activeProject.Branch
+	}
+	return branch
+}
+
+// splitRepoHost splits "host/owner/repo" into its lower-cased host and
+// "owner/repo". Arguments without a dotted first segment have no host. For
+// a GitHub URL the host is its web URL, e.g. https://ghe.example.com.
+func splitRepoHost(arg string) (host, ownerRepo string) {
+	if githubapi.IsURL(arg) {
+		if u, err := githubapi.ParseURL(arg); err == nil {
+			return u.Web, u.Owner + "/" + u.Repo
+		}
+		return "", arg
+	}
+	first, rest, ok := strings.Cut(arg, "/")
+	if !ok || !strings.Contains(first, ".") || !strings.Contains(rest, "/") {
+		return "", arg
+	}
+	return strings.ToLower(first), rest
 }
 
 // newService creates a RepoService from resolved config and parsed owner/repo.
 func newService(cfg config.Config, owner, repo string) *service.RepoService {
-	return service.NewRepoService(cfg.APIBase, cfg.Token, cfg.Timeout, owner, repo)
+	svc := service.NewRepoService(cfg.APIBase, cfg.Token, cfg.Timeout, owner, repo)
+	svc.Client.HTTPClient = httpClient(cfg)
+	svc.Client.Budget = activeBudget
+	svc.Client.Server = serverInfo(cfg)
+	if activeApp != nil {
+		activeApp.Owner, activeApp.Repo = owner, repo
+		svc.Client.App = activeApp
+	}
+	return svc
 }
 
 // serviceEntryToOutput converts a service.Entry to an output.EntryData.
 func serviceEntryToOutput(e *service.Entry) output.EntryData {
-	return output.EntryData{
-		Type:        e.Type,
-		Path:        e.Path,
-		SHA:         e.SHA,
-		Size:        e.Size,
-		DownloadURL: e.DownloadURL,
+	d := output.EntryData{
+		Type:         e.Type,
+		Path:         e.Path,
+		SHA:          e.SHA,
+		Size:         e.Size,
+		Mode:         e.Mode,
+		DownloadURL:  e.DownloadURL,
+		Children:     e.Children,
+		FileCount:    e.FileCount,
+		TotalSize:    e.TotalSize,
+		Permalink:    e.Permalink,
+		Target:       e.Target,
+		SubmoduleURL: e.SubmoduleURL,
+	}
+	if e.LastModified != nil {
+		d.LastModified = e.LastModified.Format(time.RFC3339)
+	}
+	if c := e.LastCommit; c != nil {
+		d.LastCommit = &output.CommitData{
+			SHA:         c.SHA,
+			AuthorName:  c.AuthorName,
+			AuthorEmail: c.AuthorEmail,
+			Date:        c.Date.UTC().Format(time.RFC3339),
+			Message:     c.Message,
+		}
 	}
+	return d
 }
 
 // confirmPrompt displays a confirmation prompt on stderr and reads user input.
@@ -70,3 +296,62 @@ func confirmPrompt(message string, skipConfirm bool) error {
 	}
 	return nil
 }
+
+// httpClient returns an HTTP client with cfg's timeout that goes through
+// activeTransport, for proxies, private CAs and client certificates.
+func httpClient(cfg config.Config) *http.Client {
+	c := &http.Client{Timeout: cfg.Timeout}
+	if activeTransport != nil {
+		c.Transport = activeTransport
+	}
+	return c
+}
+
+// newClient creates an API client that uses httpClient.
+func newClient(cfg config.Config, token string) *githubapi.Client {
+	c := githubapi.NewClient(cfg.APIBase, token, cfg.Timeout)
+	c.HTTPClient = httpClient(cfg)
+	return c
+}
+
+// serverInfo detects the server behind cfg.APIBase on first use, so
+// features a GitHub Enterprise Server lacks can be turned off. The result is
+// cached next to the credentials for credstore.ServerCacheTTL, so most runs
+// make no extra request. A failed detection is only logged and not cached:
+// the command's own requests report the problem.
+func serverInfo(cfg config.Config) *githubapi.ServerInfo {
+	if activeServer != nil {
+		return activeServer
+	}
+	e := githubapi.APIEndpoints(cfg.APIBase)
+	var cache *credstore.Store
+	if dir := config.CredentialsDir(); dir != "" && e.Web != "https://github.com" {
+		cache = &credstore.Store{Dir: dir}
+	}
+
+	var info *githubapi.ServerInfo
+	if cache != nil {
+		cached, err := cache.LoadServer(e.API)
+		if err != nil {
+			slog.Info("server cache unreadable", "err", err)
+		} else if cached != nil {
+			info = &githubapi.ServerInfo{Enterprise: cached.Enterprise, Version: cached.Version}
+		}
+	}
+	if info == nil {
+		detected, err := newClient(cfg, cfg.Token).DetectServer()
+		switch {
+		case err != nil:
+			slog.Info("server detection failed", "err", err)
+			detected = &githubapi.ServerInfo{}
+		case cache != nil:
+			if err := cache.SaveServer(e.API, credstore.CachedServer{Enterprise: detected.Enterprise, Version: detected.Version, DetectedAt: time.Now()}); err != nil {
+				slog.Info("cannot cache server", "err", err)
+			}
+		}
+		info = detected
+	}
+	activeServer = info
+	slog.Info("server", "server", info.String(), "api", e.API, "graphql", e.GraphQL, "uploads", e.Uploads, "raw", e.Raw)
+	return info
+}
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"githubRAGCli/internal/config"
+)
+
+func TestServerInfo_CachedBetweenRuns(t *testing.T) {
+	t.Setenv("GHREPO_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/meta" {
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+		hits++
+		w.Write([]byte(`{"installed_version":"3.12.4"}`))
+	}))
+	defer srv.Close()
+	t.Cleanup(func() { activeServer = nil })
+
+	cfg := config.Config{APIBase: srv.URL + "/api/v3", Timeout: 5 * time.Second}
+	for run := 0; run < 2; run++ {
+		activeServer = nil
+		info := serverInfo(cfg)
+		if !info.Enterprise || info.Version != "3.12.4" {
+			t.Errorf("run %d: got %+v", run, info)
+		}
+	}
+	if hits != 1 {
+		t.Errorf("/meta requested %d times, want 1", hits)
+	}
+}
@@ -2,11 +2,20 @@ package cli
 
 import (
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	"githubRAGCli/internal/config"
+	clerrors "githubRAGCli/internal/exitcode"
+	"githubRAGCli/internal/githubapi"
+	"githubRAGCli/internal/jq"
+	"githubRAGCli/internal/output"
 )
 
 var (
@@ -14,7 +23,77 @@ var (
 	flagAPIBase string
 	flagTimeout time.Duration
 	flagJSON    bool
+	flagFormat  string
+	flagFields  string
+	flagJQ      string
 	flagVerbose bool
+	flagProfile string
+
+	flagTokenCommand string
+	flagHostname     string
+
+	flagAppID             string
+	flagAppKeyFile        string
+	flagAppInstallationID int64
+
+	flagMaxRequests  int
+	flagReserve      int
+	flagWaitForReset bool
+
+	flagProxy        string
+	flagCAFile       string
+	flagClientCert   string
+	flagClientKey    string
+	flagInsecure     bool
+	flagDisableHTTP2 bool
+
+	flagTrace     bool
+	flagTraceBody bool
+	flagTraceFile string
+
+	flagLogLevel  string
+	flagLogFormat string
+	flagLogFile   string
+
+	flagRecord string
+	flagReplay string
+
+	// outFormat is the parsed --format, set before any command runs.
+	outFormat = output.FormatText
+
+	// configFile and activeProfile are loaded before any command runs.
+	// activeProfile is nil when no profile applies.
+	configFile    *config.File
+	activeProfile *config.Profile
+
+	// activeProject is the project file in use; it is only set when a
+	// command's <owner/repo> argument was omitted.
+	activeProject *config.Project
+
+	// activeApp is set by requireToken when GitHub App authentication is
+	// configured; it is shared by all clients so the token is cached once.
+	activeApp *githubapi.AppAuth
+
+	// activeBudget limits the requests of the run; it is nil unless
+	// --max-requests, --reserve or --wait-for-reset is given.
+	activeBudget *githubapi.Budget
+
+	// activeServer is the server behind the API base, detected once per run
+	// by serverInfo.
+	activeServer *githubapi.ServerInfo
+
+	// activeURL is the GitHub URL given in place of <owner/repo>; its ref
+	// is the default for --ref and --branch.
+	activeURL *githubapi.RepoURL
+
+	// activeTransport carries the proxy and TLS settings, --record or
+	// --replay, and --trace to every client; see httpClient.
+	activeTransport http.RoundTripper
+
+	// logFile and traceFile are the open --log-file and --trace-file,
+	// closed by CloseLog.
+	logFile   *os.File
+	traceFile *os.File
 )
 
 // NewRootCmd creates the top-level ghrepo command.
@@ -24,13 +103,54 @@ func NewRootCmd() *cobra.Command {
 		Short:         "CLI for GitHub repository contents",
 		SilenceUsage:  true,
 		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := setupLogging(cmd); err != nil {
+				return err
+			}
+			if err := loadProfile(cmd, args); err != nil {
+				return err
+			}
+			if err := setupBudget(cmd); err != nil {
+				return err
+			}
+			if err := parseFormatFlags(); err != nil {
+				return err
+			}
+			return setupTransport()
+		},
 	}
 
 	root.PersistentFlags().StringVar(&flagToken, "token", "", "GitHub personal access token (overrides GITHUB_TOKEN / GH_TOKEN)")
+	root.PersistentFlags().StringVar(&flagTokenCommand, "token-command", "", "Shell command that prints a token (tried after --token)")
+	root.PersistentFlags().StringVar(&flagAppID, "app-id", "", "Authenticate as this GitHub App (App ID or client ID) instead of with a token")
+	root.PersistentFlags().StringVar(&flagAppKeyFile, "app-key-file", "", "PEM private key of the GitHub App")
+	root.PersistentFlags().Int64Var(&flagAppInstallationID, "app-installation-id", 0, "GitHub App installation ID (default: the installation on the target repository)")
 	root.PersistentFlags().StringVar(&flagAPIBase, "api-base", config.DefaultAPIBase, "GitHub API base URL")
+	root.PersistentFlags().StringVar(&flagHostname, "hostname", "", "GitHub host, e.g. ghe.example.com; sets the API base unless --api-base is given")
 	root.PersistentFlags().DurationVar(&flagTimeout, "timeout", config.DefaultTimeout, "HTTP request timeout")
-	root.PersistentFlags().BoolVar(&flagJSON, "json", false, "Output in JSON format")
-	root.PersistentFlags().BoolVar(&flagVerbose, "verbose", false, "Enable verbose output (never prints token)")
+	root.PersistentFlags().IntVar(&flagMaxRequests, "max-requests", 0, "Stop after this many API requests (0: no limit)")
+	root.PersistentFlags().IntVar(&flagReserve, "reserve", 0, "Stop before the remaining rate-limit quota drops below this many requests")
+	root.PersistentFlags().BoolVar(&flagWaitForReset, "wait-for-reset", false, "At the --reserve, pause until the quota resets instead of stopping")
+	root.PersistentFlags().StringVar(&flagProxy, "proxy", "", "Proxy URL, e.g. http://proxy.example.com:3128 (default: HTTPS_PROXY / NO_PROXY)")
+	root.PersistentFlags().StringVar(&flagCAFile, "ca-file", "", "PEM file of CA certificates to trust besides the system's")
+	root.PersistentFlags().StringVar(&flagClientCert, "client-cert", "", "PEM client certificate for mTLS")
+	root.PersistentFlags().StringVar(&flagClientKey, "client-key", "", "PEM private key of --client-cert (default: read from --client-cert)")
+	root.PersistentFlags().BoolVar(&flagInsecure, "insecure-skip-verify", false, "Do not verify server TLS certificates (INSECURE: for testing only)")
+	root.PersistentFlags().BoolVar(&flagDisableHTTP2, "disable-http2", false, "Use HTTP/1.1 only")
+	root.PersistentFlags().BoolVar(&flagTrace, "trace", false, "Log every API request and response to stderr, secrets redacted (also GHREPO_DEBUG=api)")
+	root.PersistentFlags().BoolVar(&flagTraceBody, "trace-body", false, "Like --trace, also logging headers and bodies (also GHREPO_DEBUG=api-body)")
+	root.PersistentFlags().StringVar(&flagTraceFile, "trace-file", "", "Append the --trace log to this file instead of stderr (implies --trace)")
+	root.PersistentFlags().StringVar(&flagRecord, "record", "", "Save every API request and response to cassette files in this directory, secrets redacted (also GHREPO_RECORD)")
+	root.PersistentFlags().StringVar(&flagReplay, "replay", "", "Answer API requests from the cassette files in this directory instead of the network (also GHREPO_REPLAY)")
+	root.PersistentFlags().BoolVar(&flagJSON, "json", false, "Output in JSON format (same as --format json)")
+	root.PersistentFlags().StringVar(&flagFormat, "format", "", "Output format: "+strings.Join(output.FormatNames(), ", ")+", or a Go template such as '{{.path}} {{.size}}'")
+	root.PersistentFlags().StringVar(&flagFields, "fields", "", "Comma-separated JSON fields to keep, e.g. path,sha (implies --json)")
+	root.PersistentFlags().StringVar(&flagJQ, "jq", "", "Filter JSON output with a jq expression (implies --json)")
+	root.PersistentFlags().BoolVar(&flagVerbose, "verbose", false, "Enable verbose output (never prints token); same as --log-level debug")
+	root.PersistentFlags().StringVar(&flagLogLevel, "log-level", "warn", "Log level: debug, info, warn or error")
+	root.PersistentFlags().StringVar(&flagLogFormat, "log-format", "text", "Log format: text or json")
+	root.PersistentFlags().StringVar(&flagLogFile, "log-file", "", "Append logs to this file instead of stderr")
+	root.PersistentFlags().StringVar(&flagProfile, "profile", "", "Config profile to use (overrides GHREPO_PROFILE)")
 
 	root.AddCommand(newInitCmd())
 	root.AddCommand(newAuthCmd())
@@ -40,30 +160,404 @@ func NewRootCmd() *cobra.Command {
 	root.AddCommand(newGetCmd())
 	root.AddCommand(newPutCmd())
 	root.AddCommand(newRmCmd())
+	root.AddCommand(newVerifyCmd())
+	root.AddCommand(newConfigCmd())
+	root.AddCommand(newRateLimitCmd())
+	root.AddCommand(newExitCodesTopic())
 
 	return root
 }
 
-// resolveConfig builds a Config from flags and environment.
+// resolveConfig builds a Config from flags, the active profile and
+// environment, in the precedence documented on config.Config. The token is
+// looked up later by requireToken, since some sources run programs.
 func resolveConfig() config.Config {
-	return config.Config{
-		Token:   config.ResolveToken(flagToken),
+	cfg := config.Config{
+		Token:   flagToken,
 		APIBase: flagAPIBase,
 		Timeout: flagTimeout,
-		JSON:    flagJSON,
-		Verbose: flagVerbose,
+		JSON:    outFormat.Name == "json",
+	}
+	if flagToken != "" {
+		cfg.TokenSource = "--token"
+	} else {
+		cfg.AppID = flagAppID
+		cfg.AppKeyFile = flagAppKeyFile
+		cfg.AppInstallationID = flagAppInstallationID
+	}
+	if p := activeProfile; p != nil {
+		cfg.Profile = p.Name
+		cfg.Owner = p.Owner
+	}
+	cfg.Proxy = flagOrEnv(flagProxy, "GHREPO_PROXY")
+	cfg.CAFile = flagOrEnv(flagCAFile, "GHREPO_CA_FILE")
+	cfg.ClientCert = flagOrEnv(flagClientCert, "GHREPO_CLIENT_CERT")
+	cfg.ClientKey = flagOrEnv(flagClientKey, "GHREPO_CLIENT_KEY")
+	cfg.InsecureSkipVerify = flagInsecure
+	cfg.DisableHTTP2 = flagDisableHTTP2
+	return cfg
+}
+
+// flagOrEnv returns the flag value, or the environment variable env when
+// the flag is empty.
+func flagOrEnv(value, env string) string {
+	if value != "" {
+		return value
+	}
+	return os.Getenv(env)
+}
+
+// loadProfile reads the config file and selects the active profile from
+// --profile, GHREPO_PROFILE, --hostname, the host of the host/owner/repo
+// argument (or of the project file's repo when it is omitted) or the file's
+// default. Profile settings fill in flags the user did not set; a host
+// without a profile gets its API base from githubapi.HostEndpoints.
+func loadProfile(cmd *cobra.Command, args []string) error {
+	f, err := config.LoadFile(config.FilePath())
+	if err != nil {
+		return err
+	}
+	configFile = f
+
+	flags := cmd.Flags()
+	if flags.Changed("token") && flags.Changed("app-id") {
+		return clerrors.NewBadArgs("--token conflicts with --app-id", nil)
+	}
+
+	name := flagProfile
+	if name == "" {
+		name = os.Getenv("GHREPO_PROFILE")
+	}
+	var repoArg string
+	if cmd.Annotations[annotRepoArg] != "" && len(args) > 0 {
+		if repoArg, err = activeRepoArgument(args); err != nil {
+			return err
+		}
+	}
+	p, apiBase, err := selectProfile(f, name, flagHostname, repoArg)
+	if err != nil {
+		return err
+	}
+	activeProfile = p
+	if apiBase != "" && !flags.Changed("api-base") {
+		flagAPIBase = apiBase
+	}
+	if p == nil {
+		return nil
+	}
+
+	if p.Timeout != 0 && !flags.Changed("timeout") {
+		flagTimeout = p.Timeout
+	}
+	if p.AppID != "" && !flags.Changed("app-id") {
+		flagAppID = p.AppID
 	}
+	if p.AppKeyFile != "" && !flags.Changed("app-key-file") {
+		flagAppKeyFile = p.AppKeyFile
+	}
+	if p.AppInstallationID != 0 && !flags.Changed("app-installation-id") {
+		flagAppInstallationID = p.AppInstallationID
+	}
+	if p.Format != "" && flagFormat == "" && !flagJSON && flagFields == "" && flagJQ == "" {
+		flagFormat = p.Format
+	}
+	for _, s := range []struct {
+		flag  string
+		value string
+		dst   *string
+	}{
+		{"proxy", p.Proxy, &flagProxy},
+		{"ca-file", p.CAFile, &flagCAFile},
+		{"client-cert", p.ClientCert, &flagClientCert},
+		{"client-key", p.ClientKey, &flagClientKey},
+	} {
+		if s.value != "" && !flags.Changed(s.flag) {
+			*s.dst = s.value
+		}
+	}
+	if p.InsecureSkipVerify && !flags.Changed("insecure-skip-verify") {
+		flagInsecure = true
+	}
+	if p.DisableHTTP2 && !flags.Changed("disable-http2") {
+		flagDisableHTTP2 = true
+	}
+	return nil
 }
 
-// JSONFlag returns the current value of --json. Used by main for error output.
-func JSONFlag() bool {
-	return flagJSON
+// selectProfile picks the profile for the profile name, the --hostname
+// and the repository argument, and the API base that goes with it. The host
+// comes from hostname, else from repoArg; when both name one they must
+// agree. The API base is that of the host when the profile is for another
+// host or has none, else the profile's; it is empty when neither applies.
+func selectProfile(f *config.File, name, hostname, repoArg string) (p *config.Profile, apiBase string, err error) {
+	var host string
+	var endpoints githubapi.Endpoints
+	if hostname != "" {
+		if endpoints, err = githubapi.HostEndpoints(hostname); err != nil {
+			return nil, "", clerrors.NewBadArgs(fmt.Sprintf("invalid --hostname %q", hostname), nil)
+		}
+		host = config.HostFromAPIBase(endpoints.API)
+	}
+	if argHost, _ := splitRepoHost(repoArg); argHost != "" {
+		argEndpoints, err := githubapi.HostEndpoints(argHost)
+		if err != nil {
+			return nil, "", clerrors.NewBadArgs(fmt.Sprintf("invalid host in %q", repoArg), nil)
+		}
+		argHost = config.HostFromAPIBase(argEndpoints.API)
+		if host != "" && argHost != host {
+			return nil, "", clerrors.NewBadArgs(fmt.Sprintf("--hostname %s conflicts with the host of %s", hostname, repoArg), nil)
+		}
+		if host == "" {
+			host, endpoints = argHost, argEndpoints
+		}
+	}
+	if p, err = f.Select(name, host); err != nil {
+		return nil, "", err
+	}
+
+	switch {
+	case host != "" && (p == nil || p.APIBase == "" || p.HostName() != host):
+		apiBase = endpoints.API
+	case p != nil && p.APIBase != "":
+		apiBase = p.APIBase
+	case p != nil && p.Host != "":
+		if e, err := githubapi.HostEndpoints(p.Host); err == nil {
+			apiBase = e.API
+		}
+	}
+	return p, apiBase, nil
 }
 
-// verboseLog prints a message only when --verbose is set.
-// It must never include token values.
-func verboseLog(cfg config.Config, format string, args ...any) {
-	if cfg.Verbose {
-		fmt.Printf("[verbose] "+format+"\n", args...)
+// setupBudget creates activeBudget from --max-requests, --reserve and
+// --wait-for-reset.
+func setupBudget(cmd *cobra.Command) error {
+	flags := cmd.Flags()
+	if !flags.Changed("max-requests") && !flags.Changed("reserve") && !flags.Changed("wait-for-reset") {
+		return nil
+	}
+	if flagMaxRequests < 0 {
+		return clerrors.NewBadArgs("--max-requests must not be negative", nil)
+	}
+	if flagReserve < 0 {
+		return clerrors.NewBadArgs("--reserve must not be negative", nil)
+	}
+	activeBudget = &githubapi.Budget{
+		MaxRequests: flagMaxRequests,
+		Reserve:     flagReserve,
+		Wait:        flagWaitForReset,
+		OnWait: func(resource string, remaining int, until time.Time) {
+			slog.Warn("rate limit reserve reached, pausing until the reset", "resource", resource, "remaining", remaining, "until", until.Local().Format(time.TimeOnly))
+		},
+	}
+	return nil
+}
+
+// setupTransport creates activeTransport from the proxy and TLS settings
+// of resolveConfig and reports them with --verbose. Turning off certificate
+// verification is always warned about. --record and --replay swap in the
+// cassette transports, and --trace or GHREPO_DEBUG wraps the result in a
+// githubapi.TraceTransport.
+func setupTransport() error {
+	cfg := resolveConfig()
+	t, err := githubapi.NewTransport(githubapi.TransportOptions{
+		Proxy:              cfg.Proxy,
+		CAFile:             cfg.CAFile,
+		ClientCert:         cfg.ClientCert,
+		ClientKey:          cfg.ClientKey,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		DisableHTTP2:       cfg.DisableHTTP2,
+	})
+	if err != nil {
+		return err
+	}
+	activeTransport = t
+	if err := setupCassette(); err != nil {
+		return err
+	}
+	if err := setupTrace(); err != nil {
+		return err
+	}
+
+	// Printed directly, so that no --log-level or --log-file can hide it.
+	if cfg.InsecureSkipVerify {
+		fmt.Fprintf(os.Stderr, "warning: TLS certificate verification is disabled for %s (--insecure-skip-verify); the connection can be intercepted and your token stolen\n",
+			config.HostFromAPIBase(cfg.APIBase))
+	}
+	slog.Debug("transport", "proxy", orNone(githubapi.ProxyFor(t, cfg.APIBase)), "ca_file", orNone(cfg.CAFile),
+		"client_cert", orNone(cfg.ClientCert), "verify", !cfg.InsecureSkipVerify, "http2", !cfg.DisableHTTP2)
+	return nil
+}
+
+// setupCassette wraps activeTransport in a githubapi.RecordTransport for
+// --record, or replaces it with a githubapi.ReplayTransport for --replay, so
+// that runs can be repeated without network access.
+func setupCassette() error {
+	record, replay := flagOrEnv(flagRecord, "GHREPO_RECORD"), flagOrEnv(flagReplay, "GHREPO_REPLAY")
+	switch {
+	case record != "" && replay != "":
+		return clerrors.NewBadArgs("--record conflicts with --replay", nil)
+	case record != "":
+		t, err := githubapi.NewRecordTransport(record, activeTransport)
+		if err != nil {
+			return err
+		}
+		activeTransport = t
+		slog.Info("recording API requests", "dir", record)
+	case replay != "":
+		t, err := githubapi.NewReplayTransport(replay)
+		if err != nil {
+			return err
+		}
+		activeTransport = t
+		slog.Info("replaying API requests", "dir", replay)
+	}
+	return nil
+}
+
+// setupTrace wraps activeTransport in a TraceTransport when --trace,
+// --trace-body, --trace-file or GHREPO_DEBUG (a comma-separated list with
+// "api" or "api-body") asks for it.
+func setupTrace() error {
+	trace, bodies := flagTrace || flagTraceFile != "", flagTraceBody
+	for _, v := range strings.Split(os.Getenv("GHREPO_DEBUG"), ",") {
+		switch strings.TrimSpace(v) {
+		case "api":
+			trace = true
+		case "api-body":
+			trace, bodies = true, true
+		}
+	}
+	if !trace && !bodies {
+		return nil
+	}
+	tt := &githubapi.TraceTransport{Next: activeTransport, Out: os.Stderr, Bodies: bodies}
+	if flagTraceFile != "" {
+		// The trace may hold repository content, so only the owner reads it.
+		f, err := os.OpenFile(flagTraceFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			return clerrors.NewLocalWriteErr("cannot open --trace-file", err)
+		}
+		traceFile, tt.Out = f, f
+	}
+	activeTransport = tt
+	return nil
+}
+
+// orNone returns s, or "none" when s is empty.
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// parseFormatFlags resolves --format, --json, --fields and --jq into outFormat.
+func parseFormatFlags() error {
+	f := output.FormatJSON
+	if flagJSON {
+		if flagFormat != "" && flagFormat != "json" {
+			return clerrors.NewBadArgs("--json conflicts with --format "+flagFormat, nil)
+		}
+	} else {
+		var err error
+		if f, err = output.ParseFormat(flagFormat); err != nil {
+			return clerrors.NewBadArgs(err.Error(), nil)
+		}
+	}
+
+	if flagFields != "" {
+		for _, name := range strings.Split(flagFields, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				f.Fields = append(f.Fields, name)
+			}
+		}
+		if len(f.Fields) == 0 {
+			return clerrors.NewBadArgs("--fields needs at least one field name", nil)
+		}
+	}
+	if flagJQ != "" {
+		if !f.IsText() && f.Name != "json" {
+			return clerrors.NewBadArgs("--jq conflicts with --format "+f.Name, nil)
+		}
+		q, err := jq.Parse(flagJQ)
+		if err != nil {
+			return clerrors.NewBadArgs(err.Error(), nil)
+		}
+		f.Query = q
+	}
+	// Field selection and queries work on the JSON form of the output.
+	if f.IsText() && (f.Fields != nil || f.Query != nil) {
+		f.Name = "json"
+	}
+
+	outFormat = f
+	return nil
+}
+
+// OutputFormat returns the selected output format. Used by main for error output.
+func OutputFormat() output.Format {
+	return outFormat
+}
+
+// setupLogging installs the default slog logger, which cli, service and
+// githubapi log to, from --log-level (or --verbose, which means debug),
+// --log-format and --log-file. Logs never go to stdout, which carries
+// command output, and must never include token values.
+func setupLogging(cmd *cobra.Command) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(flagLogLevel)); err != nil {
+		return clerrors.NewBadArgs(fmt.Sprintf("invalid --log-level %q (want debug, info, warn or error)", flagLogLevel), nil)
+	}
+	if flagVerbose && !cmd.Flags().Changed("log-level") {
+		level = slog.LevelDebug
+	}
+
+	if flagLogFormat != "text" && flagLogFormat != "json" {
+		return clerrors.NewBadArgs(fmt.Sprintf("invalid --log-format %q (want text or json)", flagLogFormat), nil)
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var out io.Writer = os.Stderr
+	if flagLogFile != "" {
+		f, err := os.OpenFile(flagLogFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			return clerrors.NewLocalWriteErr("cannot open --log-file", err)
+		}
+		logFile, out = f, f
+	} else {
+		// On the terminal the time of each line is noise.
+		opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		}
+	}
+
+	var h slog.Handler = slog.NewTextHandler(out, opts)
+	if flagLogFormat == "json" {
+		h = slog.NewJSONHandler(out, opts)
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+// CloseLog closes the --log-file and --trace-file once the command has
+// finished; the caller of Execute runs it whether or not the command failed.
+func CloseLog() error {
+	var err error
+	if traceFile != nil {
+		if cerr := traceFile.Close(); cerr != nil {
+			err = clerrors.NewLocalWriteErr("cannot close --trace-file", cerr)
+		}
+		traceFile = nil
+	}
+	if logFile != nil {
+		slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+		if cerr := logFile.Close(); cerr != nil && err == nil {
+			err = clerrors.NewLocalWriteErr("cannot close --log-file", cerr)
+		}
+		logFile = nil
 	}
+	return err
 }
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+func TestSetupLogging_InvalidFormatOpensNoFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ghrepo.log")
+	cmd := NewRootCmd() // resets the flags
+	flagLogLevel, flagLogFormat, flagLogFile = "info", "xml", path
+	t.Cleanup(func() { flagLogFormat, flagLogFile = "text", "" })
+
+	err := setupLogging(cmd)
+	var ce *clerrors.CLIError
+	if !errors.As(err, &ce) || ce.Cat != clerrors.CatBadArgs {
+		t.Fatalf("got %v, want a bad-args error", err)
+	}
+	if logFile != nil {
+		t.Error("log file left open")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("log file created for an invalid format: %v", err)
+	}
+}
+
+func TestCloseLog_ClosesTraceFile(t *testing.T) {
+	flagTraceFile = filepath.Join(t.TempDir(), "trace.log")
+	t.Cleanup(func() { flagTraceFile, activeTransport = "", nil })
+
+	if err := setupTrace(); err != nil {
+		t.Fatal(err)
+	}
+	f := traceFile
+	if f == nil {
+		t.Fatal("trace file not kept")
+	}
+	if err := CloseLog(); err != nil {
+		t.Fatal(err)
+	}
+	if traceFile != nil {
+		t.Error("traceFile not reset")
+	}
+	if _, err := f.Write([]byte("x")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("trace file still open: %v", err)
+	}
+}
@@ -1,9 +1,6 @@
 package config
 
-import (
-	"os"
-	"time"
-)
+import "time"
 
 const (
 	DefaultAPIBase = "https://api.github.com"
@@ -11,26 +8,52 @@ const (
 )
 
 // Config holds resolved runtime configuration.
+//
+// Each setting is taken from the first source that provides it:
+//
+//  1. command-line arguments and flags (<owner/repo>, --ref, --branch,
+//     --token, --app-id, --api-base, --timeout, --format, --proxy,
+//     --ca-file, ...)
+//  2. the project file (.ghrepo.yaml, see FindProject), only when the
+//     <owner/repo> argument is omitted: repo, ref, branch and path_prefix
+//  3. the active profile of the config file (see File.Select); its token
+//     source also wins over GITHUB_TOKEN / GH_TOKEN
+//  4. environment variables (GHREPO_PROFILE, GITHUB_TOKEN, GH_TOKEN,
+//     GHREPO_PROXY and the other transport variables, HTTPS_PROXY)
+//  5. the login stored by "ghrepo auth login", then credentials stored by
+//     other tools (gh, git, netrc); see FindToken for the full token order
+//  6. built-in defaults
+//
+// GHREPO_PROFILE only chooses the profile; --profile overrides it.
 type Config struct {
-	Token   string
-	APIBase string
-	Timeout time.Duration
-	JSON    bool
-	Verbose bool
+	Token       string
+	TokenSource string // where Token came from, e.g. "GH_TOKEN"; never the value
+	APIBase     string
+	Timeout     time.Duration
+	JSON        bool
+	Profile     string // name of the active profile, empty if none
+	Owner       string // default owner from the profile
+
+	// GitHub App authentication, from the --app-* flags or the profile.
+	// When AppID is set, requests use installation tokens instead of Token.
+	AppID             string
+	AppKeyFile        string
+	AppInstallationID int64
+
+	// HTTP transport, from the flags, the profile or GHREPO_PROXY,
+	// GHREPO_CA_FILE, GHREPO_CLIENT_CERT and GHREPO_CLIENT_KEY.
+	Proxy              string
+	CAFile             string
+	ClientCert         string
+	ClientKey          string
+	InsecureSkipVerify bool
+	DisableHTTP2       bool
 }
 
-// ResolveToken returns the token from the first available source:
-// flag value > GITHUB_TOKEN > GH_TOKEN.
+// ResolveToken returns the token for github.com from the first available
+// source, as FindToken finds it without a token command or profile.
 // Returns empty string if none are set.
 func ResolveToken(flagValue string) string {
-	if flagValue != "" {
-		return flagValue
-	}
-	if v := os.Getenv("GITHUB_TOKEN"); v != "" {
-		return v
-	}
-	if v := os.Getenv("GH_TOKEN"); v != "" {
-		return v
-	}
-	return ""
+	token, _, _ := FindToken(TokenOptions{Flag: flagValue, APIBase: DefaultAPIBase})
+	return token
 }
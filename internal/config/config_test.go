@@ -2,7 +2,10 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+
+	"githubRAGCli/internal/credstore"
 )
 
 func TestResolveToken_FlagTakesPrecedence(t *testing.T) {
@@ -26,7 +29,7 @@ func TestResolveToken_GitHubTokenOverGHToken(t *testing.T) {
 }
 
 func TestResolveToken_FallbackToGHToken(t *testing.T) {
-	os.Unsetenv("GITHUB_TOKEN")
+	t.Setenv("GITHUB_TOKEN", "")
 	t.Setenv("GH_TOKEN", "env-gh")
 
 	got := ResolveToken("")
@@ -36,11 +39,33 @@ func TestResolveToken_FallbackToGHToken(t *testing.T) {
 }
 
 func TestResolveToken_EmptyWhenNoneSet(t *testing.T) {
-	os.Unsetenv("GITHUB_TOKEN")
-	os.Unsetenv("GH_TOKEN")
+	isolateTokenSources(t, "")
+	t.Setenv("GHREPO_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
 
 	got := ResolveToken("")
 	if got != "" {
 		t.Errorf("expected empty, got %q", got)
 	}
 }
+
+func TestResolveToken_StoredLogin(t *testing.T) {
+	isolateTokenSources(t, "")
+	t.Setenv("GHREPO_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
+	store := &credstore.Store{Dir: CredentialsDir()}
+	if _, err := store.Save(credstore.Credential{Host: "github.com", Token: "from-login"}, credstore.StorageFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := ResolveToken(""); got != "from-login" {
+		t.Errorf("expected from-login, got %q", got)
+	}
+}
+
+func TestResolveToken_NetrcAfterStoredSources(t *testing.T) {
+	dir := isolateTokenSources(t, "")
+	os.WriteFile(filepath.Join(dir, "netrc"), []byte("machine github.com login me password from-netrc\n"), 0o600)
+
+	if got := ResolveToken(""); got != "from-netrc" {
+		t.Errorf("expected from-netrc, got %q", got)
+	}
+}
@@ -0,0 +1,463 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+// DefaultProfileName is used when no profile is selected and the file names
+// no default_profile.
+const DefaultProfileName = "default"
+
+// Profile is a named set of defaults for one GitHub host and identity.
+type Profile struct {
+	Name          string
+	Host          string        // web host used to auto-select the profile, e.g. ghe.example.com
+	APIBase       string        // API base URL
+	Token         string        // literal token; prefer TokenEnv
+	TokenEnv      string        // environment variable holding the token
+	TokenCommand  string        // shell command printing the token
+	Timeout       time.Duration // HTTP timeout
+	Format        string        // default --format
+	Owner         string        // owner used when the repo argument is just "repo"
+	OAuthClientID string        // OAuth App client ID for auth login
+
+	// GitHub App authentication; used instead of a token when AppID is set.
+	AppID             string // App ID or client ID
+	AppKeyFile        string // path to the App's PEM private key
+	AppInstallationID int64  // 0: discover it from the repository
+
+	// HTTP transport, for proxies and private CAs; see
+	// githubapi.TransportOptions.
+	Proxy              string // proxy URL; empty: HTTPS_PROXY and NO_PROXY apply
+	CAFile             string // PEM CA certificates trusted besides the system's
+	ClientCert         string // PEM client certificate for mTLS
+	ClientKey          string // its private key; empty: in ClientCert
+	InsecureSkipVerify bool   // do not verify server certificates
+	DisableHTTP2       bool
+}
+
+// HostName returns the web host the profile talks to: Host if set,
+// otherwise derived from APIBase (see HostFromAPIBase).
+func (p *Profile) HostName() string {
+	if p.Host != "" {
+		return strings.ToLower(p.Host)
+	}
+	return HostFromAPIBase(p.APIBase)
+}
+
+// ResolveToken returns the profile's token: the TokenEnv variable if it is
+// set and non-empty, else the literal Token.
+func (p *Profile) ResolveToken() string {
+	if p.TokenEnv != "" {
+		if v := os.Getenv(p.TokenEnv); v != "" {
+			return v
+		}
+	}
+	return p.Token
+}
+
+// profileKey binds a config key to a Profile field.
+type profileKey struct {
+	name   string
+	secret bool // redacted by List
+	get    func(p *Profile) string
+	set    func(p *Profile, v string) error
+}
+
+// profileKeys lists the settable profile keys in file order.
+var profileKeys = []profileKey{
+	{name: "host",
+		get: func(p *Profile) string { return p.Host },
+		set: func(p *Profile, v string) error { p.Host = v; return nil }},
+	{name: "api_base",
+		get: func(p *Profile) string { return p.APIBase },
+		set: func(p *Profile, v string) error {
+			if v != "" {
+				if u, err := url.Parse(v); err != nil || u.Scheme == "" || u.Host == "" {
+					return fmt.Errorf("api_base must be an absolute URL, got %q", v)
+				}
+			}
+			p.APIBase = strings.TrimRight(v, "/")
+			return nil
+		}},
+	{name: "token", secret: true,
+		get: func(p *Profile) string { return p.Token },
+		set: func(p *Profile, v string) error { p.Token = v; return nil }},
+	{name: "token_env",
+		get: func(p *Profile) string { return p.TokenEnv },
+		set: func(p *Profile, v string) error { p.TokenEnv = v; return nil }},
+	{name: "token_command",
+		get: func(p *Profile) string { return p.TokenCommand },
+		set: func(p *Profile, v string) error { p.TokenCommand = v; return nil }},
+	{name: "timeout",
+		get: func(p *Profile) string {
+			if p.Timeout == 0 {
+				return ""
+			}
+			return p.Timeout.String()
+		},
+		set: func(p *Profile, v string) error {
+			if v == "" {
+				p.Timeout = 0
+				return nil
+			}
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("timeout must be a positive duration such as 30s, got %q", v)
+			}
+			p.Timeout = d
+			return nil
+		}},
+	{name: "format",
+		get: func(p *Profile) string { return p.Format },
+		set: func(p *Profile, v string) error { p.Format = v; return nil }},
+	{name: "owner",
+		get: func(p *Profile) string { return p.Owner },
+		set: func(p *Profile, v string) error { p.Owner = v; return nil }},
+	{name: "oauth_client_id",
+		get: func(p *Profile) string { return p.OAuthClientID },
+		set: func(p *Profile, v string) error { p.OAuthClientID = v; return nil }},
+	{name: "app_id",
+		get: func(p *Profile) string { return p.AppID },
+		set: func(p *Profile, v string) error { p.AppID = v; return nil }},
+	{name: "app_key_file",
+		get: func(p *Profile) string { return p.AppKeyFile },
+		set: func(p *Profile, v string) error { p.AppKeyFile = v; return nil }},
+	{name: "app_installation_id",
+		get: func(p *Profile) string {
+			if p.AppInstallationID == 0 {
+				return ""
+			}
+			return strconv.FormatInt(p.AppInstallationID, 10)
+		},
+		set: func(p *Profile, v string) error {
+			if v == "" {
+				p.AppInstallationID = 0
+				return nil
+			}
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || id <= 0 {
+				return fmt.Errorf("app_installation_id must be a positive integer, got %q", v)
+			}
+			p.AppInstallationID = id
+			return nil
+		}},
+	{name: "proxy",
+		get: func(p *Profile) string { return p.Proxy },
+		set: func(p *Profile, v string) error { p.Proxy = v; return nil }},
+	{name: "ca_file",
+		get: func(p *Profile) string { return p.CAFile },
+		set: func(p *Profile, v string) error { p.CAFile = v; return nil }},
+	{name: "client_cert",
+		get: func(p *Profile) string { return p.ClientCert },
+		set: func(p *Profile, v string) error { p.ClientCert = v; return nil }},
+	{name: "client_key",
+		get: func(p *Profile) string { return p.ClientKey },
+		set: func(p *Profile, v string) error { p.ClientKey = v; return nil }},
+	boolKey("insecure_skip_verify", func(p *Profile) *bool { return &p.InsecureSkipVerify }),
+	boolKey("disable_http2", func(p *Profile) *bool { return &p.DisableHTTP2 }),
+}
+
+// boolKey is a profileKey for a bool field, which is unset when false.
+func boolKey(name string, field func(p *Profile) *bool) profileKey {
+	return profileKey{name: name,
+		get: func(p *Profile) string {
+			if !*field(p) {
+				return ""
+			}
+			return "true"
+		},
+		set: func(p *Profile, v string) error {
+			if v == "" {
+				*field(p) = false
+				return nil
+			}
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("%s must be true or false, got %q", name, v)
+			}
+			*field(p) = b
+			return nil
+		}}
+}
+
+// ProfileKeys returns the names of the per-profile settings.
+func ProfileKeys() []string {
+	names := make([]string, len(profileKeys))
+	for i, k := range profileKeys {
+		names[i] = k.name
+	}
+	return names
+}
+
+func lookupProfileKey(name string) (profileKey, bool) {
+	for _, k := range profileKeys {
+		if k.name == name {
+			return k, true
+		}
+	}
+	return profileKey{}, false
+}
+
+// File is the user configuration file.
+type File struct {
+	Path           string
+	DefaultProfile string
+	Profiles       map[string]*Profile
+}
+
+// FilePath returns the configuration file location: $GHREPO_CONFIG if set,
+// else ghrepo/config.yaml under $XDG_CONFIG_HOME (default ~/.config).
+func FilePath() string {
+	if p := os.Getenv("GHREPO_CONFIG"); p != "" {
+		return p
+	}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "ghrepo", "config.yaml")
+}
+
+// LoadFile reads the configuration file at path. A missing file yields an
+// empty File so that callers can Set and Save into it.
+func LoadFile(path string) (*File, error) {
+	f := &File{Path: path, Profiles: make(map[string]*Profile)}
+	if path == "" {
+		return f, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, clerrors.NewBadArgs(fmt.Sprintf("failed to read config %q", path), err)
+	}
+
+	doc, err := parseYAML(data)
+	if err != nil {
+		return nil, clerrors.NewBadArgs(fmt.Sprintf("invalid config %s: %v", path, err), nil)
+	}
+	for key, v := range doc {
+		switch key {
+		case "default_profile":
+			s, _ := v.(string)
+			f.DefaultProfile = s
+		case "profiles":
+			profiles, ok := v.(map[string]any)
+			if !ok {
+				return nil, clerrors.NewBadArgs(fmt.Sprintf("invalid config %s: profiles must be a mapping", path), nil)
+			}
+			for name, pv := range profiles {
+				fields, ok := pv.(map[string]any)
+				if !ok {
+					return nil, clerrors.NewBadArgs(fmt.Sprintf("invalid config %s: profile %q must be a mapping", path, name), nil)
+				}
+				p := &Profile{Name: name}
+				for field, fv := range fields {
+					k, ok := lookupProfileKey(field)
+					s, isString := fv.(string)
+					if !ok || !isString {
+						return nil, clerrors.NewBadArgs(fmt.Sprintf("invalid config %s: unknown setting %s.%s", path, name, field), nil)
+					}
+					if err := k.set(p, s); err != nil {
+						return nil, clerrors.NewBadArgs(fmt.Sprintf("invalid config %s: %s.%s: %v", path, name, field, err), nil)
+					}
+				}
+				f.Profiles[name] = p
+			}
+		default:
+			return nil, clerrors.NewBadArgs(fmt.Sprintf("invalid config %s: unknown key %q", path, key), nil)
+		}
+	}
+	return f, nil
+}
+
+// Save writes the file back to f.Path, readable only by the owner since it
+// may hold tokens.
+func (f *File) Save() error {
+	if f.Path == "" {
+		return clerrors.NewLocalWriteErr("cannot locate config directory: set GHREPO_CONFIG", nil)
+	}
+	var buf bytes.Buffer
+	if f.DefaultProfile != "" {
+		fmt.Fprintf(&buf, "default_profile: %s\n", yamlScalar(f.DefaultProfile))
+	}
+	if len(f.Profiles) > 0 {
+		buf.WriteString("profiles:\n")
+	}
+	for _, name := range f.ProfileNames() {
+		p := f.Profiles[name]
+		fmt.Fprintf(&buf, "  %s:", yamlScalar(name))
+		empty := true
+		for _, k := range profileKeys {
+			if v := k.get(p); v != "" {
+				if empty {
+					buf.WriteString("\n")
+					empty = false
+				}
+				fmt.Fprintf(&buf, "    %s: %s\n", k.name, yamlScalar(v))
+			}
+		}
+		if empty {
+			buf.WriteString(" {}\n")
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o700); err != nil {
+		return clerrors.NewLocalWriteErr("cannot create config directory", err)
+	}
+	tmp := f.Path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return clerrors.NewLocalWriteErr("cannot write config "+f.Path, err)
+	}
+	if err := os.Rename(tmp, f.Path); err != nil {
+		os.Remove(tmp)
+		return clerrors.NewLocalWriteErr("cannot write config "+f.Path, err)
+	}
+	return nil
+}
+
+// ProfileNames returns the profile names in sorted order.
+func (f *File) ProfileNames() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Select picks the active profile. An explicit name (from --profile or
+// GHREPO_PROFILE) must exist. Otherwise, when host is set, the first profile
+// for that host wins, or none. Without a host, default_profile is used, then
+// a profile named "default". It returns nil when nothing applies.
+func (f *File) Select(name, host string) (*Profile, error) {
+	if name != "" {
+		p, ok := f.Profiles[name]
+		if !ok {
+			return nil, clerrors.NewBadArgs(fmt.Sprintf("unknown profile %q (configured: %s)", name, f.profileList()), nil)
+		}
+		return p, nil
+	}
+	if host != "" {
+		host = strings.ToLower(host)
+		for _, n := range f.ProfileNames() {
+			if f.Profiles[n].HostName() == host {
+				return f.Profiles[n], nil
+			}
+		}
+		return nil, nil
+	}
+	if f.DefaultProfile != "" {
+		p, ok := f.Profiles[f.DefaultProfile]
+		if !ok {
+			return nil, clerrors.NewBadArgs(fmt.Sprintf("default_profile %q is not defined in %s", f.DefaultProfile, f.Path), nil)
+		}
+		return p, nil
+	}
+	return f.Profiles[DefaultProfileName], nil
+}
+
+func (f *File) profileList() string {
+	if len(f.Profiles) == 0 {
+		return "none"
+	}
+	return strings.Join(f.ProfileNames(), ", ")
+}
+
+// Setting is one key/value pair as shown by List.
+type Setting struct {
+	Key   string
+	Value string
+}
+
+// List returns every set value as "default_profile" and "<profile>.<key>"
+// pairs. Secrets are redacted.
+func (f *File) List() []Setting {
+	var out []Setting
+	if f.DefaultProfile != "" {
+		out = append(out, Setting{"default_profile", f.DefaultProfile})
+	}
+	for _, name := range f.ProfileNames() {
+		for _, k := range profileKeys {
+			v := k.get(f.Profiles[name])
+			if v == "" {
+				continue
+			}
+			if k.secret {
+				v = "********"
+			}
+			out = append(out, Setting{name + "." + k.name, v})
+		}
+	}
+	return out
+}
+
+// splitKey resolves "default_profile", "<profile>.<key>" or a bare "<key>",
+// which refers to profile current.
+func splitKey(key, current string) (profile string, k profileKey, err error) {
+	name, field, ok := strings.Cut(key, ".")
+	if !ok {
+		name, field = current, key
+	}
+	k, known := lookupProfileKey(field)
+	if name == "" || !known {
+		return "", profileKey{}, clerrors.NewBadArgs(fmt.Sprintf("unknown config key %q (want default_profile, or [<profile>.]%s)", key, strings.Join(ProfileKeys(), "|")), nil)
+	}
+	return name, k, nil
+}
+
+// Get returns the value of key. A bare profile key refers to profile
+// current. ok is false when the key is valid but unset.
+func (f *File) Get(key, current string) (value string, ok bool, err error) {
+	if key == "default_profile" {
+		return f.DefaultProfile, f.DefaultProfile != "", nil
+	}
+	name, k, err := splitKey(key, current)
+	if err != nil {
+		return "", false, err
+	}
+	p, exists := f.Profiles[name]
+	if !exists {
+		return "", false, nil
+	}
+	v := k.get(p)
+	return v, v != "", nil
+}
+
+// Set assigns key, creating the profile if needed. An empty value clears
+// the setting. A bare profile key refers to profile current.
+func (f *File) Set(key, value, current string) error {
+	if key == "default_profile" {
+		f.DefaultProfile = value
+		return nil
+	}
+	name, k, err := splitKey(key, current)
+	if err != nil {
+		return err
+	}
+	p, exists := f.Profiles[name]
+	if !exists {
+		p = &Profile{Name: name}
+	}
+	if err := k.set(p, value); err != nil {
+		return clerrors.NewBadArgs(err.Error(), nil)
+	}
+	f.Profiles[name] = p
+	return nil
+}
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	path := writeConfig(t, `default_profile: work
+profiles:
+  work:
+    host: ghe.example.com
+    api_base: https://ghe.example.com/api/v3/
+    token_env: GHE_TOKEN
+    timeout: 30s
+    format: yaml
+    owner: platform
+  personal:
+    token: ghp_literal
+`)
+	f, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := f.Profiles["work"]
+	if w == nil || w.APIBase != "https://ghe.example.com/api/v3" || w.Timeout != 30*time.Second || w.Owner != "platform" || w.Format != "yaml" {
+		t.Fatalf("work profile: %+v", w)
+	}
+	if f.DefaultProfile != "work" || f.Profiles["personal"].Token != "ghp_literal" {
+		t.Errorf("unexpected file: %+v", f)
+	}
+}
+
+func TestLoadFile_MissingIsEmpty(t *testing.T) {
+	f, err := LoadFile(filepath.Join(t.TempDir(), "nope.yaml"))
+	if err != nil || len(f.Profiles) != 0 {
+		t.Fatalf("got %+v, %v", f, err)
+	}
+}
+
+func TestLoadFile_Invalid(t *testing.T) {
+	for _, content := range []string{
+		"profiles:\n  work:\n    tokne: x\n",
+		"profiles:\n  work:\n    timeout: soon\n",
+		"profiles:\n  work:\n    api_base: not a url\n",
+		"profiles:\n  bot:\n    app_installation_id: abc\n",
+		"colour: blue\n",
+		"profiles: x\n",
+	} {
+		if _, err := LoadFile(writeConfig(t, content)); err == nil {
+			t.Errorf("expected error for %q", content)
+		}
+	}
+}
+
+func TestFile_SetSaveRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ghrepo", "config.yaml")
+	f, _ := LoadFile(path)
+	for _, kv := range [][2]string{
+		{"default_profile", "work"},
+		{"api_base", "https://ghe.example.com/api/v3"},
+		{"work.token", "secret: yes"},
+		{"personal.owner", "octocat"},
+		{"empty.owner", "x"},
+		{"empty.owner", ""},
+	} {
+		if err := f.Set(kv[0], kv[1], "work"); err != nil {
+			t.Fatalf("set %s: %v", kv[0], err)
+		}
+	}
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("config should be private: %v %v", info.Mode(), err)
+	}
+
+	g, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok, _ := g.Get("token", "work"); !ok || v != "secret: yes" {
+		t.Errorf("token: %q %v", v, ok)
+	}
+	if v, _, _ := g.Get("work.api_base", ""); v != "https://ghe.example.com/api/v3" {
+		t.Errorf("api_base: %q", v)
+	}
+	if _, ok := g.Profiles["empty"]; !ok {
+		t.Errorf("profile with no settings should survive a round trip")
+	}
+
+	var keys []string
+	for _, s := range g.List() {
+		keys = append(keys, s.Key+"="+s.Value)
+	}
+	want := "default_profile=work,personal.owner=octocat,work.api_base=https://ghe.example.com/api/v3,work.token=********"
+	if got := strings.Join(keys, ","); got != want {
+		t.Errorf("List:\ngot  %s\nwant %s", got, want)
+	}
+}
+
+func TestFile_SetUnknownKey(t *testing.T) {
+	f, _ := LoadFile("")
+	if err := f.Set("work.colour", "blue", "work"); err == nil {
+		t.Error("expected error for unknown setting")
+	}
+	if err := f.Set("timeout", "-1s", "work"); err == nil {
+		t.Error("expected error for negative timeout")
+	}
+	if err := f.Set("app_installation_id", "0", "work"); err == nil {
+		t.Error("expected error for zero installation ID")
+	}
+	if err := f.Set("bot.app_installation_id", "42", "work"); err != nil || f.Profiles["bot"].AppInstallationID != 42 {
+		t.Errorf("app_installation_id: %v", err)
+	}
+	if err := f.Set("insecure_skip_verify", "maybe", "work"); err == nil {
+		t.Error("expected error for non-boolean insecure_skip_verify")
+	}
+	if err := f.Set("disable_http2", "true", "work"); err != nil || !f.Profiles["work"].DisableHTTP2 {
+		t.Errorf("disable_http2: %v", err)
+	}
+	if v, ok, _ := f.Get("disable_http2", "work"); !ok || v != "true" {
+		t.Errorf("disable_http2 get: %q %v", v, ok)
+	}
+}
+
+func TestFile_Select(t *testing.T) {
+	f := &File{Profiles: map[string]*Profile{
+		"default": {Name: "default"},
+		"ghes":    {Name: "ghes", APIBase: "https://ghe.example.com/api/v3"},
+		"other":   {Name: "other", Host: "Code.Example.org"},
+	}}
+	tests := []struct {
+		name, host, want string
+	}{
+		{"ghes", "", "ghes"},
+		{"", "ghe.example.com", "ghes"},
+		{"", "code.example.org", "other"},
+		{"", "github.com", "default"},
+		{"", "", "default"},
+		{"", "unknown.example", ""},
+	}
+	for _, tt := range tests {
+		p, err := f.Select(tt.name, tt.host)
+		if err != nil {
+			t.Fatalf("Select(%q, %q): %v", tt.name, tt.host, err)
+		}
+		got := ""
+		if p != nil {
+			got = p.Name
+		}
+		if got != tt.want {
+			t.Errorf("Select(%q, %q) = %q, want %q", tt.name, tt.host, got, tt.want)
+		}
+	}
+
+	if _, err := f.Select("missing", ""); err == nil {
+		t.Error("expected error for unknown profile")
+	}
+	f.DefaultProfile = "ghes"
+	if p, _ := f.Select("", ""); p.Name != "ghes" {
+		t.Errorf("default_profile should win over \"default\", got %s", p.Name)
+	}
+}
+
+func TestProfile_ResolveToken(t *testing.T) {
+	p := &Profile{Token: "literal", TokenEnv: "GHREPO_TEST_TOKEN"}
+	t.Setenv("GHREPO_TEST_TOKEN", "")
+	if got := p.ResolveToken(); got != "literal" {
+		t.Errorf("got %q", got)
+	}
+	t.Setenv("GHREPO_TEST_TOKEN", "from-env")
+	if got := p.ResolveToken(); got != "from-env" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestFilePath(t *testing.T) {
+	t.Setenv("GHREPO_CONFIG", "")
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	if got := FilePath(); got != filepath.Join("/xdg", "ghrepo", "config.yaml") {
+		t.Errorf("got %q", got)
+	}
+	t.Setenv("GHREPO_CONFIG", "/explicit.yaml")
+	if got := FilePath(); got != "/explicit.yaml" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+// ProjectFileName is the project-local defaults file written by ghrepo init.
+const ProjectFileName = ".ghrepo.yaml"
+
+// Project holds the defaults of a project directory. They apply when a
+// command is run without the <owner/repo> argument.
+type Project struct {
+	Path       string // location of the file
+	Repo       string // "owner/repo" or "host/owner/repo"
+	Ref        string // default --ref for read commands
+	Branch     string // default --branch for put and rm
+	PathPrefix string // prepended to relative path arguments
+}
+
+// FindProject looks for ProjectFileName in dir and its parents and loads
+// the first one found. It returns nil when there is none.
+func FindProject(dir string) (*Project, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, nil
+	}
+	for {
+		path := filepath.Join(dir, ProjectFileName)
+		if _, err := os.Stat(path); err == nil {
+			return LoadProject(path)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// LoadProject reads the project file at path.
+func LoadProject(path string) (*Project, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, clerrors.NewBadArgs(fmt.Sprintf("failed to read project file %q", path), err)
+	}
+	doc, err := parseYAML(data)
+	if err != nil {
+		return nil, clerrors.NewBadArgs(fmt.Sprintf("invalid project file %s: %v", path, err), nil)
+	}
+	p := &Project{Path: path}
+	for key, v := range doc {
+		s, ok := v.(string)
+		if !ok {
+			return nil, clerrors.NewBadArgs(fmt.Sprintf("invalid project file %s: %s must be a string", path, key), nil)
+		}
+		switch key {
+		case "repo":
+			p.Repo = s
+		case "ref":
+			p.Ref = s
+		case "branch":
+			p.Branch = s
+		case "path_prefix":
+			p.PathPrefix = s
+		default:
+			return nil, clerrors.NewBadArgs(fmt.Sprintf("invalid project file %s: unknown key %q", path, key), nil)
+		}
+	}
+	if p.Repo == "" {
+		return nil, clerrors.NewBadArgs(fmt.Sprintf("invalid project file %s: repo is required", path), nil)
+	}
+	return p, nil
+}
+
+// Save writes the project file to p.Path.
+func (p *Project) Save() error {
+	var buf bytes.Buffer
+	buf.WriteString("# ghrepo project defaults, used when <owner/repo> is omitted.\n")
+	for _, kv := range [][2]string{
+		{"repo", p.Repo},
+		{"ref", p.Ref},
+		{"branch", p.Branch},
+		{"path_prefix", p.PathPrefix},
+	} {
+		if kv[1] != "" {
+			fmt.Fprintf(&buf, "%s: %s\n", kv[0], yamlScalar(kv[1]))
+		}
+	}
+	if err := os.WriteFile(p.Path, buf.Bytes(), 0o644); err != nil {
+		return clerrors.NewLocalWriteErr("failed to write "+ProjectFileName, err)
+	}
+	return nil
+}
+
+// ResolvePath applies PathPrefix to a path argument. A leading "/" makes
+// the path relative to the repository root instead.
+func (p *Project) ResolvePath(arg string) string {
+	if strings.HasPrefix(arg, "/") {
+		return path.Clean(strings.TrimLeft(arg, "/"))
+	}
+	if p.PathPrefix == "" {
+		return arg
+	}
+	return path.Join(p.PathPrefix, arg)
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindProject_WalksUp(t *testing.T) {
+	root := t.TempDir()
+	want := &Project{
+		Path:       filepath.Join(root, ProjectFileName),
+		Repo:       "ghe.example.com/octo/hello",
+		Ref:        "v1.0",
+		Branch:     "main",
+		PathPrefix: "docs",
+	}
+	if err := want.Save(); err != nil {
+		t.Fatal(err)
+	}
+	deep := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(deep, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := FindProject(deep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || *got != *want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestFindProject_None(t *testing.T) {
+	p, err := FindProject(t.TempDir())
+	if err != nil || p != nil {
+		t.Errorf("got %+v, %v", p, err)
+	}
+}
+
+func TestLoadProject_Invalid(t *testing.T) {
+	for _, content := range []string{
+		"ref: main\n",
+		"repo: o/r\nbranches: x\n",
+		"repo:\n  nested: x\n",
+	} {
+		path := filepath.Join(t.TempDir(), ProjectFileName)
+		os.WriteFile(path, []byte(content), 0o644)
+		if _, err := LoadProject(path); err == nil {
+			t.Errorf("expected error for %q", content)
+		}
+	}
+}
+
+func TestProject_ResolvePath(t *testing.T) {
+	p := &Project{PathPrefix: "docs"}
+	tests := map[string]string{
+		"README.md":  "docs/README.md",
+		".":          "docs",
+		"/README.md": "README.md",
+		"/":          ".",
+	}
+	for arg, want := range tests {
+		if got := p.ResolvePath(arg); got != want {
+			t.Errorf("ResolvePath(%q) = %q, want %q", arg, got, want)
+		}
+	}
+	if got := (&Project{}).ResolvePath("a/b"); got != "a/b" {
+		t.Errorf("no prefix: got %q", got)
+	}
+}
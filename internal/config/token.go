@@ -0,0 +1,285 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"githubRAGCli/internal/credstore"
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+// externalTimeout bounds the programs run to obtain a token.
+const externalTimeout = 30 * time.Second
+
+// TokenOptions describes where FindToken may look for a token.
+type TokenOptions struct {
+	Flag    string   // --token
+	Command string   // --token-command, or the profile's token_command
+	Profile *Profile // active profile, may be nil
+	APIBase string   // API base URL the token is for
+}
+
+// tokenSource is one place a token can come from. find returns "" when the
+// source has no token; only explicitly requested sources return errors.
+type tokenSource struct {
+	name string
+	find func(o TokenOptions) (string, error)
+}
+
+// tokenSources lists the token sources in precedence order.
+var tokenSources = []tokenSource{
+	{"--token", func(o TokenOptions) (string, error) { return o.Flag, nil }},
+	{"token command", runTokenCommand},
+	{"profile", func(o TokenOptions) (string, error) {
+		if o.Profile == nil {
+			return "", nil
+		}
+		return o.Profile.ResolveToken(), nil
+	}},
+	{"GITHUB_TOKEN", func(TokenOptions) (string, error) { return os.Getenv("GITHUB_TOKEN"), nil }},
+	{"GH_TOKEN", func(TokenOptions) (string, error) { return os.Getenv("GH_TOKEN"), nil }},
+	{"auth login", func(o TokenOptions) (string, error) { return storedToken(HostFromAPIBase(o.APIBase)) }},
+	{"gh hosts.yml", func(o TokenOptions) (string, error) { return ghHostsToken(HostFromAPIBase(o.APIBase)), nil }},
+	{"git credential", func(o TokenOptions) (string, error) { return gitCredentialToken(HostFromAPIBase(o.APIBase)), nil }},
+	{"netrc", func(o TokenOptions) (string, error) { return netrcToken(o.APIBase), nil }},
+}
+
+// TokenSourceNames returns the token sources in the order FindToken tries them.
+func TokenSourceNames() []string {
+	names := make([]string, len(tokenSources))
+	for i, s := range tokenSources {
+		names[i] = s.name
+	}
+	return names
+}
+
+// FindToken returns the first token found and a description of its source,
+// trying --token, the token command, the profile, GITHUB_TOKEN, GH_TOKEN,
+// the login stored by "ghrepo auth login", the gh CLI's hosts.yml,
+// "git credential fill" and ~/.netrc in turn.
+// token is empty when no source has one.
+func FindToken(o TokenOptions) (token, source string, err error) {
+	for _, s := range tokenSources {
+		token, err := s.find(o)
+		if err != nil {
+			return "", s.name, err
+		}
+		if token = strings.TrimSpace(token); token != "" {
+			source := s.name
+			if s.name == "profile" {
+				source = "profile " + o.Profile.Name
+			}
+			return token, source, nil
+		}
+	}
+	return "", "", nil
+}
+
+// HostFromAPIBase returns the web host for an API base URL:
+// "https://api.github.com" gives "github.com" and
+// "https://ghe.example.com/api/v3" gives "ghe.example.com".
+func HostFromAPIBase(apiBase string) string {
+	if apiBase == "" {
+		return "github.com"
+	}
+	u, err := url.Parse(apiBase)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "api.github.com" {
+		return "github.com"
+	}
+	return strings.TrimPrefix(host, "api.")
+}
+
+// CredentialsDir returns the directory of the credentials stored by
+// "ghrepo auth login": the directory of the config file.
+func CredentialsDir() string {
+	path := FilePath()
+	if path == "" {
+		return ""
+	}
+	return filepath.Dir(path)
+}
+
+// storedToken returns the token stored by "ghrepo auth login" for host.
+func storedToken(host string) (string, error) {
+	dir := CredentialsDir()
+	if dir == "" || host == "" {
+		return "", nil
+	}
+	c, err := credstore.New(dir).Load(host)
+	if c == nil || err != nil {
+		return "", err
+	}
+	return c.Token, nil
+}
+
+// runTokenCommand runs the configured token command through the shell and
+// returns its trimmed standard output.
+func runTokenCommand(o TokenOptions) (string, error) {
+	command := o.Command
+	if command == "" && o.Profile != nil {
+		command = o.Profile.TokenCommand
+	}
+	if command == "" {
+		return "", nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), externalTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", clerrors.NewAuthFailure("token command failed: "+msg, nil)
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", clerrors.NewAuthFailure("token command printed no token", nil)
+	}
+	return token, nil
+}
+
+// ghHostsToken reads the gh CLI's stored token for host from hosts.yml.
+// Tokens that gh keeps in the system keyring are not visible here.
+func ghHostsToken(host string) string {
+	dir := os.Getenv("GH_CONFIG_DIR")
+	if dir == "" {
+		base := os.Getenv("XDG_CONFIG_HOME")
+		if base == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return ""
+			}
+			base = filepath.Join(home, ".config")
+		}
+		dir = filepath.Join(base, "gh")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "hosts.yml"))
+	if err != nil {
+		return ""
+	}
+	doc, err := parseYAML(data)
+	if err != nil {
+		return ""
+	}
+	entry, _ := doc[host].(map[string]any)
+	if entry == nil {
+		return ""
+	}
+	if token, _ := entry["oauth_token"].(string); token != "" {
+		return token
+	}
+	// Newer gh versions nest tokens per user.
+	user, _ := entry["user"].(string)
+	users, _ := entry["users"].(map[string]any)
+	u, _ := users[user].(map[string]any)
+	token, _ := u["oauth_token"].(string)
+	return token
+}
+
+// gitCredentialToken asks git's credential helpers for the password stored
+// for https://host, without prompting.
+func gitCredentialToken(host string) string {
+	if host == "" {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), externalTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", "credential", "fill")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("protocol=https\nhost=%s\n\n", host))
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=never", "GIT_ASKPASS=")
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		if v, ok := strings.CutPrefix(sc.Text(), "password="); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// netrcToken returns the password of the netrc entry for the API host or
+// the web host, from $NETRC or ~/.netrc.
+func netrcToken(apiBase string) string {
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	entries := parseNetrc(string(data))
+
+	var hosts []string
+	if u, err := url.Parse(apiBase); err == nil && u.Host != "" {
+		hosts = append(hosts, strings.ToLower(u.Hostname()))
+	}
+	hosts = append(hosts, HostFromAPIBase(apiBase))
+	for _, h := range hosts {
+		if p, ok := entries[h]; ok {
+			return p
+		}
+	}
+	return entries[""] // "default" entry
+}
+
+// parseNetrc maps machine names to passwords; the default entry is keyed "".
+func parseNetrc(data string) map[string]string {
+	entries := make(map[string]string)
+	fields := strings.Fields(data)
+	machine, inEntry := "", false
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			if i+1 < len(fields) {
+				i++
+				machine, inEntry = strings.ToLower(fields[i]), true
+			}
+		case "default":
+			machine, inEntry = "", true
+		case "password":
+			if i+1 < len(fields) {
+				i++
+				if _, seen := entries[machine]; inEntry && !seen {
+					entries[machine] = fields[i]
+				}
+			}
+		case "macdef":
+			// A macro runs to the next blank line, which Fields cannot see;
+			// stop rather than misread it.
+			return entries
+		}
+	}
+	return entries
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"githubRAGCli/internal/credstore"
+)
+
+// isolateTokenSources points every token source at empty temp locations and
+// puts a fake git first on PATH, whose credential helper prints gitPassword
+// (or fails when it is empty).
+func isolateTokenSources(t *testing.T, gitPassword string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake git needs a POSIX shell")
+	}
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("GH_CONFIG_DIR", filepath.Join(dir, "gh"))
+	t.Setenv("NETRC", filepath.Join(dir, "netrc"))
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+	t.Setenv("GHREPO_CONFIG", "")
+	t.Setenv(credstore.PassphraseEnv, "")
+
+	bin := filepath.Join(dir, "bin")
+	os.MkdirAll(bin, 0o755)
+	script := "#!/bin/sh\nexit 1\n"
+	if gitPassword != "" {
+		// Echo the request's host back so the test can check it was asked
+		// about the right host.
+		script = "#!/bin/sh\nwhile read line && [ -n \"$line\" ]; do case $line in host=*) h=${line#host=};; esac; done\n" +
+			"[ \"$h\" = ghe.example.com ] || exit 1\nprintf 'protocol=https\\nhost=%s\\nusername=x\\npassword=" + gitPassword + "\\n' \"$h\"\n"
+	}
+	os.WriteFile(filepath.Join(bin, "git"), []byte(script), 0o755)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return dir
+}
+
+func TestFindToken_Order(t *testing.T) {
+	dir := isolateTokenSources(t, "from-git")
+	const ghes = "https://ghe.example.com/api/v3"
+
+	os.WriteFile(filepath.Join(dir, "netrc"), []byte("machine other login a password x\nmachine ghe.example.com login me password from-netrc\n"), 0o600)
+	check := func(o TokenOptions, wantToken, wantSource string) {
+		t.Helper()
+		token, source, err := FindToken(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != wantToken || source != wantSource {
+			t.Errorf("got %q from %q, want %q from %q", token, source, wantToken, wantSource)
+		}
+	}
+
+	check(TokenOptions{APIBase: ghes}, "from-git", "git credential")
+
+	isolateTokenSources(t, "")
+	os.WriteFile(os.Getenv("NETRC"), []byte("machine ghe.example.com login me password from-netrc\n"), 0o600)
+	check(TokenOptions{APIBase: ghes}, "from-netrc", "netrc")
+
+	os.MkdirAll(os.Getenv("GH_CONFIG_DIR"), 0o755)
+	os.WriteFile(filepath.Join(os.Getenv("GH_CONFIG_DIR"), "hosts.yml"), []byte("ghe.example.com:\n    oauth_token: from-gh\n    user: me\n"), 0o600)
+	check(TokenOptions{APIBase: ghes}, "from-gh", "gh hosts.yml")
+	check(TokenOptions{APIBase: "https://api.github.com"}, "", "")
+
+	store := &credstore.Store{Dir: CredentialsDir()}
+	if _, err := store.Save(credstore.Credential{Host: "ghe.example.com", Token: "from-login"}, credstore.StorageFile); err != nil {
+		t.Fatal(err)
+	}
+	check(TokenOptions{APIBase: ghes}, "from-login", "auth login")
+
+	t.Setenv("GH_TOKEN", "from-gh-token")
+	check(TokenOptions{APIBase: ghes}, "from-gh-token", "GH_TOKEN")
+	t.Setenv("GITHUB_TOKEN", "from-github-token")
+	check(TokenOptions{APIBase: ghes}, "from-github-token", "GITHUB_TOKEN")
+
+	work := &Profile{Name: "work", Token: "from-profile"}
+	check(TokenOptions{APIBase: ghes, Profile: work}, "from-profile", "profile work")
+	check(TokenOptions{APIBase: ghes, Profile: work, Command: "echo ' from-command '"}, "from-command", "token command")
+	check(TokenOptions{APIBase: ghes, Profile: &Profile{Name: "p", TokenCommand: "echo from-profile-command"}}, "from-profile-command", "token command")
+	check(TokenOptions{APIBase: ghes, Flag: "from-flag", Command: "exit 1"}, "from-flag", "--token")
+}
+
+func TestFindToken_CommandFailure(t *testing.T) {
+	isolateTokenSources(t, "")
+	for _, cmd := range []string{"echo denied >&2; exit 2", "true"} {
+		if _, _, err := FindToken(TokenOptions{Command: cmd}); err == nil {
+			t.Errorf("%q: expected error", cmd)
+		}
+	}
+}
+
+func TestGHHostsToken_UserEntry(t *testing.T) {
+	isolateTokenSources(t, "")
+	os.MkdirAll(os.Getenv("GH_CONFIG_DIR"), 0o755)
+	hosts := "github.com:\n    user: me\n    git_protocol: https\n    users:\n        me:\n            oauth_token: gho_user\n"
+	os.WriteFile(filepath.Join(os.Getenv("GH_CONFIG_DIR"), "hosts.yml"), []byte(hosts), 0o600)
+	if got := ghHostsToken("github.com"); got != "gho_user" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestParseNetrc(t *testing.T) {
+	got := parseNetrc(`machine API.github.com
+  login octocat
+  password ghp_one
+machine api.github.com login x password ghp_dup
+default login anon password ghp_default
+`)
+	if got["api.github.com"] != "ghp_one" || got[""] != "ghp_default" {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestHostFromAPIBase(t *testing.T) {
+	tests := map[string]string{
+		"":                               "github.com",
+		"https://api.github.com":         "github.com",
+		"https://ghe.example.com/api/v3": "ghe.example.com",
+		"https://api.acme.ghe.com":       "acme.ghe.com",
+		"http://127.0.0.1:8080":          "127.0.0.1",
+	}
+	for in, want := range tests {
+		if got := HostFromAPIBase(in); got != want {
+			t.Errorf("HostFromAPIBase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML reads the subset of YAML used by ghrepo's configuration files:
+// nested block mappings of scalars, with comments and quoted strings.
+// Scalars are returned as strings; nested mappings as map[string]any.
+// Sequences and flow collections other than {} are rejected.
+func parseYAML(data []byte) (map[string]any, error) {
+	type frame struct {
+		indent int
+		m      map[string]any
+	}
+	root := make(map[string]any)
+	stack := []frame{{indent: 0, m: root}}
+	var pending map[string]any // mapping opened by "key:" on the previous line
+
+	for n, raw := range strings.Split(string(data), "\n") {
+		lineNo := n + 1
+		line := strings.TrimRight(raw, " \r")
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || trimmed[0] == '#' || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") || strings.HasPrefix(line, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", lineNo)
+		}
+		indent := len(line) - len(trimmed)
+
+		if pending != nil && indent > stack[len(stack)-1].indent {
+			stack = append(stack, frame{indent: indent, m: pending})
+		} else {
+			for len(stack) > 1 && indent < stack[len(stack)-1].indent {
+				stack = stack[:len(stack)-1]
+			}
+			if indent != stack[len(stack)-1].indent {
+				return nil, fmt.Errorf("line %d: unexpected indentation", lineNo)
+			}
+		}
+		pending = nil
+
+		if trimmed == "-" || strings.HasPrefix(trimmed, "- ") {
+			return nil, fmt.Errorf("line %d: lists are not supported", lineNo)
+		}
+		key, rest, err := splitYAMLKey(trimmed)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		value, err := parseYAMLScalar(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+
+		m := stack[len(stack)-1].m
+		switch {
+		case rest == "" || rest[0] == '#':
+			// "key:" opens a nested mapping; an empty one if nothing follows.
+			pending = make(map[string]any)
+			m[key] = pending
+		case value == "{}" && !isQuoted(rest):
+			m[key] = map[string]any{}
+		case !isQuoted(rest) && (strings.HasPrefix(value, "[") || strings.HasPrefix(value, "{")):
+			return nil, fmt.Errorf("line %d: flow collections are not supported", lineNo)
+		default:
+			m[key] = value
+		}
+	}
+	return root, nil
+}
+
+// splitYAMLKey splits "key: value" into the key and the raw value text.
+func splitYAMLKey(s string) (key, rest string, err error) {
+	if s[0] == '"' || s[0] == '\'' {
+		k, n, err := unquoteYAML(s)
+		if err != nil {
+			return "", "", err
+		}
+		after := s[n:]
+		if !strings.HasPrefix(after, ":") {
+			return "", "", fmt.Errorf("expected ':' after key %q", k)
+		}
+		return k, strings.TrimSpace(after[1:]), nil
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == ':' && (i == len(s)-1 || s[i+1] == ' ') {
+			return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:]), nil
+		}
+	}
+	return "", "", fmt.Errorf("expected 'key: value', got %q", s)
+}
+
+// parseYAMLScalar decodes a plain or quoted scalar and drops a trailing
+// comment. "~" and "null" decode to the empty string.
+func parseYAMLScalar(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	if s[0] == '"' || s[0] == '\'' {
+		v, n, err := unquoteYAML(s)
+		if err != nil {
+			return "", err
+		}
+		if tail := strings.TrimSpace(s[n:]); tail != "" && tail[0] != '#' {
+			return "", fmt.Errorf("unexpected text after quoted string: %q", tail)
+		}
+		return v, nil
+	}
+	if s[0] == '#' {
+		return "", nil
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	if s == "~" || s == "null" {
+		return "", nil
+	}
+	return s, nil
+}
+
+// unquoteYAML decodes the quoted string at the start of s and returns it
+// with the number of bytes consumed.
+func unquoteYAML(s string) (string, int, error) {
+	q := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case q == '"' && s[i] == '\\':
+			i++
+		case s[i] == q && q == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++ // '' is an escaped quote
+		case s[i] == q:
+			if q == '\'' {
+				return strings.ReplaceAll(s[1:i], "''", "'"), i + 1, nil
+			}
+			v, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", 0, fmt.Errorf("invalid string %s", s[:i+1])
+			}
+			return v, i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string %s", s)
+}
+
+func isQuoted(s string) bool {
+	return s != "" && (s[0] == '"' || s[0] == '\'')
+}
+
+// yamlScalar formats s for writing, double-quoting it when YAML would read
+// it as another type or misparse it.
+func yamlScalar(s string) string {
+	if s == "" || strings.TrimSpace(s) != s || strings.ContainsAny(s, "\"'\\\n\t") ||
+		strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") ||
+		strings.ContainsRune("-?:,[]{}#&*!|>%@`", rune(s[0])) {
+		return strconv.Quote(s)
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "y", "n", "null", "~":
+		return strconv.Quote(s)
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return strconv.Quote(s)
+	}
+	return s
+}
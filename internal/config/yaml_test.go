@@ -0,0 +1,70 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	src := `# ghrepo config
+---
+default_profile: work   # trailing comment
+profiles:
+  work:
+    api_base: "https://ghe.example.com/api/v3"
+    token_env: 'GHE_TOKEN'
+    owner: it''s
+  empty:
+  personal: {}
+"quoted key": "a # not a comment"
+nothing: ~
+`
+	got, err := parseYAML([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"default_profile": "work",
+		"profiles": map[string]any{
+			"work": map[string]any{
+				"api_base":  "https://ghe.example.com/api/v3",
+				"token_env": "GHE_TOKEN",
+				"owner":     "it''s",
+			},
+			"empty":    map[string]any{},
+			"personal": map[string]any{},
+		},
+		"quoted key": "a # not a comment",
+		"nothing":    "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %#v\nwant %#v", got, want)
+	}
+}
+
+func TestParseYAML_Errors(t *testing.T) {
+	for _, src := range []string{
+		"a:\n  - x\n",
+		"a: [1, 2]\n",
+		"a:\n    b: 1\n  c: 2\n",
+		"\ta: 1\n",
+		"just text\n",
+		"a: \"open\n",
+	} {
+		if _, err := parseYAML([]byte(src)); err == nil {
+			t.Errorf("expected error for %q", src)
+		}
+	}
+}
+
+func TestYAMLScalar_RoundTrip(t *testing.T) {
+	for _, s := range []string{"plain", "", "yes", "30", "a: b", "x #y", "-dash", "https://h/api/v3", "it's", " pad"} {
+		doc, err := parseYAML([]byte("k: " + yamlScalar(s) + "\n"))
+		if err != nil {
+			t.Fatalf("%q: %v", s, err)
+		}
+		if doc["k"] != s {
+			t.Errorf("round trip %q: got %q via %s", s, doc["k"], yamlScalar(s))
+		}
+	}
+}
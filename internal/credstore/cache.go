@@ -0,0 +1,175 @@
+package credstore
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+// CacheFileName holds short-lived tokens kept between runs, such as GitHub
+// App installation tokens. They are sealed like file-stored logins, never
+// put in the keyring.
+const CacheFileName = "token-cache.json"
+
+// cacheEntry is a sealed token and when it expires.
+type cacheEntry struct {
+	entry
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type cacheIndex struct {
+	Tokens map[string]*cacheEntry `json:"tokens"`
+}
+
+func (s *Store) loadCache() (*cacheIndex, error) {
+	idx := &cacheIndex{Tokens: make(map[string]*cacheEntry)}
+	data, err := os.ReadFile(s.path(CacheFileName))
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, clerrors.NewBadArgs("failed to read "+s.path(CacheFileName), err)
+	}
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, clerrors.NewBadArgs("invalid "+s.path(CacheFileName), err)
+	}
+	if idx.Tokens == nil {
+		idx.Tokens = make(map[string]*cacheEntry)
+	}
+	return idx, nil
+}
+
+// saveCache writes idx, dropping the tokens that have expired.
+func (s *Store) saveCache(idx *cacheIndex) error {
+	now := time.Now()
+	for key, e := range idx.Tokens {
+		if !e.ExpiresAt.After(now) {
+			delete(idx.Tokens, key)
+		}
+	}
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return clerrors.NewLocalWriteErr("failed to encode token cache", err)
+	}
+	return writePrivate(s.path(CacheFileName), append(data, '\n'))
+}
+
+// LoadToken returns the token cached under key and when it expires, or an
+// empty token when there is none. Expired tokens are returned too; the
+// caller decides how much validity it needs.
+func (s *Store) LoadToken(key string) (string, time.Time, error) {
+	idx, err := s.loadCache()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	e := idx.Tokens[key]
+	if e == nil {
+		return "", time.Time{}, nil
+	}
+	token, err := s.open(&e.entry, key)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, e.ExpiresAt, nil
+}
+
+// SaveToken caches token under key until expires, encrypted and bound to
+// key as file-stored logins are.
+func (s *Store) SaveToken(key, token string, expires time.Time) error {
+	idx, err := s.loadCache()
+	if err != nil {
+		return err
+	}
+	e := &cacheEntry{entry: entry{Storage: StorageFile, CreatedAt: time.Now().UTC()}, ExpiresAt: expires}
+	if err := s.seal(&e.entry, key, token); err != nil {
+		return err
+	}
+	idx.Tokens[key] = e
+	return s.saveCache(idx)
+}
+
+// DeleteToken removes the token cached under key, if any.
+func (s *Store) DeleteToken(key string) error {
+	idx, err := s.loadCache()
+	if err != nil {
+		return err
+	}
+	if idx.Tokens[key] == nil {
+		return nil
+	}
+	delete(idx.Tokens, key)
+	return s.saveCache(idx)
+}
+
+// ServerCacheFileName records which server each API base runs, so that a
+// GitHub Enterprise Server is not detected again on every run.
+const ServerCacheFileName = "server-cache.json"
+
+// ServerCacheTTL is how long a detected server is trusted; a GHES upgrade
+// is noticed at most this long after it happens.
+const ServerCacheTTL = 24 * time.Hour
+
+// CachedServer is what was detected about the server behind an API base.
+type CachedServer struct {
+	Enterprise bool      `json:"enterprise"`
+	Version    string    `json:"version,omitempty"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+type serverIndex struct {
+	Servers map[string]*CachedServer `json:"servers"`
+}
+
+func (s *Store) loadServers() (*serverIndex, error) {
+	idx := &serverIndex{Servers: make(map[string]*CachedServer)}
+	data, err := os.ReadFile(s.path(ServerCacheFileName))
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, clerrors.NewBadArgs("failed to read "+s.path(ServerCacheFileName), err)
+	}
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, clerrors.NewBadArgs("invalid "+s.path(ServerCacheFileName), err)
+	}
+	if idx.Servers == nil {
+		idx.Servers = make(map[string]*CachedServer)
+	}
+	return idx, nil
+}
+
+// LoadServer returns the server cached for apiBase, or nil when there is
+// none or it was detected more than ServerCacheTTL ago.
+func (s *Store) LoadServer(apiBase string) (*CachedServer, error) {
+	idx, err := s.loadServers()
+	if err != nil {
+		return nil, err
+	}
+	srv := idx.Servers[apiBase]
+	if srv == nil || time.Since(srv.DetectedAt) > ServerCacheTTL {
+		return nil, nil
+	}
+	return srv, nil
+}
+
+// SaveServer caches srv for apiBase, dropping the entries that are too old
+// to be used.
+func (s *Store) SaveServer(apiBase string, srv CachedServer) error {
+	idx, err := s.loadServers()
+	if err != nil {
+		return err
+	}
+	for key, e := range idx.Servers {
+		if time.Since(e.DetectedAt) > ServerCacheTTL {
+			delete(idx.Servers, key)
+		}
+	}
+	idx.Servers[apiBase] = &srv
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return clerrors.NewLocalWriteErr("failed to encode server cache", err)
+	}
+	return writePrivate(s.path(ServerCacheFileName), append(data, '\n'))
+}
@@ -0,0 +1,83 @@
+package credstore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore_TokenCache(t *testing.T) {
+	s := newTestStore(t, nil)
+	expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	if err := s.SaveToken("app 1 installation 42", "ghs_secret", expires); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveToken("app 1 installation 7", "ghs_stale", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(s.Dir, CacheFileName))
+	if strings.Contains(string(data), "ghs_secret") {
+		t.Errorf("token cached in plain text:\n%s", data)
+	}
+	if strings.Contains(string(data), "installation 7") {
+		t.Errorf("expired token kept:\n%s", data)
+	}
+
+	token, got, err := s.LoadToken("app 1 installation 42")
+	if err != nil || token != "ghs_secret" || !got.Equal(expires) {
+		t.Errorf("load: %q %v %v", token, got, err)
+	}
+	if token, _, err := s.LoadToken("app 2 installation 42"); token != "" || err != nil {
+		t.Errorf("unknown key: %q %v", token, err)
+	}
+
+	if err := s.DeleteToken("app 1 installation 42"); err != nil {
+		t.Fatal(err)
+	}
+	if token, _, _ := s.LoadToken("app 1 installation 42"); token != "" {
+		t.Errorf("deleted token still cached: %q", token)
+	}
+	if logins, err := s.List(); err != nil || len(logins) != 0 {
+		t.Errorf("cached tokens must not show up as logins: %v %v", logins, err)
+	}
+}
+
+func TestStore_TokenCacheBoundToKey(t *testing.T) {
+	s := newTestStore(t, nil)
+	s.SaveToken("app 1 installation 42", "ghs_secret", time.Now().Add(time.Hour))
+	path := filepath.Join(s.Dir, CacheFileName)
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, []byte(strings.Replace(string(data), "installation 42", "installation 43", 1)), 0o600)
+	if _, _, err := s.LoadToken("app 1 installation 43"); err == nil {
+		t.Error("expected decryption failure for a moved entry")
+	}
+}
+
+func TestStore_ServerCache(t *testing.T) {
+	s := newTestStore(t, nil)
+	const base = "https://ghe.example.com/api/v3"
+	if srv, err := s.LoadServer(base); srv != nil || err != nil {
+		t.Fatalf("empty cache: %+v %v", srv, err)
+	}
+
+	if err := s.SaveServer(base, CachedServer{Enterprise: true, Version: "3.12.4", DetectedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveServer("https://old.example.com/api/v3", CachedServer{Enterprise: true, DetectedAt: time.Now().Add(-2 * ServerCacheTTL)}); err != nil {
+		t.Fatal(err)
+	}
+
+	srv, err := s.LoadServer(base)
+	if err != nil || srv == nil || !srv.Enterprise || srv.Version != "3.12.4" {
+		t.Errorf("load: %+v %v", srv, err)
+	}
+	if srv, _ := s.LoadServer("https://old.example.com/api/v3"); srv != nil {
+		t.Errorf("stale server returned: %+v", srv)
+	}
+	if srv, _ := s.LoadServer("https://other.example.com/api/v3"); srv != nil {
+		t.Errorf("unknown server: %+v", srv)
+	}
+}
@@ -0,0 +1,140 @@
+package credstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// keyringService is the service name tokens are stored under.
+const keyringService = "ghrepo"
+
+// keyringTimeout bounds keyring commands, which may wait for the user to
+// unlock the keyring.
+const keyringTimeout = time.Minute
+
+// ErrNotFound is returned by Keyring.Get when no secret is stored.
+var ErrNotFound = errors.New("secret not found in keyring")
+
+// Keyring stores one secret per host in an operating system keyring.
+type Keyring interface {
+	Name() string
+	Get(host string) (string, error)
+	Set(host, secret string) error
+	Delete(host string) error
+}
+
+// SystemKeyring returns the keyring of the current system: the macOS
+// keychain through security(1), or the Secret Service (GNOME Keyring,
+// KWallet) through secret-tool(1) when a D-Bus session is available.
+// It returns nil when neither is usable.
+func SystemKeyring() Keyring {
+	switch runtime.GOOS {
+	case "darwin":
+		if path, err := exec.LookPath("security"); err == nil {
+			return macKeychain{path}
+		}
+	case "linux", "freebsd", "openbsd", "netbsd":
+		if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+			return nil
+		}
+		if path, err := exec.LookPath("secret-tool"); err == nil {
+			return secretService{path}
+		}
+	}
+	return nil
+}
+
+// runKeyring runs a keyring command with stdin and returns its standard
+// output without the trailing newline, and its exit code. The error carries
+// the command's stderr.
+func runKeyring(stdin string, name string, args ...string) (string, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), keyringTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		code := -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			code = exitErr.ExitCode()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", code, errors.New(msg)
+		}
+		return "", code, err
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), 0, nil
+}
+
+// macKeychain uses the login keychain through the security tool.
+type macKeychain struct{ path string }
+
+func (k macKeychain) Name() string { return "macOS keychain" }
+
+func (k macKeychain) Get(host string) (string, error) {
+	out, code, err := runKeyring("", k.path, "find-generic-password", "-s", keyringService, "-a", host, "-w")
+	if code == 44 { // errSecItemNotFound
+		return "", ErrNotFound
+	}
+	return out, err
+}
+
+func (k macKeychain) Set(host, secret string) error {
+	// Pass the secret on stdin (interactive mode) rather than as an
+	// argument, where other users could see it in the process list.
+	cmd := fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+		quoteSecurity(keyringService), quoteSecurity(host), quoteSecurity(secret))
+	_, _, err := runKeyring(cmd, k.path, "-i")
+	return err
+}
+
+func (k macKeychain) Delete(host string) error {
+	_, code, err := runKeyring("", k.path, "delete-generic-password", "-s", keyringService, "-a", host)
+	if code == 44 {
+		return nil
+	}
+	return err
+}
+
+// quoteSecurity quotes s for the command line read by "security -i".
+func quoteSecurity(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// secretService uses the freedesktop Secret Service through secret-tool.
+type secretService struct{ path string }
+
+func (k secretService) Name() string { return "Secret Service" }
+
+func (k secretService) Get(host string) (string, error) {
+	out, _, err := runKeyring("", k.path, "lookup", "service", keyringService, "host", host)
+	if err == nil && out == "" {
+		return "", ErrNotFound
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// secret-tool exits 1 without a message when nothing matches.
+		return "", ErrNotFound
+	}
+	return out, err
+}
+
+func (k secretService) Set(host, secret string) error {
+	_, _, err := runKeyring(secret, k.path, "store", "--label", "ghrepo: "+host, "service", keyringService, "host", host)
+	return err
+}
+
+func (k secretService) Delete(host string) error {
+	_, _, err := runKeyring("", k.path, "clear", "service", keyringService, "host", host)
+	return err
+}
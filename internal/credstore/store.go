@@ -0,0 +1,336 @@
+// Package credstore keeps the OAuth tokens obtained by "ghrepo auth login",
+// one per host, in the system keyring or in an encrypted file, and caches
+// short-lived tokens such as GitHub App installation tokens.
+package credstore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+// Storage backends for Save. StorageAuto uses the keyring when there is one
+// and falls back to the encrypted file.
+const (
+	StorageAuto    = ""
+	StorageKeyring = "keyring"
+	StorageFile    = "file"
+)
+
+const (
+	// FileName is the credential index, kept next to the config file.
+	FileName = "credentials.json"
+	// KeyFileName holds the random key that encrypts file-stored tokens
+	// when no passphrase is set. Kept next to FileName, it only obfuscates
+	// them against casual reading.
+	KeyFileName = "credentials.key"
+
+	// PassphraseEnv, when set, derives the file encryption key from a
+	// passphrase instead of the key file.
+	PassphraseEnv = "GHREPO_CREDENTIALS_PASSPHRASE"
+
+	pbkdf2Iterations = 600_000
+)
+
+// Credential is a stored login.
+type Credential struct {
+	Host      string
+	User      string
+	Token     string // empty in List results
+	Scopes    string
+	Storage   string // StorageKeyring or StorageFile
+	CreatedAt time.Time
+}
+
+// Store reads and writes the credentials under Dir.
+type Store struct {
+	Dir     string
+	Keyring Keyring // nil when no keyring is available
+}
+
+// New returns a Store in dir using the system keyring.
+func New(dir string) *Store {
+	return &Store{Dir: dir, Keyring: SystemKeyring()}
+}
+
+// entry is the on-disk form of a Credential. Token holds the sealed token
+// for file storage and is empty for keyring storage.
+type entry struct {
+	User      string    `json:"user,omitempty"`
+	Scopes    string    `json:"scopes,omitempty"`
+	Storage   string    `json:"storage"`
+	CreatedAt time.Time `json:"created_at"`
+	Token     string    `json:"token,omitempty"`
+	Salt      string    `json:"salt,omitempty"` // set when the key comes from a passphrase
+}
+
+type index struct {
+	Hosts map[string]*entry `json:"hosts"`
+}
+
+func (s *Store) path(name string) string { return filepath.Join(s.Dir, name) }
+
+func (s *Store) load() (*index, error) {
+	idx := &index{Hosts: make(map[string]*entry)}
+	data, err := os.ReadFile(s.path(FileName))
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, clerrors.NewBadArgs("failed to read "+s.path(FileName), err)
+	}
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, clerrors.NewBadArgs("invalid "+s.path(FileName), err)
+	}
+	if idx.Hosts == nil {
+		idx.Hosts = make(map[string]*entry)
+	}
+	return idx, nil
+}
+
+func (s *Store) save(idx *index) error {
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return clerrors.NewLocalWriteErr("failed to encode credentials", err)
+	}
+	return writePrivate(s.path(FileName), append(data, '\n'))
+}
+
+// writePrivate writes data to path with mode 0600, via a temporary file.
+func writePrivate(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return clerrors.NewLocalWriteErr("cannot create config directory", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return clerrors.NewLocalWriteErr("cannot write "+path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return clerrors.NewLocalWriteErr("cannot write "+path, err)
+	}
+	return nil
+}
+
+// Save stores c, replacing any login for c.Host, and returns it with
+// Storage set to where the token went.
+func (s *Store) Save(c Credential, storage string) (Credential, error) {
+	idx, err := s.load()
+	if err != nil {
+		return c, err
+	}
+	e := &entry{User: c.User, Scopes: c.Scopes, CreatedAt: c.CreatedAt}
+
+	switch storage {
+	case StorageAuto, StorageKeyring:
+		if s.Keyring == nil {
+			if storage == StorageKeyring {
+				return c, clerrors.NewBadArgs("no system keyring available: use file storage", nil)
+			}
+			break
+		}
+		err := s.Keyring.Set(c.Host, c.Token)
+		if err == nil {
+			e.Storage = StorageKeyring
+			break
+		}
+		if storage == StorageKeyring {
+			return c, clerrors.NewLocalWriteErr("failed to store token in "+s.Keyring.Name(), err)
+		}
+	case StorageFile:
+	default:
+		return c, clerrors.NewBadArgs(fmt.Sprintf("unknown storage %q (want keyring or file)", storage), nil)
+	}
+
+	if e.Storage == "" {
+		e.Storage = StorageFile
+		if err := s.seal(e, c.Host, c.Token); err != nil {
+			return c, err
+		}
+		if old := idx.Hosts[c.Host]; old != nil && old.Storage == StorageKeyring && s.Keyring != nil {
+			s.Keyring.Delete(c.Host)
+		}
+	}
+	idx.Hosts[c.Host] = e
+	c.Storage = e.Storage
+	return c, s.save(idx)
+}
+
+// Load returns the stored login for host, or nil when there is none.
+func (s *Store) Load(host string) (*Credential, error) {
+	idx, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	e := idx.Hosts[host]
+	if e == nil {
+		return nil, nil
+	}
+	c := e.credential(host)
+	switch e.Storage {
+	case StorageKeyring:
+		if s.Keyring == nil {
+			return nil, clerrors.NewAuthFailure(fmt.Sprintf("the login for %s is stored in a system keyring that is not available: run 'ghrepo auth login' again", host), nil)
+		}
+		token, err := s.Keyring.Get(host)
+		if errors.Is(err, ErrNotFound) {
+			return nil, clerrors.NewAuthFailure(fmt.Sprintf("the login for %s is missing from the %s: run 'ghrepo auth login' again", host, s.Keyring.Name()), nil)
+		}
+		if err != nil {
+			return nil, clerrors.NewAuthFailure("failed to read token from "+s.Keyring.Name(), err)
+		}
+		c.Token = token
+	default:
+		if c.Token, err = s.open(e, host); err != nil {
+			return nil, err
+		}
+	}
+	return &c, nil
+}
+
+// Delete removes the login for host. It reports whether there was one.
+func (s *Store) Delete(host string) (bool, error) {
+	idx, err := s.load()
+	if err != nil {
+		return false, err
+	}
+	e := idx.Hosts[host]
+	if e == nil {
+		return false, nil
+	}
+	if e.Storage == StorageKeyring && s.Keyring != nil {
+		if err := s.Keyring.Delete(host); err != nil {
+			return false, clerrors.NewLocalWriteErr("failed to remove token from "+s.Keyring.Name(), err)
+		}
+	}
+	delete(idx.Hosts, host)
+	return true, s.save(idx)
+}
+
+// List returns the stored logins sorted by host, without their tokens.
+func (s *Store) List() ([]Credential, error) {
+	idx, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	hosts := make([]string, 0, len(idx.Hosts))
+	for h := range idx.Hosts {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+	out := make([]Credential, len(hosts))
+	for i, h := range hosts {
+		out[i] = idx.Hosts[h].credential(h)
+	}
+	return out, nil
+}
+
+func (e *entry) credential(host string) Credential {
+	return Credential{Host: host, User: e.User, Scopes: e.Scopes, Storage: e.Storage, CreatedAt: e.CreatedAt}
+}
+
+// seal encrypts token into e with AES-256-GCM, bound to host.
+func (s *Store) seal(e *entry, host, token string) error {
+	var salt []byte
+	if os.Getenv(PassphraseEnv) != "" {
+		salt = make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return clerrors.NewLocalWriteErr("cannot generate credentials salt", err)
+		}
+		e.Salt = base64.StdEncoding.EncodeToString(salt)
+	}
+	aead, err := s.cipher(salt, true)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return clerrors.NewLocalWriteErr("cannot generate credentials nonce", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(token), []byte(host))
+	e.Token = base64.StdEncoding.EncodeToString(sealed)
+	return nil
+}
+
+// open decrypts the token sealed in e.
+func (s *Store) open(e *entry, host string) (string, error) {
+	var salt []byte
+	if e.Salt != "" {
+		if os.Getenv(PassphraseEnv) == "" {
+			return "", clerrors.NewAuthFailure(fmt.Sprintf("the login for %s is encrypted with a passphrase: set %s", host, PassphraseEnv), nil)
+		}
+		salt, _ = base64.StdEncoding.DecodeString(e.Salt)
+	}
+	aead, err := s.cipher(salt, false)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(e.Token)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", clerrors.NewAuthFailure(fmt.Sprintf("the stored login for %s is corrupt: run 'ghrepo auth login' again", host), nil)
+	}
+	n := aead.NonceSize()
+	plain, err := aead.Open(nil, sealed[:n], sealed[n:], []byte(host))
+	if err != nil {
+		return "", clerrors.NewAuthFailure(fmt.Sprintf("cannot decrypt the stored login for %s (wrong key or passphrase): run 'ghrepo auth login' again", host), nil)
+	}
+	return string(plain), nil
+}
+
+// cipher returns the AEAD for file-stored tokens. With a salt the key is
+// derived from the passphrase; otherwise it is read from the key file,
+// which is created when create is set.
+func (s *Store) cipher(salt []byte, create bool) (cipher.AEAD, error) {
+	var key []byte
+	if salt != nil {
+		key = pbkdf2.Key([]byte(os.Getenv(PassphraseEnv)), salt, pbkdf2Iterations, 32, sha256.New)
+	} else {
+		var err error
+		if key, err = s.fileKey(create); err != nil {
+			return nil, err
+		}
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, clerrors.NewAuthFailure("invalid credentials key", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// fileKey reads the 32-byte key from KeyFileName, generating it first when
+// create is set and the file does not exist.
+func (s *Store) fileKey(create bool) ([]byte, error) {
+	path := s.path(KeyFileName)
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err := base64.StdEncoding.DecodeString(string(data))
+		if err != nil || len(key) != 32 {
+			return nil, clerrors.NewAuthFailure("invalid credentials key "+path, nil)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) || !create {
+		return nil, clerrors.NewAuthFailure("cannot read credentials key "+path, err)
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, clerrors.NewLocalWriteErr("cannot generate credentials key", err)
+	}
+	if err := writePrivate(path, []byte(base64.StdEncoding.EncodeToString(key))); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
@@ -0,0 +1,190 @@
+package credstore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeKeyring is an in-memory Keyring; fail makes Set return an error.
+type fakeKeyring struct {
+	secrets map[string]string
+	fail    bool
+}
+
+func (k *fakeKeyring) Name() string { return "fake keyring" }
+
+func (k *fakeKeyring) Get(host string) (string, error) {
+	s, ok := k.secrets[host]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return s, nil
+}
+
+func (k *fakeKeyring) Set(host, secret string) error {
+	if k.fail {
+		return errors.New("keyring locked")
+	}
+	k.secrets[host] = secret
+	return nil
+}
+
+func (k *fakeKeyring) Delete(host string) error {
+	delete(k.secrets, host)
+	return nil
+}
+
+func newTestStore(t *testing.T, kr Keyring) *Store {
+	t.Helper()
+	t.Setenv(PassphraseEnv, "")
+	return &Store{Dir: filepath.Join(t.TempDir(), "ghrepo"), Keyring: kr}
+}
+
+var testCred = Credential{Host: "github.com", User: "octocat", Token: "gho_secret", Scopes: "repo", CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+func TestStore_FileRoundTrip(t *testing.T) {
+	s := newTestStore(t, nil)
+	saved, err := s.Save(testCred, StorageAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Storage != StorageFile {
+		t.Errorf("storage: got %q, want file without a keyring", saved.Storage)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(s.Dir, FileName))
+	if strings.Contains(string(data), "gho_secret") {
+		t.Errorf("token stored in plain text:\n%s", data)
+	}
+	for _, name := range []string{FileName, KeyFileName} {
+		if info, err := os.Stat(filepath.Join(s.Dir, name)); err != nil || info.Mode().Perm() != 0o600 {
+			t.Errorf("%s should be private: %v", name, err)
+		}
+	}
+
+	got, err := s.Load("github.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Token != "gho_secret" || got.User != "octocat" || got.Scopes != "repo" || !got.CreatedAt.Equal(testCred.CreatedAt) {
+		t.Errorf("loaded %+v", got)
+	}
+	if c, err := s.Load("ghe.example.com"); c != nil || err != nil {
+		t.Errorf("unknown host: %v %v", c, err)
+	}
+}
+
+func TestStore_FileBoundToHost(t *testing.T) {
+	s := newTestStore(t, nil)
+	s.Save(testCred, StorageFile)
+	// Move the sealed token to another host; decryption must fail.
+	path := filepath.Join(s.Dir, FileName)
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, []byte(strings.Replace(string(data), `"github.com"`, `"evil.example.com"`, 1)), 0o600)
+	if _, err := s.Load("evil.example.com"); err == nil {
+		t.Error("expected decryption failure for a moved entry")
+	}
+}
+
+func TestStore_Passphrase(t *testing.T) {
+	s := newTestStore(t, nil)
+	t.Setenv(PassphraseEnv, "hunter2")
+	if _, err := s.Save(testCred, StorageFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(s.Dir, KeyFileName)); !os.IsNotExist(err) {
+		t.Error("passphrase mode should not create a key file")
+	}
+	if c, err := s.Load("github.com"); err != nil || c.Token != "gho_secret" {
+		t.Fatalf("load: %v %v", c, err)
+	}
+
+	t.Setenv(PassphraseEnv, "wrong")
+	if _, err := s.Load("github.com"); err == nil || !strings.Contains(err.Error(), "wrong key or passphrase") {
+		t.Errorf("wrong passphrase: %v", err)
+	}
+	t.Setenv(PassphraseEnv, "")
+	if _, err := s.Load("github.com"); err == nil || !strings.Contains(err.Error(), PassphraseEnv) {
+		t.Errorf("missing passphrase: %v", err)
+	}
+}
+
+func TestStore_Keyring(t *testing.T) {
+	kr := &fakeKeyring{secrets: map[string]string{}}
+	s := newTestStore(t, kr)
+	saved, err := s.Save(testCred, StorageAuto)
+	if err != nil || saved.Storage != StorageKeyring {
+		t.Fatalf("save: %+v %v", saved, err)
+	}
+	if kr.secrets["github.com"] != "gho_secret" {
+		t.Errorf("keyring: %v", kr.secrets)
+	}
+	data, _ := os.ReadFile(filepath.Join(s.Dir, FileName))
+	if strings.Contains(string(data), `"token"`) {
+		t.Errorf("index should not hold keyring tokens:\n%s", data)
+	}
+	if c, err := s.Load("github.com"); err != nil || c.Token != "gho_secret" {
+		t.Fatalf("load: %v %v", c, err)
+	}
+
+	// Switching the host to file storage removes the keyring copy.
+	if _, err := s.Save(testCred, StorageFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := kr.secrets["github.com"]; ok {
+		t.Error("keyring entry should be removed")
+	}
+}
+
+func TestStore_KeyringFallback(t *testing.T) {
+	kr := &fakeKeyring{secrets: map[string]string{}, fail: true}
+	s := newTestStore(t, kr)
+	if saved, err := s.Save(testCred, StorageAuto); err != nil || saved.Storage != StorageFile {
+		t.Fatalf("auto should fall back to file: %+v %v", saved, err)
+	}
+	if _, err := s.Save(testCred, StorageKeyring); err == nil {
+		t.Error("explicit keyring storage should fail")
+	}
+	if _, err := newTestStore(t, nil).Save(testCred, StorageKeyring); err == nil {
+		t.Error("keyring storage without a keyring should fail")
+	}
+	if _, err := s.Save(testCred, "vault"); err == nil {
+		t.Error("expected error for unknown storage")
+	}
+}
+
+func TestStore_DeleteAndList(t *testing.T) {
+	kr := &fakeKeyring{secrets: map[string]string{}}
+	s := newTestStore(t, kr)
+	s.Save(testCred, StorageKeyring)
+	ghe := testCred
+	ghe.Host = "ghe.example.com"
+	s.Save(ghe, StorageFile)
+
+	list, err := s.List()
+	if err != nil || len(list) != 2 || list[0].Host != "ghe.example.com" || list[1].Storage != StorageKeyring {
+		t.Fatalf("list: %+v %v", list, err)
+	}
+	for _, c := range list {
+		if c.Token != "" {
+			t.Errorf("List must not return tokens: %+v", c)
+		}
+	}
+
+	if ok, err := s.Delete("github.com"); !ok || err != nil {
+		t.Fatalf("delete: %v %v", ok, err)
+	}
+	if len(kr.secrets) != 0 {
+		t.Error("delete should clear the keyring")
+	}
+	if ok, _ := s.Delete("github.com"); ok {
+		t.Error("second delete should report nothing removed")
+	}
+	if list, _ := s.List(); len(list) != 1 {
+		t.Errorf("list after delete: %+v", list)
+	}
+}
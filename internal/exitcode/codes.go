@@ -1,15 +1,22 @@
 package exitcode
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Exit codes aligned with docs/USAGE.md.
 const (
 	ExitOK             = 0
+	ExitGeneral        = 1
 	ExitAuthFailure    = 10
 	ExitPermission     = 11
 	ExitNotFound       = 12
@@ -18,27 +25,86 @@ const (
 	ExitRateLimit      = 15
 	ExitLocalWriteErr  = 16
 	ExitUserAbort      = 17
+	ExitVerifyFailed   = 18
+	ExitConflict       = 19
+	ExitValidation     = 20
+	ExitServer         = 21
+	ExitSecondaryLimit = 22
 )
 
 // Category classifies an error for exit-code mapping.
 type Category int
 
 const (
-	CatAuthFailure   Category = iota // 401 or missing token
-	CatPermission                     // 403 permission denied
+	CatAuthFailure    Category = iota // 401 or missing token
+	CatPermission                     // 403 permission denied, 451
 	CatNotFound                       // 404
 	CatBadArgs                        // invalid CLI arguments
 	CatTransport                      // timeout / network
-	CatRateLimit                      // 403 rate-limited
+	CatRateLimit                      // 403/429 primary rate limit
 	CatLocalWriteErr                  // local I/O failure
 	CatUserAbort                      // user cancelled operation
+	CatVerifyFailed                   // local files drifted from a manifest
+	CatConflict                       // 409, e.g. a stale blob SHA
+	CatValidation                     // 422 rejected request
+	CatServer                         // 5xx
+	CatSecondaryLimit                 // 403/429 secondary (abuse) rate limit
 )
 
+// CodeInfo describes one exit code.
+type CodeInfo struct {
+	Code        int
+	Category    string // empty for success and general errors
+	Description string
+}
+
+// categories is the authoritative category table, indexed by Category.
+var categories = [...]CodeInfo{
+	CatAuthFailure:    {ExitAuthFailure, "auth_failure", "Auth failure (missing/invalid token)"},
+	CatPermission:     {ExitPermission, "permission", "Permission denied or unavailable for legal reasons"},
+	CatNotFound:       {ExitNotFound, "not_found", "Repository, ref or path not found"},
+	CatBadArgs:        {ExitBadArgs, "bad_args", "Bad arguments"},
+	CatTransport:      {ExitTransport, "transport", "Network/timeout error"},
+	CatRateLimit:      {ExitRateLimit, "rate_limit", "Rate limited"},
+	CatLocalWriteErr:  {ExitLocalWriteErr, "local_write", "Local write failure"},
+	CatUserAbort:      {ExitUserAbort, "user_abort", "User cancelled operation"},
+	CatVerifyFailed:   {ExitVerifyFailed, "verify_failed", "Local files drifted from manifest (verify)"},
+	CatConflict:       {ExitConflict, "conflict", "Conflict, e.g. the file changed since it was read"},
+	CatValidation:     {ExitValidation, "validation", "Request rejected by GitHub validation (HTTP 422)"},
+	CatServer:         {ExitServer, "server", "GitHub server error (HTTP 5xx)"},
+	CatSecondaryLimit: {ExitSecondaryLimit, "secondary_rate_limit", "Secondary rate limit; retry after a pause"},
+}
+
+// Codes returns every exit code ghrepo can return, in ascending order.
+func Codes() []CodeInfo {
+	codes := []CodeInfo{
+		{ExitOK, "", "Success"},
+		{ExitGeneral, "", "Other error (e.g. unknown command or flag)"},
+	}
+	return append(codes, categories[:]...)
+}
+
+// String returns the stable machine-readable name used in JSON errors.
+func (c Category) String() string {
+	if c < 0 || int(c) >= len(categories) {
+		return "unknown"
+	}
+	return categories[c].Category
+}
+
 // CLIError is the single error type that reaches main and maps to an exit code.
 type CLIError struct {
 	Cat     Category
 	Message string
 	Err     error
+
+	// Set when the error comes from a GitHub API response.
+	Status           int           // HTTP status code
+	APIMessage       string        // GitHub's "message" field
+	DocumentationURL string        // GitHub's "documentation_url" field
+	RequestID        string        // X-GitHub-Request-Id header
+	RateLimitReset   time.Time     // X-RateLimit-Reset, zero if absent
+	RetryAfter       time.Duration // Retry-After, zero if absent
 }
 
 func (e *CLIError) Error() string {
@@ -52,26 +118,10 @@ func (e *CLIError) Unwrap() error { return e.Err }
 
 // ExitCode returns the numeric exit code for this error.
 func (e *CLIError) ExitCode() int {
-	switch e.Cat {
-	case CatAuthFailure:
-		return ExitAuthFailure
-	case CatPermission:
-		return ExitPermission
-	case CatNotFound:
-		return ExitNotFound
-	case CatBadArgs:
-		return ExitBadArgs
-	case CatTransport:
-		return ExitTransport
-	case CatRateLimit:
-		return ExitRateLimit
-	case CatLocalWriteErr:
-		return ExitLocalWriteErr
-	case CatUserAbort:
-		return ExitUserAbort
-	default:
-		return 1
+	if e.Cat < 0 || int(e.Cat) >= len(categories) {
+		return ExitGeneral
 	}
+	return categories[e.Cat].Code
 }
 
 // Convenience constructors.
@@ -108,23 +158,111 @@ func NewUserAbort(msg string, err error) *CLIError {
 	return &CLIError{Cat: CatUserAbort, Message: msg, Err: err}
 }
 
+func NewVerifyFailed(msg string, err error) *CLIError {
+	return &CLIError{Cat: CatVerifyFailed, Message: msg, Err: err}
+}
+
+func NewConflict(msg string, err error) *CLIError {
+	return &CLIError{Cat: CatConflict, Message: msg, Err: err}
+}
+
+func NewValidation(msg string, err error) *CLIError {
+	return &CLIError{Cat: CatValidation, Message: msg, Err: err}
+}
+
+func NewServer(msg string, err error) *CLIError {
+	return &CLIError{Cat: CatServer, Message: msg, Err: err}
+}
+
+func NewSecondaryLimit(msg string, err error) *CLIError {
+	return &CLIError{Cat: CatSecondaryLimit, Message: msg, Err: err}
+}
+
 // ClassifyHTTP converts an HTTP status code plus optional context into a CLIError.
 // rateLimited should be true when response headers indicate rate limiting.
+// body is GitHub's error message, or the raw body when it is not JSON.
 func ClassifyHTTP(status int, rateLimited bool, body string) *CLIError {
 	switch {
 	case status == 401:
 		return NewAuthFailure("authentication failed: invalid or expired token", nil)
-	case status == 403 && rateLimited:
+	case (status == 403 || status == 429) && isSecondaryLimit(body):
+		return NewSecondaryLimit("secondary rate limit exceeded: "+body, nil)
+	case status == 403 && rateLimited, status == 429:
 		return NewRateLimit("rate limit exceeded", nil)
 	case status == 403:
 		return NewPermission("permission denied: insufficient token scope", nil)
 	case status == 404:
 		return &CLIError{Cat: CatNotFound, Message: "not found: " + body}
+	case status == 409:
+		return NewConflict("conflict: "+body, nil)
+	case status == 422:
+		return NewValidation("validation failed: "+body, nil)
+	case status == 451:
+		return NewPermission("unavailable for legal reasons: "+body, nil)
+	case status >= 500:
+		return NewServer(fmt.Sprintf("GitHub server error (HTTP %d): %s", status, body), nil)
 	default:
 		return &CLIError{Cat: CatTransport, Message: fmt.Sprintf("unexpected HTTP %d: %s", status, body)}
 	}
 }
 
+func isSecondaryLimit(msg string) bool {
+	msg = strings.ToLower(msg)
+	return strings.Contains(msg, "secondary rate limit") || strings.Contains(msg, "abuse detection")
+}
+
+// ClassifyResponse converts a non-success API response into a CLIError that
+// carries the status, GitHub's error message and documentation link, the
+// request ID and the rate-limit reset time.
+func ClassifyResponse(status int, header http.Header, body []byte) *CLIError {
+	var apiErr struct {
+		Message          string `json:"message"`
+		DocumentationURL string `json:"documentation_url"`
+		Errors           []struct {
+			Field   string `json:"field"`
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	msg := strings.TrimSpace(string(body))
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+		msg = apiErr.Message
+		// 422 responses explain what was rejected in "errors".
+		var details []string
+		for _, d := range apiErr.Errors {
+			switch {
+			case d.Message != "":
+				details = append(details, d.Message)
+			case d.Field != "":
+				details = append(details, d.Field+" "+d.Code)
+			}
+		}
+		if len(details) > 0 {
+			msg += " (" + strings.Join(details, "; ") + ")"
+		}
+	}
+
+	rateLimited := header.Get("X-RateLimit-Remaining") == "0"
+	var e *CLIError
+	if (status == 403 || status == 429) && !rateLimited && header.Get("Retry-After") != "" {
+		// Only secondary limits send Retry-After without exhausting the quota.
+		e = NewSecondaryLimit("secondary rate limit exceeded: "+msg, nil)
+	} else {
+		e = ClassifyHTTP(status, rateLimited, msg)
+	}
+	e.Status = status
+	e.APIMessage = apiErr.Message
+	e.DocumentationURL = apiErr.DocumentationURL
+	e.RequestID = header.Get("X-GitHub-Request-Id")
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		e.RateLimitReset = time.Unix(reset, 0).UTC()
+	}
+	if secs, err := strconv.Atoi(header.Get("Retry-After")); err == nil && secs >= 0 {
+		e.RetryAfter = time.Duration(secs) * time.Second
+	}
+	return e
+}
+
 // ClassifyTransportErr converts a Go network/timeout error into a CLIError.
 func ClassifyTransportErr(err error) *CLIError {
 	if err == nil {
@@ -134,6 +272,11 @@ func ClassifyTransportErr(err error) *CLIError {
 	if errors.As(err, &netErr) && netErr.Timeout() {
 		return NewTransport("request timed out", err)
 	}
+	var unknownCA x509.UnknownAuthorityError
+	var certErr *tls.CertificateVerificationError
+	if errors.As(err, &unknownCA) || errors.As(err, &certErr) {
+		return NewTransport("TLS certificate verification failed (for a private CA, pass it with --ca-file)", err)
+	}
 	if isNetworkErr(err) {
 		return NewTransport("network error", err)
 	}
@@ -1,7 +1,9 @@
 package exitcode
 
 import (
+	"net/http"
 	"testing"
+	"time"
 )
 
 func TestExitCode_Mapping(t *testing.T) {
@@ -17,6 +19,12 @@ func TestExitCode_Mapping(t *testing.T) {
 		{CatRateLimit, 15},
 		{CatLocalWriteErr, 16},
 		{CatUserAbort, 17},
+		{CatVerifyFailed, 18},
+		{CatConflict, 19},
+		{CatValidation, 20},
+		{CatServer, 21},
+		{CatSecondaryLimit, 22},
+		{Category(99), 1},
 	}
 	for _, tt := range tests {
 		e := &CLIError{Cat: tt.cat, Message: "test"}
@@ -36,7 +44,13 @@ func TestClassifyHTTP(t *testing.T) {
 		{403, false, ExitPermission},
 		{403, true, ExitRateLimit},
 		{404, false, ExitNotFound},
-		{500, false, ExitTransport},
+		{409, false, ExitConflict},
+		{422, false, ExitValidation},
+		{429, false, ExitRateLimit},
+		{451, false, ExitPermission},
+		{500, false, ExitServer},
+		{503, false, ExitServer},
+		{418, false, ExitTransport},
 	}
 	for _, tt := range tests {
 		e := ClassifyHTTP(tt.status, tt.rateLimited, "body")
@@ -47,6 +61,94 @@ func TestClassifyHTTP(t *testing.T) {
 	}
 }
 
+func TestClassifyResponse(t *testing.T) {
+	h := http.Header{}
+	h.Set("X-GitHub-Request-Id", "C0DE:42")
+	h.Set("X-RateLimit-Remaining", "12")
+	h.Set("X-RateLimit-Reset", "1700000000")
+	e := ClassifyResponse(404, h, []byte(`{"message":"Not Found","documentation_url":"https://docs.github.com/rest/repos/contents"}`))
+
+	if e.Cat != CatNotFound || e.Message != "not found: Not Found" {
+		t.Errorf("got %v %q", e.Cat, e.Message)
+	}
+	if e.Status != 404 || e.APIMessage != "Not Found" || e.RequestID != "C0DE:42" {
+		t.Errorf("missing response details: %+v", e)
+	}
+	if e.DocumentationURL != "https://docs.github.com/rest/repos/contents" {
+		t.Errorf("documentation URL: %q", e.DocumentationURL)
+	}
+	if !e.RateLimitReset.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("reset: %v", e.RateLimitReset)
+	}
+
+	// Non-JSON bodies are kept verbatim in the message.
+	e = ClassifyResponse(502, http.Header{}, []byte("Bad Gateway\n"))
+	if e.Message != "GitHub server error (HTTP 502): Bad Gateway" || e.APIMessage != "" || !e.RateLimitReset.IsZero() {
+		t.Errorf("got %+v", e)
+	}
+
+	h.Set("X-RateLimit-Remaining", "0")
+	if e := ClassifyResponse(403, h, nil); e.Cat != CatRateLimit {
+		t.Errorf("exhausted quota should be a rate limit, got %v", e.Cat)
+	}
+}
+
+func TestClassifyResponse_SecondaryLimit(t *testing.T) {
+	body := []byte(`{"message":"You have exceeded a secondary rate limit. Please wait a few minutes before you try again."}`)
+	if e := ClassifyResponse(403, http.Header{}, body); e.Cat != CatSecondaryLimit {
+		t.Errorf("403 with secondary message: got %v", e.Cat)
+	}
+
+	h := http.Header{}
+	h.Set("Retry-After", "60")
+	e := ClassifyResponse(429, h, []byte(`{"message":"slow down"}`))
+	if e.Cat != CatSecondaryLimit || e.RetryAfter != time.Minute {
+		t.Errorf("429 with Retry-After: got %v, retry after %v", e.Cat, e.RetryAfter)
+	}
+
+	h.Set("X-RateLimit-Remaining", "0")
+	if e := ClassifyResponse(429, h, nil); e.Cat != CatRateLimit {
+		t.Errorf("exhausted quota takes precedence: got %v", e.Cat)
+	}
+}
+
+func TestClassifyResponse_ValidationDetails(t *testing.T) {
+	body := []byte(`{"message":"Validation Failed","errors":[{"resource":"Commit","field":"message","code":"missing_field"},{"message":"path is too long"}]}`)
+	e := ClassifyResponse(422, http.Header{}, body)
+	if want := "validation failed: Validation Failed (message missing_field; path is too long)"; e.Message != want {
+		t.Errorf("got %q, want %q", e.Message, want)
+	}
+}
+
+func TestCodes(t *testing.T) {
+	codes := Codes()
+	if codes[0].Code != ExitOK || codes[1].Code != ExitGeneral {
+		t.Errorf("table should start with 0 and 1: %+v", codes[:2])
+	}
+	seen := make(map[string]bool)
+	for i, c := range codes {
+		if i > 0 && c.Code <= codes[i-1].Code {
+			t.Errorf("codes not strictly ascending at %d: %+v", i, c)
+		}
+		if c.Description == "" || (i >= 2 && (c.Category == "" || seen[c.Category])) {
+			t.Errorf("incomplete or duplicate entry: %+v", c)
+		}
+		seen[c.Category] = true
+	}
+}
+
+func TestCategory_String(t *testing.T) {
+	if got := CatRateLimit.String(); got != "rate_limit" {
+		t.Errorf("got %q", got)
+	}
+	if got := CatSecondaryLimit.String(); got != "secondary_rate_limit" {
+		t.Errorf("got %q", got)
+	}
+	if got := Category(-1).String(); got != "unknown" {
+		t.Errorf("got %q", got)
+	}
+}
+
 func TestCLIError_Error(t *testing.T) {
 	e := NewAuthFailure("bad token", nil)
 	if e.Error() != "bad token" {
@@ -0,0 +1,143 @@
+package githubapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+// Token types reported by TokenType.
+const (
+	TokenClassic      = "classic"      // ghp_ personal access token
+	TokenFineGrained  = "fine-grained" // github_pat_ personal access token
+	TokenOAuth        = "oauth"        // gho_ OAuth App token, e.g. from auth login
+	TokenUserToServer = "user-to-server"
+	TokenInstallation = "installation" // ghs_ GitHub App installation token
+	TokenUnknown      = "unknown"
+)
+
+// TokenType classifies a token by its prefix. It never looks further, so
+// the token itself does not leak into the result.
+func TokenType(token string) string {
+	switch {
+	case strings.HasPrefix(token, "ghp_"):
+		return TokenClassic
+	case strings.HasPrefix(token, "github_pat_"):
+		return TokenFineGrained
+	case strings.HasPrefix(token, "gho_"):
+		return TokenOAuth
+	case strings.HasPrefix(token, "ghu_"):
+		return TokenUserToServer
+	case strings.HasPrefix(token, "ghs_"):
+		return TokenInstallation
+	}
+	return TokenUnknown
+}
+
+// parseScopes reads X-OAuth-Scopes. It returns nil when the header is
+// absent, as for fine-grained and App tokens, and an empty slice for a
+// token with no scopes.
+func parseScopes(h http.Header) []string {
+	values, ok := h[http.CanonicalHeaderKey("X-OAuth-Scopes")]
+	if !ok {
+		return nil
+	}
+	scopes := []string{}
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				scopes = append(scopes, s)
+			}
+		}
+	}
+	return scopes
+}
+
+// parseTokenExpiration reads GitHub-Authentication-Token-Expiration, sent
+// for tokens that expire, e.g. "2026-11-01 12:00:00 UTC" or
+// "2026-11-01 12:00:00 -0700". It returns the zero time when absent.
+func parseTokenExpiration(h http.Header) time.Time {
+	v := strings.TrimSpace(h.Get("GitHub-Authentication-Token-Expiration"))
+	for _, layout := range []string{"2006-01-02 15:04:05 MST", "2006-01-02 15:04:05 -0700"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// Permission levels reported by RepoAccess, from most to least privileged.
+const (
+	PermAdmin    = "admin"
+	PermMaintain = "maintain"
+	PermWrite    = "write"
+	PermTriage   = "triage"
+	PermRead     = "read"
+	PermNone     = "none" // the repository is not visible to the token
+)
+
+// RepoAccess is what the token may do in one repository.
+type RepoAccess struct {
+	Repository    string // owner/repo
+	Private       bool
+	Permission    string // one of the Perm constants; "" when GitHub does not say
+	DefaultBranch string
+	Protected     bool // the default branch is protected
+}
+
+// GetRepoAccess reads the token's permission on owner/repo and whether the
+// default branch is protected. A repository the token cannot see yields
+// PermNone rather than an error.
+func (c *Client) GetRepoAccess(owner, repo string) (*RepoAccess, error) {
+	access := &RepoAccess{Repository: owner + "/" + repo}
+	raw, err := c.doGet(c.repoURL(owner, repo))
+	var ce *clerrors.CLIError
+	if errors.As(err, &ce) && ce.Cat == clerrors.CatNotFound {
+		access.Permission = PermNone
+		return access, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var info struct {
+		Private       bool             `json:"private"`
+		DefaultBranch string           `json:"default_branch"`
+		Permissions   *map[string]bool `json:"permissions"`
+	}
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return nil, clerrors.NewTransport("failed to parse repository response", err)
+	}
+	access.Private = info.Private
+	access.DefaultBranch = info.DefaultBranch
+	if info.Permissions != nil {
+		p := *info.Permissions
+		for _, level := range []struct {
+			key, name string
+		}{{"admin", PermAdmin}, {"maintain", PermMaintain}, {"push", PermWrite}, {"triage", PermTriage}, {"pull", PermRead}} {
+			if p[level.key] {
+				access.Permission = level.name
+				break
+			}
+		}
+	}
+
+	if info.DefaultBranch != "" {
+		raw, err := c.doGet(c.repoURL(owner, repo, "branches", info.DefaultBranch))
+		if err != nil {
+			return nil, err
+		}
+		var branch struct {
+			Protected bool `json:"protected"`
+		}
+		if err := json.Unmarshal(raw, &branch); err != nil {
+			return nil, clerrors.NewTransport("failed to parse branch response", err)
+		}
+		access.Protected = branch.Protected
+	}
+	return access, nil
+}
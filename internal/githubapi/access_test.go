@@ -0,0 +1,86 @@
+package githubapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTokenType(t *testing.T) {
+	for token, want := range map[string]string{
+		"ghp_abc":         TokenClassic,
+		"github_pat_11AB": TokenFineGrained,
+		"gho_abc":         TokenOAuth,
+		"ghu_abc":         TokenUserToServer,
+		"ghs_abc":         TokenInstallation,
+		"0123456789abcde": TokenUnknown,
+	} {
+		if got := TokenType(token); got != want {
+			t.Errorf("%s: got %q, want %q", token, got, want)
+		}
+	}
+}
+
+func TestGetAuthenticatedUser_ScopesAndExpiry(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-OAuth-Scopes", "repo, read:org")
+		w.Header().Set("GitHub-Authentication-Token-Expiration", "2026-11-01 12:00:00 -0700")
+		w.Write([]byte(`{"login":"octocat"}`))
+	}))
+	defer srv.Close()
+
+	result, err := NewClient(srv.URL, "ghp_x", 5*time.Second).GetAuthenticatedUser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.OAuthScopes) != 2 || result.OAuthScopes[0] != "repo" || result.OAuthScopes[1] != "read:org" {
+		t.Errorf("scopes: %q", result.OAuthScopes)
+	}
+	if want := time.Date(2026, 11, 1, 19, 0, 0, 0, time.UTC); !result.TokenExpiration.Equal(want) {
+		t.Errorf("expiration: got %v, want %v", result.TokenExpiration, want)
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	if got := parseScopes(http.Header{}); got != nil {
+		t.Errorf("absent header: got %q, want nil", got)
+	}
+	if got := parseScopes(http.Header{"X-Oauth-Scopes": {""}}); got == nil || len(got) != 0 {
+		t.Errorf("empty header: got %#v, want empty slice", got)
+	}
+	exp := parseTokenExpiration(http.Header{"Github-Authentication-Token-Expiration": {"2026-11-01 12:00:00 UTC"}})
+	if !exp.Equal(time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("UTC expiration: %v", exp)
+	}
+}
+
+func TestGetRepoAccess(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/o/r":
+			w.Write([]byte(`{"private":true,"default_branch":"main","permissions":{"admin":false,"maintain":false,"push":false,"triage":true,"pull":true}}`))
+		case "/repos/o/r/branches/main":
+			w.Write([]byte(`{"name":"main","protected":true}`))
+		default:
+			w.WriteHeader(404)
+			w.Write([]byte(`{"message":"Not Found"}`))
+		}
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL, "ghp_x", 5*time.Second)
+
+	got, err := c.GetRepoAccess("o", "r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RepoAccess{Repository: "o/r", Private: true, Permission: PermTriage, DefaultBranch: "main", Protected: true}
+	if *got != want {
+		t.Errorf("got %+v, want %+v", *got, want)
+	}
+
+	got, err = c.GetRepoAccess("o", "missing")
+	if err != nil || got.Permission != PermNone {
+		t.Errorf("missing repo: %+v %v", got, err)
+	}
+}
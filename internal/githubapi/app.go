@@ -0,0 +1,328 @@
+package githubapi
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+const (
+	// appJWTLifetime is the validity of an App JWT; GitHub allows at most 10 minutes.
+	appJWTLifetime = 9 * time.Minute
+	// appClockSkew backdates the JWT's issue time to tolerate clock drift.
+	appClockSkew = 60 * time.Second
+	// appTokenMargin is how long before expiry a cached installation token
+	// is replaced.
+	appTokenMargin = 5 * time.Minute
+)
+
+// AppAuth authenticates a Client as a GitHub App installation. Requests
+// carry an installation access token, obtained with a JWT signed by the
+// App's private key and cached until shortly before it expires.
+type AppAuth struct {
+	AppID          string          // App ID or client ID, the JWT issuer
+	Key            *rsa.PrivateKey // App private key
+	InstallationID int64           // 0: discover it from Owner/Repo
+
+	// Owner and Repo name the repository whose installation is used when
+	// InstallationID is 0. Without them the App must have one installation.
+	Owner, Repo string
+
+	// Cache, if set, keeps installation tokens between runs, so that each
+	// short command does not exchange a new one.
+	Cache TokenCache
+
+	mu       sync.Mutex
+	token    string
+	expires  time.Time
+	cacheKey string           // key of token in Cache, see tokenCacheKey
+	now      func() time.Time // replaced in tests
+}
+
+// TokenCache stores installation tokens and their expiry under a key naming
+// the server, App and installation, or the repository the installation is
+// discovered from. LoadToken returns an empty token when none is stored.
+type TokenCache interface {
+	LoadToken(key string) (token string, expires time.Time, err error)
+	SaveToken(key, token string, expires time.Time) error
+	DeleteToken(key string) error
+}
+
+// LoadAppKey reads a PEM-encoded App private key, as downloaded from the
+// App settings page (PKCS#1) or converted to PKCS#8.
+func LoadAppKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, clerrors.NewBadArgs(fmt.Sprintf("failed to read App private key %q", path), err)
+	}
+	key, err := ParseAppKey(data)
+	if err != nil {
+		return nil, clerrors.NewBadArgs(fmt.Sprintf("invalid App private key %s: %v", path, err), nil)
+	}
+	return key, nil
+}
+
+// ParseAppKey decodes a PEM-encoded RSA private key.
+func ParseAppKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("not an RSA private key")
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("not an RSA private key")
+	}
+	return key, nil
+}
+
+func (a *AppAuth) clock() time.Time {
+	if a.now != nil {
+		return a.now()
+	}
+	return time.Now()
+}
+
+// JWT returns a freshly signed RS256 token identifying the App.
+func (a *AppAuth) JWT() (string, error) {
+	now := a.clock()
+	var iss any = a.AppID
+	if id, err := strconv.ParseInt(a.AppID, 10, 64); err == nil {
+		iss = id
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]any{
+		"iat": now.Add(-appClockSkew).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": iss,
+	})
+	enc := base64.RawURLEncoding
+	signed := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.Key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", clerrors.NewAuthFailure("failed to sign App JWT", err)
+	}
+	return signed + "." + enc.EncodeToString(sig), nil
+}
+
+// Invalidate drops the cached installation token, here and in Cache, so
+// that the next request obtains a new one.
+func (a *AppAuth) Invalidate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.Cache != nil && a.token != "" {
+		if err := a.Cache.DeleteToken(a.cacheKey); err != nil {
+			slog.Debug("cannot drop cached installation token", "err", err)
+		}
+	}
+	a.token = ""
+}
+
+// fresh reports whether a token expiring at expires is still usable, that
+// is valid for more than appTokenMargin.
+func (a *AppAuth) fresh(expires time.Time) bool {
+	return a.clock().Before(expires.Add(-appTokenMargin))
+}
+
+// installationToken returns the cached installation token, exchanging a
+// new JWT for one when there is none or it expires within appTokenMargin.
+// Tokens are looked up in and saved to Cache, whose failures are only
+// logged; a token found there costs no request, not even the installation
+// lookup.
+func (a *AppAuth) installationToken(c *Client) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && a.fresh(a.expires) {
+		return a.token, nil
+	}
+
+	if a.cacheKey == "" {
+		a.cacheKey = a.tokenCacheKey(c)
+	}
+	if a.Cache != nil {
+		token, expires, err := a.Cache.LoadToken(a.cacheKey)
+		switch {
+		case err != nil:
+			slog.Debug("cannot read cached installation token", "err", err)
+		case token != "" && a.fresh(expires):
+			a.token, a.expires = token, expires
+			slog.Debug("reusing cached installation token", "app", a.AppID, "key", a.cacheKey, "expires", a.expires)
+			return a.token, nil
+		}
+	}
+
+	jwt, err := a.JWT()
+	if err != nil {
+		return "", err
+	}
+	if a.InstallationID == 0 {
+		if a.InstallationID, err = a.findInstallation(c, jwt); err != nil {
+			return "", err
+		}
+	}
+	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", c.BaseURL, a.InstallationID)
+	body, err := appRequest(c, "POST", url, jwt)
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil || result.Token == "" {
+		return "", clerrors.NewTransport("failed to parse installation token response", err)
+	}
+	a.token, a.expires = result.Token, result.ExpiresAt
+	slog.Debug("obtained installation token", "app", a.AppID, "installation", a.InstallationID, "expires", a.expires)
+	if a.Cache != nil {
+		if err := a.Cache.SaveToken(a.cacheKey, a.token, a.expires); err != nil {
+			slog.Debug("cannot cache installation token", "err", err)
+		}
+	}
+	return a.token, nil
+}
+
+// tokenCacheKey names the installation token in Cache. Without an
+// installation ID it names what the installation is discovered from, the
+// repository or the App itself, so that a cached token needs no lookup.
+func (a *AppAuth) tokenCacheKey(c *Client) string {
+	switch {
+	case a.InstallationID != 0:
+		return fmt.Sprintf("%s app %s installation %d", c.BaseURL, a.AppID, a.InstallationID)
+	case a.Owner != "" && a.Repo != "":
+		return fmt.Sprintf("%s app %s repository %s/%s", c.BaseURL, a.AppID, a.Owner, a.Repo)
+	}
+	return fmt.Sprintf("%s app %s", c.BaseURL, a.AppID)
+}
+
+// findInstallation looks up the App's installation on Owner/Repo, or its
+// only installation when no repository is known.
+func (a *AppAuth) findInstallation(c *Client, jwt string) (int64, error) {
+	if a.Owner != "" && a.Repo != "" {
+		url := c.repoURL(a.Owner, a.Repo, "installation")
+		body, err := appRequest(c, "GET", url, jwt)
+		var ce *clerrors.CLIError
+		if errors.As(err, &ce) && ce.Cat == clerrors.CatNotFound {
+			return 0, clerrors.NewNotFound(fmt.Sprintf("GitHub App %s is not installed on %s/%s", a.AppID, a.Owner, a.Repo), nil)
+		}
+		if err != nil {
+			return 0, err
+		}
+		var inst struct {
+			ID int64 `json:"id"`
+		}
+		if err := json.Unmarshal(body, &inst); err != nil || inst.ID == 0 {
+			return 0, clerrors.NewTransport("failed to parse installation response", err)
+		}
+		return inst.ID, nil
+	}
+
+	body, err := appRequest(c, "GET", c.BaseURL+"/app/installations?per_page=2", jwt)
+	if err != nil {
+		return 0, err
+	}
+	var list []struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return 0, clerrors.NewTransport("failed to parse installations response", err)
+	}
+	switch len(list) {
+	case 0:
+		return 0, clerrors.NewNotFound(fmt.Sprintf("GitHub App %s has no installations", a.AppID), nil)
+	case 1:
+		return list[0].ID, nil
+	}
+	return 0, clerrors.NewBadArgs(fmt.Sprintf("GitHub App %s has several installations: set the installation ID", a.AppID), nil)
+}
+
+// appRequest sends a request authenticated with an App JWT rather than an
+// installation token.
+func appRequest(c *Client, method, url, jwt string) ([]byte, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, clerrors.NewTransport("failed to build request", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if c.Server.Supports(FeatureAPIVersionHeader) {
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	}
+
+	resp, body, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, clerrors.ClassifyResponse(resp.StatusCode, resp.Header, body)
+	}
+	return body, nil
+}
+
+// GetAuthenticatedApp identifies the App and checks that an installation
+// token can be obtained. Login is the App's bot account, "<slug>[bot]";
+// RateLimitRemaining is the installation's remaining core quota.
+func (c *Client) GetAuthenticatedApp() (*UserResult, error) {
+	if c.App == nil {
+		return nil, clerrors.NewBadArgs("client is not configured for GitHub App authentication", nil)
+	}
+	jwt, err := c.App.JWT()
+	if err != nil {
+		return nil, err
+	}
+	body, err := appRequest(c, "GET", c.BaseURL+"/app", jwt)
+	if err != nil {
+		return nil, err
+	}
+	var app struct {
+		Slug string `json:"slug"`
+	}
+	if err := json.Unmarshal(body, &app); err != nil {
+		return nil, clerrors.NewTransport("failed to parse response", err)
+	}
+
+	resp, body, err := c.do("GET", c.BaseURL+"/rate_limit", nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, clerrors.ClassifyResponse(resp.StatusCode, resp.Header, body)
+	}
+	remaining, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	return &UserResult{
+		Login:              strings.TrimSpace(app.Slug) + "[bot]",
+		RateLimitRemaining: remaining,
+	}, nil
+}
+
+// InstallationToken returns the installation token the client sends with
+// App authentication, obtaining one if none is cached.
+func (c *Client) InstallationToken() (string, error) {
+	if c.App == nil {
+		return "", clerrors.NewBadArgs("client is not configured for GitHub App authentication", nil)
+	}
+	return c.App.installationToken(c)
+}
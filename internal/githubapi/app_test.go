@@ -0,0 +1,370 @@
+package githubapi
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"githubRAGCli/internal/credstore"
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+var (
+	testKeyOnce sync.Once
+	testKey     *rsa.PrivateKey
+)
+
+func appTestKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	testKeyOnce.Do(func() {
+		var err error
+		if testKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			t.Fatalf("generate key: %v", err)
+		}
+	})
+	return testKey
+}
+
+// verifyJWT checks the signature of an App JWT and returns its claims.
+func verifyJWT(t *testing.T, key *rsa.PrivateKey, jwt string) map[string]any {
+	t.Helper()
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("JWT has %d parts", len(parts))
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatalf("decode signature: %v", err)
+	}
+	sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, sum[:], sig); err != nil {
+		t.Fatalf("bad signature: %v", err)
+	}
+	raw, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims map[string]any
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		t.Fatalf("decode claims: %v", err)
+	}
+	return claims
+}
+
+func TestAppAuth_JWT(t *testing.T) {
+	key := appTestKey(t)
+	now := time.Unix(1_700_000_000, 0)
+	a := &AppAuth{AppID: "12345", Key: key, now: func() time.Time { return now }}
+
+	jwt, err := a.JWT()
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := verifyJWT(t, key, jwt)
+	if claims["iss"] != float64(12345) {
+		t.Errorf("iss: got %v, want 12345", claims["iss"])
+	}
+	if claims["iat"] != float64(now.Unix()-60) {
+		t.Errorf("iat: got %v, want %d", claims["iat"], now.Unix()-60)
+	}
+	if claims["exp"] != float64(now.Add(9*time.Minute).Unix()) {
+		t.Errorf("exp: got %v", claims["exp"])
+	}
+
+	a.AppID = "Iv1.abc"
+	jwt, _ = a.JWT()
+	if got := verifyJWT(t, key, jwt)["iss"]; got != "Iv1.abc" {
+		t.Errorf("client ID iss: got %v", got)
+	}
+}
+
+func TestParseAppKey(t *testing.T) {
+	key := appTestKey(t)
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(key)
+	for name, data := range map[string][]byte{
+		"pkcs1": pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		"pkcs8": pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}),
+	} {
+		got, err := ParseAppKey(data)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !got.Equal(key) {
+			t.Errorf("%s: key mismatch", name)
+		}
+	}
+	if _, err := ParseAppKey([]byte("not a key")); err == nil {
+		t.Error("expected error for non-PEM data")
+	}
+}
+
+// appServer fakes the App endpoints and one contents endpoint. Each
+// exchanged token is "inst-N"; rejectToken makes the contents endpoint
+// answer 401 for that token.
+type appServer struct {
+	t           *testing.T
+	key         *rsa.PrivateKey
+	mu          sync.Mutex
+	exchanges   int
+	lookups     int
+	rejectToken string
+	expiresIn   time.Duration
+}
+
+func (s *appServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	switch {
+	case r.URL.Path == "/repos/o/r/installation":
+		verifyJWT(s.t, s.key, auth)
+		s.lookups++
+		w.Write([]byte(`{"id": 42}`))
+	case r.Method == "POST" && r.URL.Path == "/app/installations/42/access_tokens":
+		verifyJWT(s.t, s.key, auth)
+		s.exchanges++
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token": "inst-%d", "expires_at": %q}`, s.exchanges, time.Now().Add(s.expiresIn).UTC().Format(time.RFC3339))
+	case r.URL.Path == "/repos/o/r/contents/a.txt":
+		if !strings.HasPrefix(auth, "inst-") || auth == s.rejectToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"Bad credentials"}`))
+			return
+		}
+		w.Write([]byte(`{"type":"file","path":"a.txt"}`))
+	default:
+		s.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newAppTestClient(t *testing.T, s *appServer) (*Client, *AppAuth) {
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	app := &AppAuth{AppID: "1", Key: s.key, Owner: "o", Repo: "r"}
+	c := NewClient(srv.URL, "", 5*time.Second)
+	c.App = app
+	return c, app
+}
+
+func TestAppAuth_DiscoversInstallationAndCachesToken(t *testing.T) {
+	s := &appServer{t: t, key: appTestKey(t), expiresIn: time.Hour}
+	c, app := newAppTestClient(t, s)
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetContents("o", "r", "a.txt", ""); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	if s.lookups != 1 || s.exchanges != 1 {
+		t.Errorf("lookups=%d exchanges=%d, want 1 and 1", s.lookups, s.exchanges)
+	}
+	if app.InstallationID != 42 {
+		t.Errorf("installation ID: got %d, want 42", app.InstallationID)
+	}
+}
+
+func TestAppAuth_RefreshesNearExpiry(t *testing.T) {
+	// Tokens expiring within the margin are replaced on every use.
+	s := &appServer{t: t, key: appTestKey(t), expiresIn: 2 * time.Minute}
+	c, _ := newAppTestClient(t, s)
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetContents("o", "r", "a.txt", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.exchanges != 2 {
+		t.Errorf("exchanges: got %d, want 2", s.exchanges)
+	}
+}
+
+func TestAppAuth_RefreshesOn401(t *testing.T) {
+	s := &appServer{t: t, key: appTestKey(t), expiresIn: time.Hour, rejectToken: "inst-1"}
+	c, _ := newAppTestClient(t, s)
+
+	if _, err := c.GetContents("o", "r", "a.txt", ""); err != nil {
+		t.Fatalf("expected retry with a fresh token to succeed: %v", err)
+	}
+	if s.exchanges != 2 {
+		t.Errorf("exchanges: got %d, want 2", s.exchanges)
+	}
+}
+
+func TestAppAuth_401RetriedOnce(t *testing.T) {
+	s := &appServer{t: t, key: appTestKey(t), expiresIn: time.Hour}
+	c, _ := newAppTestClient(t, s)
+	s.rejectToken = "inst-1"
+	c.App.token, c.App.expires = "stale", time.Now().Add(time.Hour)
+
+	_, err := c.GetContents("o", "r", "a.txt", "")
+	ce, ok := err.(*clerrors.CLIError)
+	if !ok || ce.ExitCode() != clerrors.ExitAuthFailure {
+		t.Fatalf("expected auth failure after one retry, got %v", err)
+	}
+	if s.exchanges != 1 {
+		t.Errorf("exchanges: got %d, want 1", s.exchanges)
+	}
+}
+
+func TestAppAuth_ReusesCachedTokenAcrossInstances(t *testing.T) {
+	t.Setenv(credstore.PassphraseEnv, "")
+	s := &appServer{t: t, key: appTestKey(t), expiresIn: time.Hour}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	cache := &credstore.Store{Dir: t.TempDir()}
+
+	// Each run of ghrepo has its own AppAuth; the second must reuse the
+	// token the first obtained.
+	for run := 0; run < 2; run++ {
+		c := NewClient(srv.URL, "", 5*time.Second)
+		c.App = &AppAuth{AppID: "1", Key: s.key, InstallationID: 42, Cache: cache}
+		if _, err := c.GetContents("o", "r", "a.txt", ""); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+		if c.App.token != "inst-1" {
+			t.Errorf("run %d: token %q, want inst-1", run, c.App.token)
+		}
+	}
+	if s.exchanges != 1 {
+		t.Errorf("exchanges: got %d, want 1", s.exchanges)
+	}
+
+	// A rejected cached token is dropped from the cache and replaced.
+	s.rejectToken = "inst-1"
+	c := NewClient(srv.URL, "", 5*time.Second)
+	c.App = &AppAuth{AppID: "1", Key: s.key, InstallationID: 42, Cache: cache}
+	if _, err := c.GetContents("o", "r", "a.txt", ""); err != nil {
+		t.Fatal(err)
+	}
+	if token, _, _ := cache.LoadToken(c.App.cacheKey); token != "inst-2" || s.exchanges != 2 {
+		t.Errorf("after 401: cached %q, exchanges %d; want inst-2 and 2", token, s.exchanges)
+	}
+}
+
+func TestAppAuth_CachedTokenSkipsInstallationLookup(t *testing.T) {
+	t.Setenv(credstore.PassphraseEnv, "")
+	s := &appServer{t: t, key: appTestKey(t), expiresIn: time.Hour}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	cache := &credstore.Store{Dir: t.TempDir()}
+
+	for run := 0; run < 2; run++ {
+		c := NewClient(srv.URL, "", 5*time.Second)
+		c.App = &AppAuth{AppID: "1", Key: s.key, Owner: "o", Repo: "r", Cache: cache}
+		if _, err := c.GetContents("o", "r", "a.txt", ""); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+	}
+	if s.lookups != 1 || s.exchanges != 1 {
+		t.Errorf("lookups=%d exchanges=%d, want 1 and 1", s.lookups, s.exchanges)
+	}
+}
+
+func TestAppAuth_APIVersionHeader(t *testing.T) {
+	for _, tt := range []struct {
+		server *ServerInfo
+		want   string
+	}{
+		{nil, "2022-11-28"},
+		{&ServerInfo{Enterprise: true, Version: "3.12.0"}, "2022-11-28"},
+		{&ServerInfo{Enterprise: true, Version: "3.8.0"}, ""},
+	} {
+		var got []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = append(got, r.Header.Get("X-GitHub-Api-Version"))
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"token": "inst-1", "expires_at": "2999-01-01T00:00:00Z"}`))
+		}))
+		c := NewClient(srv.URL, "", 5*time.Second)
+		c.Server = tt.server
+		c.App = &AppAuth{AppID: "1", Key: appTestKey(t), InstallationID: 42}
+		if _, err := c.InstallationToken(); err != nil {
+			t.Fatal(err)
+		}
+		srv.Close()
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("server %v: header %q, want %q", tt.server, got, tt.want)
+		}
+	}
+}
+
+func TestAppAuth_IgnoresExpiringCachedToken(t *testing.T) {
+	t.Setenv(credstore.PassphraseEnv, "")
+	s := &appServer{t: t, key: appTestKey(t), expiresIn: time.Hour}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	cache := &credstore.Store{Dir: t.TempDir()}
+	key := fmt.Sprintf("%s app 1 installation 42", srv.URL)
+	if err := cache.SaveToken(key, "old", time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewClient(srv.URL, "", 5*time.Second)
+	c.App = &AppAuth{AppID: "1", Key: s.key, InstallationID: 42, Cache: cache}
+	if _, err := c.GetContents("o", "r", "a.txt", ""); err != nil {
+		t.Fatal(err)
+	}
+	if s.exchanges != 1 || c.App.token != "inst-1" {
+		t.Errorf("exchanges %d, token %q; want a new token", s.exchanges, c.App.token)
+	}
+}
+
+func TestAppAuth_NotInstalled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"Not Found"}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "", 5*time.Second)
+	c.App = &AppAuth{AppID: "1", Key: appTestKey(t), Owner: "o", Repo: "r"}
+	_, err := c.GetContents("o", "r", "a.txt", "")
+	ce, ok := err.(*clerrors.CLIError)
+	if !ok || ce.ExitCode() != clerrors.ExitNotFound || !strings.Contains(ce.Message, "not installed on o/r") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestAppAuth_SingleInstallationWithoutRepo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app/installations":
+			w.Write([]byte(`[{"id": 7}]`))
+		case "/app/installations/7/access_tokens":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"token": "inst-7", "expires_at": "2999-01-01T00:00:00Z"}`))
+		case "/app":
+			w.Write([]byte(`{"slug": "deploy-bot"}`))
+		case "/rate_limit":
+			if r.Header.Get("Authorization") != "Bearer inst-7" {
+				t.Errorf("rate_limit auth: %s", r.Header.Get("Authorization"))
+			}
+			w.Header().Set("X-RateLimit-Remaining", "4999")
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "", 5*time.Second)
+	c.App = &AppAuth{AppID: "1", Key: appTestKey(t)}
+	res, err := c.GetAuthenticatedApp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Login != "deploy-bot[bot]" || res.RateLimitRemaining != 4999 {
+		t.Errorf("got %+v", res)
+	}
+}
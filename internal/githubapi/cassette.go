@@ -0,0 +1,297 @@
+package githubapi
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+// A cassette is a directory of JSON files, one per request and response,
+// named "0001-get-repos-owner-repo.json" in the order they were recorded.
+// RecordTransport writes them and ReplayTransport serves them back, so runs
+// can be reproduced without network access.
+
+// interaction is the content of one cassette file.
+type interaction struct {
+	Request  recordedRequest  `json:"request"`
+	Response recordedResponse `json:"response"`
+}
+
+type recordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+type recordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	// Body holds a UTF-8 body; any other body is in BodyBase64.
+	Body       string `json:"body,omitempty"`
+	BodyBase64 string `json:"body_base64,omitempty"`
+}
+
+// cassetteFile matches the names of cassette files and captures their
+// sequence number.
+var cassetteFile = regexp.MustCompile(`^(\d+)-.*\.json$`)
+
+// RecordTransport is an http.RoundTripper that passes requests on and saves
+// each response with its request as a cassette file in Dir. Authorization
+// and cookie headers are never saved, and token-looking strings in URLs,
+// headers and request bodies are redacted. Response bodies are saved as
+// received, so that replayed file contents are byte for byte the same,
+// except those of the endpoints that issue tokens; see tokenResponse. Files
+// already in Dir are kept; new ones are numbered after them.
+type RecordTransport struct {
+	Next http.RoundTripper // nil: http.DefaultTransport
+	Dir  string
+
+	mu  sync.Mutex
+	seq int
+}
+
+// NewRecordTransport creates dir if needed and returns a RecordTransport
+// that saves to it.
+func NewRecordTransport(dir string, next http.RoundTripper) (*RecordTransport, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, clerrors.NewLocalWriteErr("cannot create --record directory", err)
+	}
+	names, err := cassetteFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	t := &RecordTransport{Next: next, Dir: dir}
+	if len(names) > 0 {
+		t.seq = cassetteSeq(names[len(names)-1])
+	}
+	return t, nil
+}
+
+// RoundTrip sends req with Next and records the response. Failed requests
+// are not recorded.
+func (t *RecordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	in := interaction{Request: recordedRequest{Method: req.Method, URL: RedactSecrets(req.URL.String())}}
+	if req.GetBody != nil {
+		if rc, err := req.GetBody(); err == nil {
+			body, _ := io.ReadAll(rc)
+			rc.Close()
+			if utf8.Valid(body) {
+				in.Request.Body = RedactSecrets(string(body))
+			}
+		}
+	}
+
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return resp, nil
+	}
+
+	in.Response = recordedResponse{Status: resp.StatusCode, Header: http.Header{}}
+	for name, values := range resp.Header {
+		// The length changes when the body is redacted.
+		if hiddenHeaders[name] || name == "Content-Length" {
+			continue
+		}
+		for _, v := range values {
+			in.Response.Header.Add(name, RedactSecrets(v))
+		}
+	}
+	switch {
+	case tokenResponse(req.URL.Path) && utf8.Valid(body):
+		in.Response.Body = RedactSecrets(string(body))
+	case utf8.Valid(body):
+		in.Response.Body = string(body)
+	default:
+		in.Response.BodyBase64 = base64.StdEncoding.EncodeToString(body)
+	}
+	if err := t.save(req, &in); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// tokenResponse reports whether responses to path carry credentials: the
+// installation tokens of /access_tokens and the OAuth tokens of
+// /login/oauth. Only their bodies are redacted.
+func tokenResponse(path string) bool {
+	return strings.HasSuffix(path, "/access_tokens") || strings.Contains(path, "/login/oauth/")
+}
+
+// save writes in to the next cassette file.
+func (t *RecordTransport) save(req *http.Request, in *interaction) error {
+	data, err := json.MarshalIndent(in, "", "  ")
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.seq++
+	name := fmt.Sprintf("%04d-%s.json", t.seq, cassetteSlug(req.Method, req.URL.Path))
+	t.mu.Unlock()
+	// The response may hold repository content, so only the owner reads it.
+	if err := os.WriteFile(filepath.Join(t.Dir, name), append(data, '\n'), 0o600); err != nil {
+		return clerrors.NewLocalWriteErr("cannot write cassette "+name, err)
+	}
+	return nil
+}
+
+// cassetteSlug makes a file name part like "get-repos-o-r-contents-a.md"
+// from a request.
+func cassetteSlug(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, r := range path {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_':
+			b.WriteRune(r)
+		default:
+			if !strings.HasSuffix(b.String(), "-") {
+				b.WriteByte('-')
+			}
+		}
+	}
+	s := strings.TrimSuffix(b.String(), "-")
+	if len(s) > 80 {
+		s = s[:80]
+	}
+	return s
+}
+
+// ReplayTransport is an http.RoundTripper that answers requests from the
+// cassette files in a directory instead of the network. A request matches
+// a recorded one with the same method, path and query (in any parameter
+// order); the host is ignored. Requests that match several recordings get
+// them in recorded order, and the last one again once all were served.
+// A request without a match fails.
+type ReplayTransport struct {
+	Dir string
+
+	mu     sync.Mutex
+	byKey  map[string][]*recordedResponse
+	served map[string]int
+}
+
+// NewReplayTransport loads the cassette files in dir. A missing or empty
+// directory and unreadable files are CLIErrors with CatBadArgs.
+func NewReplayTransport(dir string) (*ReplayTransport, error) {
+	names, err := cassetteFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, clerrors.NewBadArgs(fmt.Sprintf("no cassette files in --replay directory %s", dir), nil)
+	}
+	t := &ReplayTransport{Dir: dir, byKey: make(map[string][]*recordedResponse), served: make(map[string]int)}
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, clerrors.NewBadArgs("cannot read cassette "+name, err)
+		}
+		var in interaction
+		if err := json.Unmarshal(data, &in); err != nil {
+			return nil, clerrors.NewBadArgs("invalid cassette "+name, err)
+		}
+		u, err := url.Parse(in.Request.URL)
+		if err != nil || in.Request.Method == "" {
+			return nil, clerrors.NewBadArgs(fmt.Sprintf("invalid cassette %s: bad request %s %q", name, in.Request.Method, in.Request.URL), nil)
+		}
+		key := cassetteKey(in.Request.Method, u)
+		t.byKey[key] = append(t.byKey[key], &in.Response)
+	}
+	return t, nil
+}
+
+// RoundTrip returns the recorded response that matches req.
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	key := cassetteKey(req.Method, req.URL)
+	t.mu.Lock()
+	recorded := t.byKey[key]
+	i := t.served[key]
+	if i < len(recorded) {
+		t.served[key]++
+	} else {
+		i = len(recorded) - 1
+	}
+	t.mu.Unlock()
+	if i < 0 {
+		return nil, fmt.Errorf("no recorded response for %s in %s", key, t.Dir)
+	}
+
+	r := recorded[i]
+	body := []byte(r.Body)
+	if r.BodyBase64 != "" {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(r.BodyBase64); err != nil {
+			return nil, fmt.Errorf("recorded response for %s: %w", key, err)
+		}
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(r.Status) + " " + http.StatusText(r.Status),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// cassetteKey is what replayed requests are matched on: the method, the
+// path and the query with its parameters sorted, redacted like recordings.
+func cassetteKey(method string, u *url.URL) string {
+	key := method + " " + u.EscapedPath()
+	if q := u.Query().Encode(); q != "" {
+		key += "?" + q
+	}
+	return RedactSecrets(key)
+}
+
+// cassetteFiles returns the cassette file names in dir in recorded order.
+func cassetteFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, clerrors.NewBadArgs("cannot read cassette directory "+dir, err)
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && cassetteFile.MatchString(e.Name()) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.SliceStable(names, func(i, j int) bool { return cassetteSeq(names[i]) < cassetteSeq(names[j]) })
+	return names, nil
+}
+
+// cassetteSeq returns the sequence number of a cassette file name.
+func cassetteSeq(name string) int {
+	n, _ := strconv.Atoi(cassetteFile.FindStringSubmatch(name)[1])
+	return n
+}
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	clerrors "githubRAGCli/internal/exitcode"
@@ -17,6 +19,16 @@ type Client struct {
 	BaseURL    string
 	Token      string
 	HTTPClient *http.Client
+
+	// App, when set, authenticates requests as a GitHub App installation
+	// instead of with Token.
+	App *AppAuth
+
+	// Budget, when set, limits the requests the client makes.
+	Budget *Budget
+
+	// Server, when set, disables features the server lacks.
+	Server *ServerInfo
 }
 
 // NewClient creates a Client with the given configuration.
@@ -30,35 +42,32 @@ func NewClient(baseURL, token string, timeout time.Duration) *Client {
 	}
 }
 
+// WebURL returns the web host matching the client's API base URL:
+// https://api.github.com maps to https://github.com and a GHES base of
+// https://host/api/v3 maps to https://host.
+func (c *Client) WebURL() string {
+	return APIEndpoints(c.BaseURL).Web
+}
+
 // UserResult holds data returned from GET /user.
 type UserResult struct {
 	Login              string
 	RateLimitRemaining int
+	OAuthScopes        []string  // from X-OAuth-Scopes; nil when the token type has none
+	TokenExpiration    time.Time // zero when the token does not expire
 }
 
 // GetAuthenticatedUser calls GET /user and returns identity + rate-limit info.
 func (c *Client) GetAuthenticatedUser() (*UserResult, error) {
 	url := fmt.Sprintf("%s/user", c.BaseURL)
 
-	req, err := http.NewRequest("GET", url, nil)
+	resp, body, err := c.do("GET", url, nil)
 	if err != nil {
-		return nil, clerrors.NewTransport("failed to build request", err)
-	}
-	req.Header.Set("Authorization", "Bearer "+c.Token)
-	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
-
-	resp, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return nil, clerrors.ClassifyTransportErr(err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
-		rateLimited := isRateLimited(resp)
-		return nil, clerrors.ClassifyHTTP(resp.StatusCode, rateLimited, string(body))
+		return nil, clerrors.ClassifyResponse(resp.StatusCode, resp.Header, body)
 	}
 
 	var user struct {
@@ -73,15 +82,17 @@ func (c *Client) GetAuthenticatedUser() (*UserResult, error) {
 	return &UserResult{
 		Login:              user.Login,
 		RateLimitRemaining: remaining,
+		OAuthScopes:        parseScopes(resp.Header),
+		TokenExpiration:    parseTokenExpiration(resp.Header),
 	}, nil
 }
 
 // GetContents calls GET /repos/{owner}/{repo}/contents/{path} and returns the raw JSON body.
 // The response may be a single file object or an array of directory entries.
 func (c *Client) GetContents(owner, repo, path, ref string) (json.RawMessage, error) {
-	url := fmt.Sprintf("%s/repos/%s/%s/contents/%s", c.BaseURL, owner, repo, path)
-	if ref != "" {
-		url += "?ref=" + ref
+	url, err := c.contentsURL(owner, repo, path, ref)
+	if err != nil {
+		return nil, err
 	}
 	return c.doGet(url)
 }
@@ -90,7 +101,7 @@ func (c *Client) GetContents(owner, repo, path, ref string) (json.RawMessage, er
 type TreeEntry struct {
 	Path string `json:"path"`
 	Mode string `json:"mode"`
-	Type string `json:"type"` // "blob" or "tree"
+	Type string `json:"type"` // "blob", "tree" or "commit" (submodule)
 	SHA  string `json:"sha"`
 	Size int64  `json:"size,omitempty"`
 }
@@ -104,7 +115,7 @@ type TreeResult struct {
 
 // GetTree calls GET /repos/{owner}/{repo}/git/trees/{sha} and returns the tree.
 func (c *Client) GetTree(owner, repo, sha string, recursive bool) (*TreeResult, error) {
-	url := fmt.Sprintf("%s/repos/%s/%s/git/trees/%s", c.BaseURL, owner, repo, sha)
+	url := c.repoURL(owner, repo, "git/trees", sha)
 	if recursive {
 		url += "?recursive=1"
 	}
@@ -121,39 +132,85 @@ func (c *Client) GetTree(owner, repo, sha string, recursive bool) (*TreeResult,
 	return &result, nil
 }
 
-// doGet performs an authenticated GET request and returns the response body.
-func (c *Client) doGet(url string) (json.RawMessage, error) {
-	req, err := http.NewRequest("GET", url, nil)
+// CommitResult holds the subset of GET /repos/{owner}/{repo}/commits/{ref} we use.
+type CommitResult struct {
+	SHA string `json:"sha"`
+}
+
+// GetCommit calls GET /repos/{owner}/{repo}/commits/{ref} to resolve a ref to a commit.
+func (c *Client) GetCommit(owner, repo, ref string) (*CommitResult, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	url := c.repoURL(owner, repo, "commits", ref)
+
+	raw, err := c.doGet(url)
 	if err != nil {
-		return nil, clerrors.NewTransport("failed to build request", err)
+		return nil, err
 	}
-	req.Header.Set("Authorization", "Bearer "+c.Token)
-	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
-	resp, err := c.HTTPClient.Do(req)
+	var result CommitResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, clerrors.NewTransport("failed to parse commit response", err)
+	}
+	return &result, nil
+}
+
+// BlobResult holds the response from the Git Blobs API.
+type BlobResult struct {
+	SHA      string `json:"sha"`
+	Size     int64  `json:"size"`
+	Content  string `json:"content"`
+	Encoding string `json:"encoding"`
+}
+
+// GetBlob calls GET /repos/{owner}/{repo}/git/blobs/{sha} and returns the blob.
+func (c *Client) GetBlob(owner, repo, sha string) (*BlobResult, error) {
+	url := c.repoURL(owner, repo, "git/blobs", sha)
+
+	raw, err := c.doGet(url)
 	if err != nil {
-		return nil, clerrors.ClassifyTransportErr(err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
+	var result BlobResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, clerrors.NewTransport("failed to parse blob response", err)
+	}
+	return &result, nil
+}
 
+// GetRaw downloads raw file content, such as a contents entry's
+// download_url, with the client's token, transport and budget. The token is
+// only sent to the server's own raw and API endpoints; other URLs are
+// refused.
+func (c *Client) GetRaw(url string) ([]byte, error) {
+	e := APIEndpoints(c.BaseURL)
+	if !strings.HasPrefix(url, e.Raw+"/") && !strings.HasPrefix(url, e.API+"/") {
+		return nil, clerrors.NewBadArgs(fmt.Sprintf("refusing to download %s: not on %s or %s", url, e.Raw, e.API), nil)
+	}
+	resp, body, err := c.do("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
 	if resp.StatusCode != http.StatusOK {
-		rateLimited := isRateLimited(resp)
-		return nil, clerrors.ClassifyHTTP(resp.StatusCode, rateLimited, string(body))
+		return nil, clerrors.ClassifyResponse(resp.StatusCode, resp.Header, body)
 	}
-
-	return json.RawMessage(body), nil
+	return body, nil
 }
 
-// isRateLimited checks response headers for rate-limit indicators.
-func isRateLimited(resp *http.Response) bool {
-	remaining := resp.Header.Get("X-RateLimit-Remaining")
-	if remaining == "0" {
-		return true
+// doGet performs an authenticated GET request and returns the response body.
+func (c *Client) doGet(url string) (json.RawMessage, error) {
+	resp, body, err := c.do("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, clerrors.ClassifyResponse(resp.StatusCode, resp.Header, body)
 	}
-	return false
+
+	return json.RawMessage(body), nil
 }
 
 // PutContentsRequest is the JSON body for PUT /repos/{owner}/{repo}/contents/{path}.
@@ -184,13 +241,19 @@ type ContentsCommitResult struct {
 
 // PutContents calls PUT /repos/{owner}/{repo}/contents/{path}.
 func (c *Client) PutContents(owner, repo, path string, body *PutContentsRequest) (*ContentsCommitResult, error) {
-	url := fmt.Sprintf("%s/repos/%s/%s/contents/%s", c.BaseURL, owner, repo, path)
+	url, err := c.contentsURL(owner, repo, path, "")
+	if err != nil {
+		return nil, err
+	}
 	return doJSON[ContentsCommitResult](c, "PUT", url, body)
 }
 
 // DeleteContents calls DELETE /repos/{owner}/{repo}/contents/{path}.
 func (c *Client) DeleteContents(owner, repo, path string, body *DeleteContentsRequest) (*ContentsCommitResult, error) {
-	url := fmt.Sprintf("%s/repos/%s/%s/contents/%s", c.BaseURL, owner, repo, path)
+	url, err := c.contentsURL(owner, repo, path, "")
+	if err != nil {
+		return nil, err
+	}
 	return doJSON[ContentsCommitResult](c, "DELETE", url, body)
 }
 
@@ -201,26 +264,13 @@ func doJSON[T any](c *Client, method, url string, payload any) (*T, error) {
 		return nil, clerrors.NewTransport("failed to marshal request body", err)
 	}
 
-	req, err := http.NewRequest(method, url, bytes.NewReader(jsonBody))
-	if err != nil {
-		return nil, clerrors.NewTransport("failed to build request", err)
-	}
-	req.Header.Set("Authorization", "Bearer "+c.Token)
-	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
-
-	resp, err := c.HTTPClient.Do(req)
+	resp, respBody, err := c.do(method, url, jsonBody)
 	if err != nil {
-		return nil, clerrors.ClassifyTransportErr(err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		rateLimited := isRateLimited(resp)
-		return nil, clerrors.ClassifyHTTP(resp.StatusCode, rateLimited, string(respBody))
+		return nil, clerrors.ClassifyResponse(resp.StatusCode, resp.Header, respBody)
 	}
 
 	var result T
@@ -229,3 +279,67 @@ func doJSON[T any](c *Client, method, url string, payload any) (*T, error) {
 	}
 	return &result, nil
 }
+
+// do performs an authenticated request and returns the response with its
+// body read. A non-nil body is sent as JSON. The client's Budget, if any,
+// is checked before and updated after each attempt. With App
+// authentication, a 401 discards the cached installation token and the
+// request is retried once with a fresh one.
+func (c *Client) do(method, url string, body []byte) (*http.Response, []byte, error) {
+	// GET /rate_limit is free, so the budget neither counts nor blocks it.
+	free := strings.HasSuffix(url, "/rate_limit")
+	resource := rateResource(url)
+	for attempt := 0; ; attempt++ {
+		if !free {
+			if err := c.Budget.acquire(resource); err != nil {
+				return nil, nil, err
+			}
+		}
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequest(method, url, reader)
+		if err != nil {
+			return nil, nil, clerrors.NewTransport("failed to build request", err)
+		}
+		token := c.Token
+		if c.App != nil {
+			if token, err = c.App.installationToken(c); err != nil {
+				return nil, nil, err
+			}
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept", "application/vnd.github+json")
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if c.Server.Supports(FeatureAPIVersionHeader) {
+			req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+		}
+
+		resp, respBody, err := c.send(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		c.Budget.observe(resource, resp.Header)
+		if resp.StatusCode == http.StatusUnauthorized && c.App != nil && attempt == 0 {
+			slog.Debug("installation token rejected, retrying with a new one", "url", url)
+			c.App.Invalidate()
+			continue
+		}
+		return resp, respBody, nil
+	}
+}
+
+// send executes req and reads the whole response body.
+func (c *Client) send(req *http.Request) (*http.Response, []byte, error) {
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, nil, clerrors.ClassifyTransportErr(err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	return resp, body, nil
+}
@@ -122,3 +122,20 @@ func TestGetAuthenticatedUser_Timeout(t *testing.T) {
 		t.Errorf("exit code: got %d, want %d", ce.ExitCode(), clerrors.ExitTransport)
 	}
 }
+
+func TestWebURL(t *testing.T) {
+	tests := []struct {
+		base string
+		want string
+	}{
+		{"https://api.github.com", "https://github.com"},
+		{"https://ghe.example.com/api/v3", "https://ghe.example.com"},
+		{"http://127.0.0.1:8080", "http://127.0.0.1:8080"},
+	}
+	for _, tt := range tests {
+		c := NewClient(tt.base, "token", time.Second)
+		if got := c.WebURL(); got != tt.want {
+			t.Errorf("WebURL(%q): got %q, want %q", tt.base, got, tt.want)
+		}
+	}
+}
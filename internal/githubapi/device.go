@@ -0,0 +1,185 @@
+package githubapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+// deviceSlowDown is added to the polling interval when the server answers
+// slow_down, as RFC 8628 requires.
+const deviceSlowDown = 5 * time.Second
+
+// DeviceFlow runs the OAuth device authorization flow (RFC 8628) against
+// the login endpoints of a GitHub web host.
+type DeviceFlow struct {
+	WebURL     string // e.g. https://github.com or https://ghe.example.com
+	ClientID   string // OAuth App client ID with device flow enabled
+	Scopes     []string
+	HTTPClient *http.Client
+
+	sleep func(ctx context.Context, d time.Duration) error // replaced in tests
+}
+
+// DeviceCode is the code the user enters at VerificationURI.
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"` // seconds
+	Interval        int    `json:"interval"`   // minimum seconds between polls
+}
+
+// OAuthToken is the token granted at the end of the flow.
+type OAuthToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	Scope       string `json:"scope"`
+}
+
+// deviceResponse covers both the success and the error form of the token
+// endpoint's answer.
+type deviceResponse struct {
+	OAuthToken
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+	Interval         int    `json:"interval"`
+}
+
+// Start requests a device and user code.
+func (f *DeviceFlow) Start() (*DeviceCode, error) {
+	form := url.Values{"client_id": {f.ClientID}}
+	if len(f.Scopes) > 0 {
+		form.Set("scope", strings.Join(f.Scopes, " "))
+	}
+	var dc DeviceCode
+	resp, err := f.post("/login/device/code", form, &dc)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, deviceError(resp)
+	}
+	if dc.DeviceCode == "" || dc.UserCode == "" {
+		return nil, clerrors.NewTransport("device code response is missing the code", nil)
+	}
+	return &dc, nil
+}
+
+// Wait polls for the token until the user approves the code, denies it or
+// the code expires.
+func (f *DeviceFlow) Wait(ctx context.Context, dc *DeviceCode) (*OAuthToken, error) {
+	interval := time.Duration(dc.Interval) * time.Second
+	if interval <= 0 {
+		interval = deviceSlowDown
+	}
+	if dc.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(dc.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+	sleep := f.sleep
+	if sleep == nil {
+		sleep = sleepContext
+	}
+
+	form := url.Values{
+		"client_id":   {f.ClientID},
+		"device_code": {dc.DeviceCode},
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+	}
+	for {
+		if err := sleep(ctx, interval); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil, clerrors.NewAuthFailure("the device code expired before it was approved", nil)
+			}
+			return nil, clerrors.NewUserAbort("login cancelled", err)
+		}
+		var token OAuthToken
+		resp, err := f.post("/login/oauth/access_token", form, &token)
+		if err != nil {
+			return nil, err
+		}
+		switch resp.Error {
+		case "":
+			if token.AccessToken == "" {
+				return nil, clerrors.NewTransport("token response is missing the access token", nil)
+			}
+			return &token, nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += deviceSlowDown
+			if resp.Interval > 0 {
+				interval = time.Duration(resp.Interval) * time.Second
+			}
+		default:
+			return nil, deviceError(resp)
+		}
+	}
+}
+
+// post sends a form to the web host and decodes the JSON answer into out.
+// OAuth errors come back with status 200 and an "error" field, returned in
+// the deviceResponse.
+func (f *DeviceFlow) post(path string, form url.Values, out any) (*deviceResponse, error) {
+	req, err := http.NewRequest("POST", strings.TrimSuffix(f.WebURL, "/")+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, clerrors.NewTransport("failed to build request", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	c := &Client{HTTPClient: f.HTTPClient}
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+	resp, body, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	var r deviceResponse
+	if json.Unmarshal(body, &r) == nil && r.Error != "" {
+		return &r, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, clerrors.ClassifyResponse(resp.StatusCode, resp.Header, body)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return nil, clerrors.NewTransport("failed to parse response from "+path, err)
+	}
+	return &r, nil
+}
+
+// deviceError maps an OAuth error answer to a CLIError.
+func deviceError(r *deviceResponse) error {
+	msg := r.Error
+	if r.ErrorDescription != "" {
+		msg = r.ErrorDescription
+	}
+	switch r.Error {
+	case "access_denied":
+		return clerrors.NewUserAbort("authorization was denied", nil)
+	case "expired_token":
+		return clerrors.NewAuthFailure("the device code expired before it was approved", nil)
+	case "device_flow_disabled", "unauthorized_client", "incorrect_client_credentials":
+		return clerrors.NewBadArgs("OAuth client cannot use the device flow: "+msg, nil)
+	}
+	return clerrors.NewAuthFailure("device login failed: "+msg, nil)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,120 @@
+package githubapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+// deviceServer answers the device code request, then replies to token polls
+// with the given answers in turn.
+func deviceServer(t *testing.T, polls ...string) *httptest.Server {
+	t.Helper()
+	n := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/json" {
+			t.Errorf("Accept: %s", r.Header.Get("Accept"))
+		}
+		r.ParseForm()
+		if r.PostForm.Get("client_id") != "Iv1.test" {
+			t.Errorf("client_id: %q", r.PostForm.Get("client_id"))
+		}
+		switch r.URL.Path {
+		case "/login/device/code":
+			if r.PostForm.Get("scope") != "repo read:org" {
+				t.Errorf("scope: %q", r.PostForm.Get("scope"))
+			}
+			w.Write([]byte(`{"device_code":"dev123","user_code":"ABCD-1234","verification_uri":"https://github.com/login/device","expires_in":900,"interval":5}`))
+		case "/login/oauth/access_token":
+			if r.PostForm.Get("device_code") != "dev123" || r.PostForm.Get("grant_type") != "urn:ietf:params:oauth:grant-type:device_code" {
+				t.Errorf("poll form: %v", r.PostForm)
+			}
+			w.Write([]byte(polls[n]))
+			n++
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// runDeviceFlow runs the flow against srv and returns the result and the
+// intervals it waited.
+func runDeviceFlow(t *testing.T, srv *httptest.Server) (*OAuthToken, []time.Duration, error) {
+	t.Helper()
+	var waits []time.Duration
+	f := &DeviceFlow{
+		WebURL:     srv.URL,
+		ClientID:   "Iv1.test",
+		Scopes:     []string{"repo", "read:org"},
+		HTTPClient: srv.Client(),
+		sleep: func(_ context.Context, d time.Duration) error {
+			waits = append(waits, d)
+			return nil
+		},
+	}
+	dc, err := f.Start()
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if dc.UserCode != "ABCD-1234" || dc.VerificationURI != "https://github.com/login/device" {
+		t.Errorf("device code: %+v", dc)
+	}
+	tok, err := f.Wait(context.Background(), dc)
+	return tok, waits, err
+}
+
+func TestDeviceFlow_Success(t *testing.T) {
+	srv := deviceServer(t,
+		`{"error":"authorization_pending"}`,
+		`{"error":"slow_down","interval":10}`,
+		`{"error":"authorization_pending"}`,
+		`{"access_token":"gho_abc","token_type":"bearer","scope":"repo,read:org"}`,
+	)
+	tok, waits, err := runDeviceFlow(t, srv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.AccessToken != "gho_abc" || tok.Scope != "repo,read:org" {
+		t.Errorf("token: %+v", tok)
+	}
+	want := []time.Duration{5 * time.Second, 5 * time.Second, 10 * time.Second, 10 * time.Second}
+	if len(waits) != len(want) {
+		t.Fatalf("waits: %v, want %v", waits, want)
+	}
+	for i := range want {
+		if waits[i] != want[i] {
+			t.Errorf("wait %d: got %v, want %v", i, waits[i], want[i])
+		}
+	}
+}
+
+func TestDeviceFlow_Errors(t *testing.T) {
+	for answer, code := range map[string]int{
+		`{"error":"access_denied"}`:        clerrors.ExitUserAbort,
+		`{"error":"expired_token"}`:        clerrors.ExitAuthFailure,
+		`{"error":"device_flow_disabled"}`: clerrors.ExitBadArgs,
+	} {
+		_, _, err := runDeviceFlow(t, deviceServer(t, answer))
+		ce, ok := err.(*clerrors.CLIError)
+		if !ok || ce.ExitCode() != code {
+			t.Errorf("%s: got %v, want exit code %d", answer, err, code)
+		}
+	}
+}
+
+func TestDeviceFlow_Expiry(t *testing.T) {
+	f := &DeviceFlow{ClientID: "Iv1.test"}
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	_, err := f.Wait(ctx, &DeviceCode{DeviceCode: "dev123", Interval: 1})
+	ce, ok := err.(*clerrors.CLIError)
+	if !ok || ce.ExitCode() != clerrors.ExitAuthFailure {
+		t.Fatalf("got %v, want auth failure", err)
+	}
+}
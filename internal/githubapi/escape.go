@@ -0,0 +1,74 @@
+package githubapi
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+// CleanPath normalizes a repository path for the API: leading, trailing and
+// repeated slashes and "." segments are dropped, so "/docs//a.md/" becomes
+// "docs/a.md" and "/" the empty root path. A ".." segment, a NUL byte or
+// invalid UTF-8 is a CLIError with CatBadArgs; the API has no parent of the
+// root, and resolving ".." is left to the caller, which knows what the path
+// is relative to.
+func CleanPath(p string) (string, error) {
+	if strings.ContainsRune(p, 0) || !utf8.ValidString(p) {
+		return "", clerrors.NewBadArgs(fmt.Sprintf("invalid path %q", p), nil)
+	}
+	var segs []string
+	for _, s := range strings.Split(p, "/") {
+		switch s {
+		case "", ".":
+			continue
+		case "..":
+			return "", clerrors.NewBadArgs(fmt.Sprintf("path %q: \"..\" is not allowed", p), nil)
+		}
+		segs = append(segs, s)
+	}
+	return strings.Join(segs, "/"), nil
+}
+
+// EscapePath escapes each segment of a slash-separated path or ref for use
+// in a URL path, keeping the slashes: "a b/c#1" becomes "a%20b/c%231".
+func EscapePath(p string) string {
+	segs := strings.Split(p, "/")
+	for i, s := range segs {
+		segs[i] = url.PathEscape(s)
+	}
+	return strings.Join(segs, "/")
+}
+
+// repoURL builds {BaseURL}/repos/{owner}/{repo}/{parts...}, escaping each
+// part as EscapePath does. Parts that are themselves paths or refs keep
+// their slashes, as the API expects for contents paths and branch names.
+func (c *Client) repoURL(owner, repo string, parts ...string) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimSuffix(c.BaseURL, "/"))
+	b.WriteString("/repos/")
+	b.WriteString(url.PathEscape(owner))
+	b.WriteString("/")
+	b.WriteString(url.PathEscape(repo))
+	for _, p := range parts {
+		b.WriteString("/")
+		b.WriteString(EscapePath(p))
+	}
+	return b.String()
+}
+
+// contentsURL is the Contents API URL of a path, cleaned by CleanPath, with
+// ref, when set, as the ref query parameter.
+func (c *Client) contentsURL(owner, repo, path, ref string) (string, error) {
+	path, err := CleanPath(path)
+	if err != nil {
+		return "", err
+	}
+	u := c.repoURL(owner, repo, "contents", path)
+	if ref != "" {
+		u += "?" + url.Values{"ref": {ref}}.Encode()
+	}
+	return u, nil
+}
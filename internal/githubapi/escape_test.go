@@ -0,0 +1,135 @@
+package githubapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+func TestCleanPath(t *testing.T) {
+	for in, want := range map[string]string{
+		"":               "",
+		"/":              "",
+		".":              "",
+		"/docs//a.md/":   "docs/a.md",
+		"./docs/./a.md":  "docs/a.md",
+		"a..b/..c":       "a..b/..c",
+		"日本語/ファイル.md":    "日本語/ファイル.md",
+		"dir with space": "dir with space",
+	} {
+		got, err := CleanPath(in)
+		if err != nil || got != want {
+			t.Errorf("CleanPath(%q): got %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"..", "docs/../../etc", "a/..", "a\x00b", "bad\xffutf8"} {
+		_, err := CleanPath(in)
+		ce, ok := err.(*clerrors.CLIError)
+		if !ok || ce.Cat != clerrors.CatBadArgs {
+			t.Errorf("CleanPath(%q): got %v, want bad-args error", in, err)
+		}
+	}
+}
+
+func TestContentsURL(t *testing.T) {
+	c := NewClient("https://api.github.com/", "t", time.Second)
+	for _, tt := range []struct{ path, ref, want string }{
+		{"a b/c#1.md", "", "https://api.github.com/repos/o/r/contents/a%20b/c%231.md"},
+		{"/q?x=1%", "feature/a+b", "https://api.github.com/repos/o/r/contents/q%3Fx=1%25?ref=feature%2Fa%2Bb"},
+		{"café.md", "main", "https://api.github.com/repos/o/r/contents/caf%C3%A9.md?ref=main"},
+		{"", "v1", "https://api.github.com/repos/o/r/contents/?ref=v1"},
+	} {
+		got, err := c.contentsURL("o", "r", tt.path, tt.ref)
+		if err != nil || got != tt.want {
+			t.Errorf("contentsURL(%q, %q): got %q, %v; want %q", tt.path, tt.ref, got, err, tt.want)
+		}
+	}
+	if got := c.repoURL("o", "r", "commits", "feature/a b"); got != "https://api.github.com/repos/o/r/commits/feature/a%20b" {
+		t.Errorf("repoURL: %q", got)
+	}
+}
+
+// TestGetContents_Escaping checks what the server receives for a path and
+// ref that need escaping.
+func TestGetContents_Escaping(t *testing.T) {
+	var gotPath, gotRef string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotRef = r.URL.Path, r.URL.Query().Get("ref")
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "t", 5*time.Second)
+	if _, err := c.GetContents("o", "r", "/docs/100% #1 ?.md", "feature/a+b"); err != nil {
+		t.Fatal(err)
+	}
+	if gotPath != "/repos/o/r/contents/docs/100% #1 ?.md" || gotRef != "feature/a+b" {
+		t.Errorf("server got path %q, ref %q", gotPath, gotRef)
+	}
+	if _, err := c.PutContents("o", "r", "../x", &PutContentsRequest{}); err == nil {
+		t.Error("PutContents with \"..\" should fail")
+	}
+}
+
+func FuzzCleanPath(f *testing.F) {
+	for _, s := range []string{"", "/", "a/b", "/a//b/", "./a", "..", "a/../b", "日本語/ファイル.md", "emoji 😀/x"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, p string) {
+		got, err := CleanPath(p)
+		if err != nil {
+			return
+		}
+		if strings.HasPrefix(got, "/") || strings.HasSuffix(got, "/") || strings.Contains(got, "//") {
+			t.Errorf("CleanPath(%q) = %q: stray slashes", p, got)
+		}
+		for _, s := range strings.Split(got, "/") {
+			if s == ".." || s == "." {
+				t.Errorf("CleanPath(%q) = %q: %q segment", p, got, s)
+			}
+		}
+		if again, err := CleanPath(got); err != nil || again != got {
+			t.Errorf("CleanPath not idempotent on %q: %q, %v", got, again, err)
+		}
+	})
+}
+
+// FuzzContentsURL checks that any clean path and any ref survive the trip
+// through contentsURL and the server's URL parsing unchanged.
+func FuzzContentsURL(f *testing.F) {
+	for _, s := range [][2]string{
+		{"a b/c#1.md", "feature/a+b"},
+		{"100%/q?.md", "v1.0"},
+		{"日本語/ファイル.md", "リリース/1"},
+		{"emoji 😀/x;y=z", "a&b=c"},
+	} {
+		f.Add(s[0], s[1])
+	}
+	c := NewClient("https://api.github.com", "t", time.Second)
+	f.Fuzz(func(t *testing.T, p, ref string) {
+		clean, err := CleanPath(p)
+		if err != nil || !utf8.ValidString(ref) {
+			return
+		}
+		raw, err := c.contentsURL("o", "r", p, ref)
+		if err != nil {
+			t.Fatalf("contentsURL(%q): %v", p, err)
+		}
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatalf("url.Parse(%q): %v", raw, err)
+		}
+		if got := strings.TrimPrefix(u.Path, "/repos/o/r/contents/"); got != clean {
+			t.Errorf("path %q: server sees %q, want %q (%s)", p, got, clean, raw)
+		}
+		if got := u.Query().Get("ref"); got != ref {
+			t.Errorf("ref %q: server sees %q (%s)", ref, got, raw)
+		}
+	})
+}
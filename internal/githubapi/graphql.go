@@ -0,0 +1,139 @@
+package githubapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+// MaxHistoryBatch is the number of paths looked up per GraphQL query.
+const MaxHistoryBatch = 50
+
+// CommitInfo describes the last commit that touched a path.
+type CommitInfo struct {
+	SHA         string    `json:"sha"`
+	Message     string    `json:"message"`
+	AuthorName  string    `json:"author_name"`
+	AuthorEmail string    `json:"author_email"`
+	Date        time.Time `json:"date"` // committer date
+}
+
+// historyFields selects the commit fields decoded into historyNode.
+const historyFields = "nodes { oid message committedDate author { name email } }"
+
+// graphQLRequest is the JSON body for POST /graphql.
+type graphQLRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+// graphQLResponse is the envelope returned by the GraphQL API.
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// historyNode maps one commit from a history connection.
+type historyNode struct {
+	OID           string    `json:"oid"`
+	Message       string    `json:"message"`
+	CommittedDate time.Time `json:"committedDate"`
+	Author        struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	} `json:"author"`
+}
+
+// GraphQLURL returns the GraphQL endpoint for the client's REST base URL.
+// GitHub.com serves it at /graphql; GHES serves REST at /api/v3 and GraphQL
+// at /api/graphql.
+func (c *Client) GraphQLURL() string {
+	return APIEndpoints(c.BaseURL).GraphQL
+}
+
+// LastCommits returns the most recent commit touching each path at ref,
+// using a single GraphQL query with one aliased history field per path.
+// Paths with no history are absent from the result. Callers should pass at
+// most MaxHistoryBatch paths.
+func (c *Client) LastCommits(owner, repo, ref string, paths []string) (map[string]CommitInfo, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	vars := map[string]any{"owner": owner, "name": repo, "ref": ref}
+	var decls, fields strings.Builder
+	for i, p := range paths {
+		if p == "" {
+			// The repository root: history of the whole tree.
+			fmt.Fprintf(&fields, "      f%d: history(first: 1) { %s }\n", i, historyFields)
+			continue
+		}
+		fmt.Fprintf(&decls, ", $p%d: String!", i)
+		fmt.Fprintf(&fields, "      f%d: history(first: 1, path: $p%d) { %s }\n", i, i, historyFields)
+		vars[fmt.Sprintf("p%d", i)] = p
+	}
+	query := fmt.Sprintf(`query($owner: String!, $name: String!, $ref: String!%s) {
+  repository(owner: $owner, name: $name) {
+    object(expression: $ref) {
+      ... on Commit {
+%s      }
+    }
+  }
+}`, decls.String(), fields.String())
+
+	resp, err := doJSON[graphQLResponse](c, "POST", c.GraphQLURL(), graphQLRequest{Query: query, Variables: vars})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Errors) > 0 {
+		e := resp.Errors[0]
+		if e.Type == "NOT_FOUND" {
+			return nil, clerrors.NewNotFound("not found: "+e.Message, nil)
+		}
+		return nil, clerrors.NewTransport("graphql error: "+e.Message, nil)
+	}
+
+	var data struct {
+		Repository *struct {
+			Object map[string]json.RawMessage `json:"object"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		return nil, clerrors.NewTransport("failed to parse graphql response", err)
+	}
+	if data.Repository == nil || data.Repository.Object == nil {
+		return nil, clerrors.NewNotFound(fmt.Sprintf("ref %q not found in %s/%s", ref, owner, repo), nil)
+	}
+
+	result := make(map[string]CommitInfo, len(paths))
+	for i, p := range paths {
+		raw, ok := data.Repository.Object[fmt.Sprintf("f%d", i)]
+		if !ok {
+			continue
+		}
+		var history struct {
+			Nodes []historyNode `json:"nodes"`
+		}
+		if err := json.Unmarshal(raw, &history); err != nil {
+			return nil, clerrors.NewTransport("failed to parse graphql response", err)
+		}
+		if len(history.Nodes) == 0 {
+			continue
+		}
+		n := history.Nodes[0]
+		result[p] = CommitInfo{
+			SHA:         n.OID,
+			Message:     n.Message,
+			AuthorName:  n.Author.Name,
+			AuthorEmail: n.Author.Email,
+			Date:        n.CommittedDate,
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,83 @@
+package githubapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+func TestGraphQLURL(t *testing.T) {
+	tests := []struct {
+		base string
+		want string
+	}{
+		{"https://api.github.com", "https://api.github.com/graphql"},
+		{"https://ghe.example.com/api/v3", "https://ghe.example.com/api/graphql"},
+		{"https://ghe.example.com/api/v3/", "https://ghe.example.com/api/graphql"},
+	}
+	for _, tt := range tests {
+		c := NewClient(tt.base, "token", time.Second)
+		if got := c.GraphQLURL(); got != tt.want {
+			t.Errorf("GraphQLURL(%q): got %q, want %q", tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestLastCommits_Batched(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/graphql" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		var req graphQLRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if req.Variables["ref"] != "main" || req.Variables["p0"] != "a.md" || req.Variables["p1"] != "b.md" {
+			t.Errorf("unexpected variables: %v", req.Variables)
+		}
+		if !strings.Contains(req.Query, "f1: history(first: 1, path: $p1)") {
+			t.Errorf("query missing aliased history field:\n%s", req.Query)
+		}
+		w.Write([]byte(`{"data":{"repository":{"object":{
+			"f0":{"nodes":[{"oid":"c1","message":"edit a","committedDate":"2024-01-02T03:04:05Z","author":{"name":"Octo","email":"o@example.com"}}]},
+			"f1":{"nodes":[]}
+		}}}}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "token", 5*time.Second)
+	got, err := c.LastCommits("owner", "repo", "main", []string{"a.md", "b.md"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(got))
+	}
+	a := got["a.md"]
+	want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if a.SHA != "c1" || a.AuthorName != "Octo" || !a.Date.Equal(want) {
+		t.Errorf("a.md: got %+v", a)
+	}
+}
+
+func TestLastCommits_NotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"repository":null},"errors":[{"type":"NOT_FOUND","message":"Could not resolve to a Repository"}]}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "token", 5*time.Second)
+	_, err := c.LastCommits("owner", "missing", "", []string{"a.md"})
+	ce, ok := err.(*clerrors.CLIError)
+	if !ok {
+		t.Fatalf("expected CLIError, got %T", err)
+	}
+	if ce.ExitCode() != clerrors.ExitNotFound {
+		t.Errorf("exit code: got %d, want %d", ce.ExitCode(), clerrors.ExitNotFound)
+	}
+}
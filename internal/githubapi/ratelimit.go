@@ -0,0 +1,193 @@
+package githubapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+// Budget limits the requests one run may make. It tracks the remaining
+// quota of each rate-limit resource from the X-RateLimit-* headers of every
+// response, and stops before a request would take a resource below Reserve:
+// with an error, or, when Wait is set, by pausing until the quota resets.
+// A Budget may be shared by several clients; a nil Budget allows everything.
+type Budget struct {
+	MaxRequests int  // 0: no limit on the number of requests
+	Reserve     int  // requests to leave unused in each resource
+	Wait        bool // pause until the reset instead of failing at Reserve
+
+	// OnWait, when set, is called before pausing.
+	OnWait func(resource string, remaining int, until time.Time)
+
+	mu       sync.Mutex
+	requests int
+	quota    map[string]quota
+	now      func() time.Time    // replaced in tests
+	sleep    func(time.Duration) // replaced in tests
+}
+
+// quota is the last known state of one rate-limit resource.
+type quota struct {
+	remaining int
+	reset     time.Time
+}
+
+// Requests returns the number of requests made so far.
+func (b *Budget) Requests() int {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.requests
+}
+
+// acquire reserves one request against resource, failing or pausing as
+// configured when the budget is spent.
+func (b *Budget) acquire(resource string) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.MaxRequests > 0 && b.requests >= b.MaxRequests {
+		return clerrors.NewRateLimit(fmt.Sprintf("request budget exhausted: %d requests made (--max-requests)", b.requests), nil)
+	}
+
+	now := b.clock()
+	if q, ok := b.quota[resource]; ok && q.remaining <= b.Reserve {
+		switch {
+		case !q.reset.After(now):
+			// The window has reset since; the next response tells the new state.
+			delete(b.quota, resource)
+		case !b.Wait:
+			e := clerrors.NewRateLimit(fmt.Sprintf("stopped to keep the %s rate-limit reserve: %d requests left, reserve %d, resets at %s (use --wait-for-reset to pause instead)",
+				resource, q.remaining, b.Reserve, q.reset.Format(time.RFC3339)), nil)
+			e.RateLimitReset = q.reset
+			return e
+		default:
+			if b.OnWait != nil {
+				b.OnWait(resource, q.remaining, q.reset)
+			}
+			// Sleeping with the lock held pauses every other request too.
+			sleep := b.sleep
+			if sleep == nil {
+				sleep = time.Sleep
+			}
+			sleep(q.reset.Sub(now) + time.Second)
+			delete(b.quota, resource)
+		}
+	}
+
+	b.requests++
+	if q, ok := b.quota[resource]; ok {
+		// Count requests still in flight until their responses report back.
+		q.remaining--
+		b.quota[resource] = q
+	}
+	return nil
+}
+
+// observe records the quota reported by a response. The resource named by
+// X-RateLimit-Resource takes precedence over the one guessed from the URL.
+func (b *Budget) observe(resource string, h http.Header) {
+	if b == nil {
+		return
+	}
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return // GHES with rate limiting disabled sends no headers
+	}
+	if r := h.Get("X-RateLimit-Resource"); r != "" {
+		resource = r
+	}
+	var reset time.Time
+	if secs, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		reset = time.Unix(secs, 0).UTC()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.quota == nil {
+		b.quota = make(map[string]quota)
+	}
+	if q, ok := b.quota[resource]; ok && q.reset.Equal(reset) && q.remaining < remaining {
+		// A response overtaken by later ones within the same window.
+		return
+	}
+	b.quota[resource] = quota{remaining: remaining, reset: reset}
+}
+
+func (b *Budget) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
+
+// rateResource guesses the rate-limit resource a request URL counts against.
+func rateResource(url string) string {
+	switch {
+	case strings.HasSuffix(url, "/graphql"):
+		return "graphql"
+	case strings.Contains(url, "/search/code"):
+		return "code_search"
+	case strings.Contains(url, "/search/"):
+		return "search"
+	}
+	return "core"
+}
+
+// RateLimit is the state of one rate-limit resource from GET /rate_limit.
+type RateLimit struct {
+	Resource  string
+	Limit     int
+	Used      int
+	Remaining int
+	Reset     time.Time
+}
+
+// GetRateLimits calls GET /rate_limit, which does not count against the
+// quota, and returns every resource sorted by name with core first.
+func (c *Client) GetRateLimits() ([]RateLimit, error) {
+	raw, err := c.doGet(c.BaseURL + "/rate_limit")
+	if err != nil {
+		return nil, err
+	}
+	var body struct {
+		Resources map[string]struct {
+			Limit     int   `json:"limit"`
+			Used      int   `json:"used"`
+			Remaining int   `json:"remaining"`
+			Reset     int64 `json:"reset"`
+		} `json:"resources"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return nil, clerrors.NewTransport("failed to parse rate limit response", err)
+	}
+	limits := make([]RateLimit, 0, len(body.Resources))
+	for name, r := range body.Resources {
+		limits = append(limits, RateLimit{
+			Resource:  name,
+			Limit:     r.Limit,
+			Used:      r.Used,
+			Remaining: r.Remaining,
+			Reset:     time.Unix(r.Reset, 0).UTC(),
+		})
+	}
+	sort.Slice(limits, func(i, j int) bool {
+		a, b := limits[i].Resource, limits[j].Resource
+		if (a == "core") != (b == "core") {
+			return a == "core"
+		}
+		return a < b
+	})
+	return limits, nil
+}
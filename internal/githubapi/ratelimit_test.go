@@ -0,0 +1,156 @@
+package githubapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+// quotaServer answers every request with the remaining core quota, which
+// drops by one per request and resets at reset.
+func quotaServer(t *testing.T, remaining int, reset time.Time) (*httptest.Server, *int) {
+	t.Helper()
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		remaining--
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		w.Header().Set("X-RateLimit-Resource", "core")
+		w.Write([]byte(`{"login":"octocat"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestBudget_MaxRequests(t *testing.T) {
+	srv, calls := quotaServer(t, 5000, time.Now().Add(time.Hour))
+	c := NewClient(srv.URL, "t", 5*time.Second)
+	c.Budget = &Budget{MaxRequests: 2}
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetAuthenticatedUser(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := c.GetAuthenticatedUser()
+	ce, ok := err.(*clerrors.CLIError)
+	if !ok || ce.Cat != clerrors.CatRateLimit || !strings.Contains(err.Error(), "--max-requests") {
+		t.Fatalf("got %v, want budget error", err)
+	}
+	if *calls != 2 || c.Budget.Requests() != 2 {
+		t.Errorf("calls %d, requests %d", *calls, c.Budget.Requests())
+	}
+}
+
+func TestBudget_ReserveAborts(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	srv, calls := quotaServer(t, 13, reset)
+	c := NewClient(srv.URL, "t", 5*time.Second)
+	c.Budget = &Budget{Reserve: 10}
+	var err error
+	for i := 0; i < 5 && err == nil; i++ {
+		_, err = c.GetAuthenticatedUser()
+	}
+	ce, ok := err.(*clerrors.CLIError)
+	if !ok || ce.Cat != clerrors.CatRateLimit || !ce.RateLimitReset.Equal(reset) {
+		t.Fatalf("got %v, want reserve error with reset", err)
+	}
+	// 12, 11 and 10 were reported; the request that would leave 9 is refused.
+	if *calls != 3 {
+		t.Errorf("calls: got %d, want 3", *calls)
+	}
+}
+
+func TestBudget_ReserveWaits(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var slept time.Duration
+	var waited string
+	b := &Budget{
+		Reserve: 1,
+		Wait:    true,
+		OnWait:  func(resource string, _ int, _ time.Time) { waited = resource },
+		now:     func() time.Time { return now },
+		sleep:   func(d time.Duration) { slept = d },
+	}
+	h := http.Header{}
+	h.Set("X-RateLimit-Remaining", "1")
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(time.Minute).Unix(), 10))
+	h.Set("X-RateLimit-Resource", "graphql")
+	b.observe("core", h)
+
+	if err := b.acquire("core"); err != nil || slept != 0 {
+		t.Fatalf("core should be unaffected: %v, slept %v", err, slept)
+	}
+	if err := b.acquire("graphql"); err != nil {
+		t.Fatal(err)
+	}
+	if waited != "graphql" || slept != time.Minute+time.Second {
+		t.Errorf("waited %q for %v", waited, slept)
+	}
+	if b.Requests() != 2 {
+		t.Errorf("requests: %d", b.Requests())
+	}
+}
+
+func TestBudget_ResetWindowClears(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := &Budget{Reserve: 5, now: func() time.Time { return now }}
+	h := http.Header{}
+	h.Set("X-RateLimit-Remaining", "0")
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(-time.Second).Unix(), 10))
+	b.observe("core", h)
+	if err := b.acquire("core"); err != nil {
+		t.Errorf("quota from a past window should not block: %v", err)
+	}
+}
+
+func TestRateResource(t *testing.T) {
+	for url, want := range map[string]string{
+		"https://api.github.com/repos/o/r/contents/a": "core",
+		"https://api.github.com/graphql":              "graphql",
+		"https://ghe.example.com/api/graphql":         "graphql",
+		"https://api.github.com/search/code?q=x":      "code_search",
+		"https://api.github.com/search/issues?q=x":    "search",
+	} {
+		if got := rateResource(url); got != want {
+			t.Errorf("%s: got %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestGetRateLimits(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rate_limit" {
+			t.Errorf("path: %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"resources":{
+			"search":{"limit":30,"used":1,"remaining":29,"reset":1767225600},
+			"core":{"limit":5000,"used":10,"remaining":4990,"reset":1767225600},
+			"graphql":{"limit":5000,"used":0,"remaining":5000,"reset":1767225600}}}`))
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL, "t", 5*time.Second)
+	c.Budget = &Budget{MaxRequests: 1}
+	c.Budget.requests = 1 // spent; /rate_limit must still work
+
+	limits, err := c.GetRateLimits()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, l := range limits {
+		names = append(names, l.Resource)
+	}
+	if strings.Join(names, ",") != "core,graphql,search" {
+		t.Errorf("order: %v", names)
+	}
+	want := RateLimit{Resource: "core", Limit: 5000, Used: 10, Remaining: 4990, Reset: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if limits[0] != want {
+		t.Errorf("core: got %+v, want %+v", limits[0], want)
+	}
+}
@@ -6,9 +6,17 @@ import (
 	clerrors "githubRAGCli/internal/exitcode"
 )
 
-// ParseRepo splits an "owner/repo" string into its components.
+// ParseRepo splits an "owner/repo" string into its components. A GitHub
+// URL (see ParseURL) is accepted too; its ref and path are ignored.
 // Returns a CLIError with CatBadArgs if the format is invalid.
 func ParseRepo(ownerRepo string) (owner, repo string, err error) {
+	if IsURL(ownerRepo) {
+		u, err := ParseURL(ownerRepo)
+		if err != nil {
+			return "", "", err
+		}
+		return u.Owner, u.Repo, nil
+	}
 	parts := strings.SplitN(ownerRepo, "/", 3)
 	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
 		return "", "", clerrors.NewBadArgs("invalid owner/repo format: "+ownerRepo, nil)
@@ -0,0 +1,184 @@
+package githubapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Endpoints are the base URLs of one GitHub host. GitHub.com spreads them
+// over several hosts; GitHub Enterprise Server serves them all from its own
+// host under different paths.
+type Endpoints struct {
+	Web     string // e.g. https://github.com, https://ghe.example.com
+	API     string // REST API
+	GraphQL string // GraphQL API
+	Uploads string // release asset uploads
+	Raw     string // raw file content, see Client.GetRaw
+}
+
+// HostEndpoints returns the endpoints of a host given as a name such as
+// "ghe.example.com" or as a URL such as "http://127.0.0.1:8080". Any host
+// other than github.com is taken to be GitHub Enterprise Server.
+func HostEndpoints(host string) (Endpoints, error) {
+	web := strings.TrimSpace(host)
+	if !strings.Contains(web, "://") {
+		web = "https://" + web
+	}
+	u, err := url.Parse(web)
+	if err != nil || u.Host == "" || (u.Path != "" && u.Path != "/") {
+		return Endpoints{}, fmt.Errorf("invalid host %q", host)
+	}
+	u.Host = strings.ToLower(u.Host)
+	web = u.Scheme + "://" + u.Host
+	if u.Host == "github.com" || u.Host == "www.github.com" {
+		return Endpoints{
+			Web:     "https://github.com",
+			API:     "https://api.github.com",
+			GraphQL: "https://api.github.com/graphql",
+			Uploads: "https://uploads.github.com",
+			Raw:     "https://raw.githubusercontent.com",
+		}, nil
+	}
+	return Endpoints{
+		Web:     web,
+		API:     web + "/api/v3",
+		GraphQL: web + "/api/graphql",
+		Uploads: web + "/api/uploads",
+		Raw:     web + "/raw",
+	}, nil
+}
+
+// APIEndpoints derives the endpoints from a REST API base URL:
+// https://api.github.com is GitHub.com and https://host/api/v3 is GHES.
+// Other bases, such as a proxy or test server, serve everything themselves.
+func APIEndpoints(apiBase string) Endpoints {
+	base := strings.TrimSuffix(apiBase, "/")
+	if web, ok := strings.CutSuffix(base, "/api/v3"); ok {
+		if e, err := HostEndpoints(web); err == nil {
+			e.API = base
+			return e
+		}
+	}
+	if strings.EqualFold(base, "https://api.github.com") {
+		e, _ := HostEndpoints("github.com")
+		return e
+	}
+	return Endpoints{
+		Web:     strings.Replace(base, "://api.", "://", 1),
+		API:     base,
+		GraphQL: base + "/graphql",
+		Uploads: base,
+		Raw:     base,
+	}
+}
+
+// Feature is an API capability that GitHub Enterprise Server gained in a
+// given release.
+type Feature struct {
+	Name       string
+	MinVersion string // first GHES release that supports it
+}
+
+var (
+	// FeatureAPIVersionHeader is the X-GitHub-Api-Version request header.
+	FeatureAPIVersionHeader = Feature{"REST API versions", "3.9"}
+	// FeatureDeviceFlow is the OAuth device flow used by auth login.
+	FeatureDeviceFlow = Feature{"OAuth device flow", "3.1"}
+)
+
+// ServerInfo describes the server behind an API base URL.
+type ServerInfo struct {
+	Enterprise bool
+	Version    string // GHES release, e.g. "3.12.4"; empty for GitHub.com
+}
+
+// String names the server for messages, e.g. "GitHub Enterprise Server 3.12.4".
+func (s *ServerInfo) String() string {
+	switch {
+	case s == nil:
+		return "unknown"
+	case !s.Enterprise:
+		return "GitHub.com"
+	case s.Version == "":
+		return "GitHub Enterprise Server"
+	}
+	return "GitHub Enterprise Server " + s.Version
+}
+
+// Supports reports whether the server has f. GitHub.com, and servers whose
+// version is unknown, are assumed to support everything.
+func (s *ServerInfo) Supports(f Feature) bool {
+	if s == nil || !s.Enterprise || s.Version == "" {
+		return true
+	}
+	return compareVersions(s.Version, f.MinVersion) >= 0
+}
+
+// Require returns an error naming the server when it lacks f.
+func (s *ServerInfo) Require(f Feature) error {
+	if s.Supports(f) {
+		return nil
+	}
+	return fmt.Errorf("%s needs GitHub Enterprise Server %s or later; this server runs %s", f.Name, f.MinVersion, s.Version)
+}
+
+// DetectServer identifies the server with GET /meta, whose installed_version
+// field only GHES sends. GitHub.com is recognized from the base URL without
+// a request. A server that does not answer /meta, like a proxy without it,
+// gives a ServerInfo with an unknown version rather than an error.
+func (c *Client) DetectServer() (*ServerInfo, error) {
+	if strings.EqualFold(strings.TrimSuffix(c.BaseURL, "/"), "https://api.github.com") {
+		return &ServerInfo{}, nil
+	}
+	req, err := http.NewRequest("GET", strings.TrimSuffix(c.BaseURL, "/")+"/meta", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	// Servers in private mode only answer authenticated requests.
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	resp, body, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	info := &ServerInfo{Enterprise: strings.HasSuffix(strings.TrimSuffix(c.BaseURL, "/"), "/api/v3")}
+	if resp.StatusCode != http.StatusOK {
+		return info, nil
+	}
+	var meta struct {
+		InstalledVersion string `json:"installed_version"`
+	}
+	if json.Unmarshal(body, &meta) == nil && meta.InstalledVersion != "" {
+		info.Enterprise = true
+		info.Version = meta.InstalledVersion
+	}
+	return info, nil
+}
+
+// compareVersions compares dotted numeric versions such as "3.12.4" and
+// "3.9", returning -1, 0 or 1. Missing or non-numeric parts count as 0.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < max(len(as), len(bs)); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
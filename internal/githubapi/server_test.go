@@ -0,0 +1,105 @@
+package githubapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHostEndpoints(t *testing.T) {
+	tests := []struct {
+		host string
+		want Endpoints
+	}{
+		{"github.com", Endpoints{"https://github.com", "https://api.github.com", "https://api.github.com/graphql", "https://uploads.github.com", "https://raw.githubusercontent.com"}},
+		{"GHE.example.com", Endpoints{"https://ghe.example.com", "https://ghe.example.com/api/v3", "https://ghe.example.com/api/graphql", "https://ghe.example.com/api/uploads", "https://ghe.example.com/raw"}},
+		{"http://127.0.0.1:8080/", Endpoints{"http://127.0.0.1:8080", "http://127.0.0.1:8080/api/v3", "http://127.0.0.1:8080/api/graphql", "http://127.0.0.1:8080/api/uploads", "http://127.0.0.1:8080/raw"}},
+	}
+	for _, tt := range tests {
+		got, err := HostEndpoints(tt.host)
+		if err != nil || got != tt.want {
+			t.Errorf("HostEndpoints(%q): got %+v, %v; want %+v", tt.host, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "https://", "ghe.example.com/owner"} {
+		if _, err := HostEndpoints(bad); err == nil {
+			t.Errorf("HostEndpoints(%q): expected error", bad)
+		}
+	}
+}
+
+func TestAPIEndpoints(t *testing.T) {
+	if e := APIEndpoints("https://ghe.example.com/api/v3/"); e.Raw != "https://ghe.example.com/raw" || e.Uploads != "https://ghe.example.com/api/uploads" || e.API != "https://ghe.example.com/api/v3" {
+		t.Errorf("GHES: %+v", e)
+	}
+	if e := APIEndpoints("https://api.github.com"); e.Uploads != "https://uploads.github.com" || e.Raw != "https://raw.githubusercontent.com" {
+		t.Errorf("github.com: %+v", e)
+	}
+	if e := APIEndpoints("http://127.0.0.1:8080"); e.Web != "http://127.0.0.1:8080" || e.GraphQL != "http://127.0.0.1:8080/graphql" {
+		t.Errorf("test server: %+v", e)
+	}
+}
+
+func TestDetectServer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/meta":
+			if r.Header.Get("Authorization") != "Bearer tok" {
+				t.Errorf("auth: %q", r.Header.Get("Authorization"))
+			}
+			w.Write([]byte(`{"verifiable_password_authentication":true,"installed_version":"3.8.2"}`))
+		case "/api/v3/user":
+			if v := r.Header.Get("X-GitHub-Api-Version"); v != "" {
+				t.Errorf("GHES 3.8 should not get X-GitHub-Api-Version, got %q", v)
+			}
+			w.Write([]byte(`{"login":"octocat"}`))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL+"/api/v3", "tok", 5*time.Second)
+	info, err := c.DetectServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Enterprise || info.Version != "3.8.2" || info.String() != "GitHub Enterprise Server 3.8.2" {
+		t.Errorf("info: %+v", info)
+	}
+	if info.Supports(FeatureAPIVersionHeader) || !info.Supports(FeatureDeviceFlow) {
+		t.Error("feature gates for 3.8.2")
+	}
+	if info.Require(FeatureAPIVersionHeader) == nil {
+		t.Error("Require should fail for a missing feature")
+	}
+	c.Server = info
+	if _, err := c.GetAuthenticatedUser(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A server without /meta has an unknown version and keeps every feature.
+	info, err = NewClient(srv.URL, "", 5*time.Second).DetectServer()
+	if err != nil || info.Enterprise || !info.Supports(FeatureAPIVersionHeader) {
+		t.Errorf("no meta: %+v %v", info, err)
+	}
+	if info, _ := NewClient("https://api.github.com", "", time.Second).DetectServer(); info.String() != "GitHub.com" {
+		t.Errorf("github.com: %v", info)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"3.12.4", "3.9", 1},
+		{"3.9", "3.9.0", 0},
+		{"3.1", "3.10", -1},
+	} {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package githubapi
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultTraceBody is how much of a body TraceTransport logs by default.
+const defaultTraceBody = 4096
+
+// TraceTransport is an http.RoundTripper that logs every request it passes
+// on: method, URL, status, latency and rate-limit headers, and with Bodies
+// also the headers and the start of each body. Authorization and cookie
+// headers are never shown, and token-looking strings are redacted.
+type TraceTransport struct {
+	Next    http.RoundTripper // nil: http.DefaultTransport
+	Out     io.Writer         // where entries go; each is written at once
+	Bodies  bool              // also log headers and bodies
+	MaxBody int               // body bytes logged; 0: 4096
+
+	mu  sync.Mutex
+	seq int
+	now func() time.Time // for tests
+}
+
+// RoundTrip logs req, sends it with Next and logs the response or error.
+func (t *TraceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.seq++
+	id := t.seq
+	t.mu.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--> #%d %s %s\n", id, req.Method, RedactSecrets(req.URL.String()))
+	if t.Bodies {
+		writeHeaders(&b, "-->", req.Header)
+		// GetBody gives a copy, leaving req.Body to the transport.
+		if req.GetBody != nil {
+			if rc, err := req.GetBody(); err == nil {
+				body, _ := io.ReadAll(rc)
+				rc.Close()
+				t.writeBody(&b, "-->", body)
+			}
+		}
+	}
+	t.write(b.String())
+
+	start := t.clock()
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	resp, err := next.RoundTrip(req)
+	elapsed := t.clock().Sub(start).Round(100 * time.Microsecond)
+	b.Reset()
+	if err != nil {
+		fmt.Fprintf(&b, "<-- #%d error after %v: %s\n", id, elapsed, RedactSecrets(err.Error()))
+		t.write(b.String())
+		return nil, err
+	}
+
+	fmt.Fprintf(&b, "<-- #%d %s %v%s\n", id, resp.Status, elapsed, rateSummary(resp.Header))
+	if t.Bodies {
+		writeHeaders(&b, "<--", resp.Header)
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		t.writeBody(&b, "<--", body)
+		if err != nil {
+			fmt.Fprintf(&b, "<-- (body read failed: %v)\n", err)
+		}
+	}
+	t.write(b.String())
+	return resp, nil
+}
+
+func (t *TraceTransport) clock() time.Time {
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now()
+}
+
+func (t *TraceTransport) write(s string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	io.WriteString(t.Out, s)
+}
+
+// writeBody logs up to MaxBody bytes of body, redacted before it is cut
+// so that no part of a secret slips through.
+func (t *TraceTransport) writeBody(b *strings.Builder, dir string, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+	limit := t.MaxBody
+	if limit <= 0 {
+		limit = defaultTraceBody
+	}
+	shown := RedactSecrets(string(body))
+	cut := len(shown) > limit
+	if cut {
+		shown = shown[:limit]
+	}
+	for _, line := range strings.Split(shown, "\n") {
+		fmt.Fprintf(b, "%s   %s\n", dir, line)
+	}
+	if cut {
+		fmt.Fprintf(b, "%s   ... (truncated, %d bytes in all)\n", dir, len(body))
+	}
+}
+
+// hiddenHeaders are left out of traces entirely, whatever their value.
+var hiddenHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// writeHeaders logs h sorted by name, with hiddenHeaders redacted.
+func writeHeaders(b *strings.Builder, dir string, h http.Header) {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := "[REDACTED]"
+		if !hiddenHeaders[http.CanonicalHeaderKey(name)] {
+			value = RedactSecrets(strings.Join(h[name], ", "))
+		}
+		fmt.Fprintf(b, "%s %s: %s\n", dir, name, value)
+	}
+}
+
+// rateSummary formats the rate-limit headers of a response, if any, as
+// " (core 4990/5000 left, reset 12:00:00)".
+func rateSummary(h http.Header) string {
+	remaining := h.Get("X-RateLimit-Remaining")
+	if remaining == "" {
+		return ""
+	}
+	if limit := h.Get("X-RateLimit-Limit"); limit != "" {
+		remaining += "/" + limit
+	}
+	parts := []string{strings.TrimSpace(h.Get("X-RateLimit-Resource") + " " + remaining + " left")}
+	if secs, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		parts = append(parts, "reset "+time.Unix(secs, 0).Local().Format(time.TimeOnly))
+	}
+	if ra := h.Get("Retry-After"); ra != "" {
+		parts = append(parts, "retry after "+ra+"s")
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+// secretPatterns match credentials in URLs, bodies and error messages.
+// Each replacement keeps the group that names the credential.
+var secretPatterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// GitHub tokens: ghp_, gho_, ghu_, ghs_, ghr_ and github_pat_.
+	{regexp.MustCompile(`\b(gh[pousr]_|github_pat_)[A-Za-z0-9_]+`), "${1}[REDACTED]"},
+	// JWTs, such as GitHub App assertions.
+	{regexp.MustCompile(`\beyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`), "[REDACTED]"},
+	// JSON fields holding credentials.
+	{regexp.MustCompile(`("(?:token|access_token|refresh_token|client_secret|device_code|password)"\s*:\s*")[^"]*`), "${1}[REDACTED]"},
+	// Query and form parameters holding credentials.
+	{regexp.MustCompile(`\b((?:token|access_token|refresh_token|client_secret|device_code|password)=)[^&\s"]+`), "${1}[REDACTED]"},
+	// Credentials after an authorization scheme, and 40-hex classic tokens.
+	{regexp.MustCompile(`(?i)\b((?:bearer|basic) )[A-Za-z0-9._~+/=-]{8,}`), "${1}[REDACTED]"},
+	{regexp.MustCompile(`\b(token )[0-9a-f]{40}\b`), "${1}[REDACTED]"},
+	// Passwords in URLs.
+	{regexp.MustCompile(`(://[^/@\s:]+:)[^/@\s]+@`), "${1}[REDACTED]@"},
+}
+
+// RedactSecrets replaces tokens, JWTs and credential fields in s with
+// [REDACTED].
+func RedactSecrets(s string) string {
+	for _, p := range secretPatterns {
+		s = p.re.ReplaceAllString(s, p.repl)
+	}
+	return s
+}
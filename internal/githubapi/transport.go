@@ -0,0 +1,130 @@
+package githubapi
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+// TransportOptions configure the HTTP transport for networks that need a
+// proxy, trust a private CA or require client certificates.
+type TransportOptions struct {
+	// Proxy is the proxy URL, e.g. http://proxy.example.com:3128. When
+	// empty, HTTPS_PROXY, HTTP_PROXY and NO_PROXY apply.
+	Proxy string
+	// CAFile holds PEM CA certificates trusted in addition to the system's.
+	CAFile string
+	// ClientCert and ClientKey are the PEM client certificate and key for
+	// mTLS. ClientKey may be empty when ClientCert holds both.
+	ClientCert string
+	ClientKey  string
+	// InsecureSkipVerify turns off server certificate verification.
+	InsecureSkipVerify bool
+	// DisableHTTP2 keeps connections on HTTP/1.1.
+	DisableHTTP2 bool
+}
+
+// NewTransport returns an http.Transport configured by o, based on
+// http.DefaultTransport. Unreadable or invalid files and proxy URLs are
+// CLIErrors with CatBadArgs.
+func NewTransport(o TransportOptions) (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if o.Proxy != "" {
+		u, err := parseProxy(o.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, clerrors.NewBadArgs(fmt.Sprintf("cannot read CA file %s", o.CAFile), err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, clerrors.NewBadArgs(fmt.Sprintf("CA file %s holds no PEM certificates", o.CAFile), nil)
+		}
+		t.TLSClientConfig.RootCAs = pool
+	}
+
+	switch {
+	case o.ClientCert != "":
+		key := o.ClientKey
+		if key == "" {
+			key = o.ClientCert
+		}
+		cert, err := tls.LoadX509KeyPair(o.ClientCert, key)
+		if err != nil {
+			return nil, clerrors.NewBadArgs(fmt.Sprintf("cannot load client certificate %s: %v", o.ClientCert, err), nil)
+		}
+		t.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	case o.ClientKey != "":
+		return nil, clerrors.NewBadArgs("a client key needs a client certificate", nil)
+	}
+
+	t.TLSClientConfig.InsecureSkipVerify = o.InsecureSkipVerify
+
+	// A custom TLS config turns off HTTP/2 unless it is asked for.
+	t.ForceAttemptHTTP2 = !o.DisableHTTP2
+	if o.DisableHTTP2 {
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return t, nil
+}
+
+// parseProxy parses a proxy URL; a bare host:port means an HTTP proxy.
+func parseProxy(s string) (*url.URL, error) {
+	if !strings.Contains(s, "://") {
+		s = "http://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return nil, clerrors.NewBadArgs(fmt.Sprintf("invalid proxy URL %q", redactURL(s)), nil)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, clerrors.NewBadArgs(fmt.Sprintf("unsupported proxy scheme %q (want http, https or socks5)", u.Scheme), nil)
+	}
+	return u, nil
+}
+
+// ProxyFor returns the proxy t uses for rawURL, with any password
+// redacted, or "" when it connects directly.
+func ProxyFor(t *http.Transport, rawURL string) string {
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil || t.Proxy == nil {
+		return ""
+	}
+	u, err := t.Proxy(req)
+	if err != nil || u == nil {
+		return ""
+	}
+	return u.Redacted()
+}
+
+// redactURL hides the password of a URL that failed to parse fully.
+func redactURL(s string) string {
+	if u, err := url.Parse(s); err == nil {
+		return u.Redacted()
+	}
+	if scheme, rest, ok := strings.Cut(s, "://"); ok {
+		if i := strings.LastIndex(rest, "@"); i >= 0 {
+			return scheme + "://xxxxx@" + rest[i+1:]
+		}
+	}
+	return s
+}
@@ -0,0 +1,159 @@
+package githubapi
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+// RepoURL is a repository, and optionally a ref and a path in it, taken
+// from a GitHub URL.
+type RepoURL struct {
+	Web   string // web URL of the host, e.g. https://github.com
+	Owner string
+	Repo  string
+	Ref   string // empty when the URL names none
+	Path  string // path in the repository, without leading slash
+
+	// RefPath is set instead of Ref and Path when the URL holds
+	// "<ref>/<path>" and the ref may itself contain slashes, as in
+	// .../blob/feature/x/docs/a.md. Client.SplitRefPath resolves it.
+	RefPath string
+}
+
+// commitSHA matches abbreviated and full commit SHAs, which never contain
+// slashes and so need no resolution.
+var commitSHA = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// IsURL reports whether s looks like a URL rather than "owner/repo" or
+// "host/owner/repo": it has a scheme or is an scp-style git@host:path.
+func IsURL(s string) bool {
+	return strings.Contains(s, "://") || (strings.HasPrefix(s, "git@") && strings.Contains(s, ":"))
+}
+
+// ParseURL parses the URL forms people copy from GitHub:
+//
+//	https://github.com/o/r and https://github.com/o/r.git
+//	https://github.com/o/r/blob/<ref>/<path> (also tree, raw, blame, edit)
+//	https://github.com/o/r/commit/<sha> and .../commits/<ref>
+//	https://raw.githubusercontent.com/o/r/<ref>/<path>
+//	https://ghe.example.com/raw/o/r/<ref>/<path>
+//	git@github.com:o/r.git and ssh://git@github.com/o/r.git
+//
+// Query strings and fragments such as #L10 are ignored. Errors are
+// CLIErrors with CatBadArgs.
+func ParseURL(s string) (*RepoURL, error) {
+	u, err := parseURL(s)
+	if err != nil {
+		return nil, clerrors.NewBadArgs(fmt.Sprintf("invalid GitHub URL %q: %v", s, err), nil)
+	}
+	return u, nil
+}
+
+func parseURL(s string) (*RepoURL, error) {
+	scheme, host, path := "", "", ""
+	if rest, ok := strings.CutPrefix(s, "git@"); ok && !strings.Contains(s, "://") {
+		// scp-style: git@host:owner/repo.git
+		host, path, _ = strings.Cut(rest, ":")
+		scheme = "ssh"
+	} else {
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, errors.New("not a URL")
+		}
+		scheme, host, path = u.Scheme, u.Host, u.Path
+	}
+	host = strings.ToLower(host)
+	if host == "" {
+		return nil, errors.New("no host")
+	}
+
+	r := &RepoURL{}
+	switch scheme {
+	case "http", "https":
+		r.Web = scheme + "://" + host
+	case "ssh", "git", "git+ssh":
+		// The port, if any, is the SSH port; the web host is served over HTTPS.
+		name, _, _ := strings.Cut(host, ":")
+		r.Web = "https://" + name
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q", scheme)
+	}
+
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	raw := false
+	switch {
+	case host == "raw.githubusercontent.com":
+		r.Web, raw = "https://github.com", true
+	case host == "www.github.com":
+		r.Web = "https://github.com"
+	case r.Web != "https://github.com" && len(segs) > 0 && segs[0] == "raw":
+		// GHES serves raw content at /raw/<owner>/<repo>/<ref>/<path>.
+		segs, raw = segs[1:], true
+	}
+	if len(segs) < 2 || segs[0] == "" || segs[1] == "" {
+		return nil, errors.New("no owner/repo in the path")
+	}
+	r.Owner, r.Repo = segs[0], strings.TrimSuffix(segs[1], ".git")
+	rest := segs[2:]
+
+	if raw {
+		r.setRefPath(rest)
+		return r, nil
+	}
+	if len(rest) == 0 {
+		return r, nil
+	}
+	switch rest[0] {
+	case "blob", "tree", "raw", "blame", "edit":
+		r.setRefPath(rest[1:])
+	case "commit", "commits":
+		if len(rest) > 1 {
+			r.Ref = strings.Join(rest[1:], "/")
+		}
+	default:
+		return nil, fmt.Errorf("%q pages do not name repository content", rest[0])
+	}
+	return r, nil
+}
+
+// setRefPath stores the "<ref>/<path>" segments, in Ref and Path when the
+// split is certain and in RefPath otherwise. A refs/heads/ or refs/tags/
+// prefix, as in raw URLs, is dropped.
+func (r *RepoURL) setRefPath(segs []string) {
+	if len(segs) > 2 && segs[0] == "refs" && (segs[1] == "heads" || segs[1] == "tags") {
+		segs = segs[2:]
+	}
+	switch {
+	case len(segs) == 0 || segs[0] == "":
+	case len(segs) == 1 || commitSHA.MatchString(segs[0]):
+		r.Ref, r.Path = segs[0], strings.Join(segs[1:], "/")
+	default:
+		r.RefPath = strings.Join(segs, "/")
+	}
+}
+
+// SplitRefPath splits "<ref>/<path>" where the ref may contain slashes. It
+// tries each prefix as a branch name, shortest first; git does not allow
+// both "a" and "a/b" as branches, so at most one matches. When none does,
+// the first segment is taken as the ref, e.g. a tag or commit.
+func (c *Client) SplitRefPath(owner, repo, refPath string) (ref, path string, err error) {
+	segs := strings.Split(refPath, "/")
+	for i := 1; i <= len(segs) && len(segs) > 1; i++ {
+		candidate := strings.Join(segs[:i], "/")
+		_, err := c.doGet(c.repoURL(owner, repo, "branches", candidate))
+		var ce *clerrors.CLIError
+		if errors.As(err, &ce) && ce.Cat == clerrors.CatNotFound {
+			continue
+		}
+		if err != nil {
+			return "", "", err
+		}
+		return candidate, strings.Join(segs[i:], "/"), nil
+	}
+	return segs[0], strings.Join(segs[1:], "/"), nil
+}
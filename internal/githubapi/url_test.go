@@ -0,0 +1,88 @@
+package githubapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want RepoURL
+	}{
+		{"https://github.com/o/r", RepoURL{Web: "https://github.com", Owner: "o", Repo: "r"}},
+		{"https://www.github.com/o/r.git", RepoURL{Web: "https://github.com", Owner: "o", Repo: "r"}},
+		{"https://github.com/o/r/blob/main/docs/a.md#L10", RepoURL{Web: "https://github.com", Owner: "o", Repo: "r", RefPath: "main/docs/a.md"}},
+		{"https://github.com/o/r/tree/main", RepoURL{Web: "https://github.com", Owner: "o", Repo: "r", Ref: "main"}},
+		{"https://github.com/o/r/blob/0a1b2c3d/docs/a.md", RepoURL{Web: "https://github.com", Owner: "o", Repo: "r", Ref: "0a1b2c3d", Path: "docs/a.md"}},
+		{"https://github.com/o/r/commit/0a1b2c3d", RepoURL{Web: "https://github.com", Owner: "o", Repo: "r", Ref: "0a1b2c3d"}},
+		{"https://raw.githubusercontent.com/o/r/refs/heads/feature/x/a.md", RepoURL{Web: "https://github.com", Owner: "o", Repo: "r", RefPath: "feature/x/a.md"}},
+		{"https://GHE.example.com/raw/o/r/v1/a.md", RepoURL{Web: "https://ghe.example.com", Owner: "o", Repo: "r", RefPath: "v1/a.md"}},
+		{"https://ghe.example.com/o/r/blob/v1/a.md?plain=1", RepoURL{Web: "https://ghe.example.com", Owner: "o", Repo: "r", RefPath: "v1/a.md"}},
+		{"git@github.com:o/r.git", RepoURL{Web: "https://github.com", Owner: "o", Repo: "r"}},
+		{"ssh://git@ghe.example.com:2222/o/r.git", RepoURL{Web: "https://ghe.example.com", Owner: "o", Repo: "r"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseURL(tt.in)
+		if err != nil {
+			t.Errorf("ParseURL(%q): %v", tt.in, err)
+			continue
+		}
+		if *got != tt.want {
+			t.Errorf("ParseURL(%q): got %+v, want %+v", tt.in, *got, tt.want)
+		}
+	}
+}
+
+func TestParseURL_Invalid(t *testing.T) {
+	for _, in := range []string{
+		"https://github.com/o",
+		"https://github.com/o/r/issues/1",
+		"ftp://github.com/o/r",
+		"https:///o/r",
+	} {
+		_, err := ParseURL(in)
+		ce, ok := err.(*clerrors.CLIError)
+		if !ok || ce.Cat != clerrors.CatBadArgs {
+			t.Errorf("ParseURL(%q): got %v, want bad-args error", in, err)
+		}
+	}
+}
+
+func TestParseRepo_URL(t *testing.T) {
+	owner, repo, err := ParseRepo("https://github.com/o/r/blob/main/a.md")
+	if err != nil || owner != "o" || repo != "r" {
+		t.Errorf("got %q %q %v", owner, repo, err)
+	}
+	if IsURL("o/r") || IsURL("github.com/o/r") || !IsURL("git@github.com:o/r") {
+		t.Error("IsURL")
+	}
+}
+
+func TestSplitRefPath(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/o/r/branches/feature/x" {
+			w.Write([]byte(`{"name":"feature/x"}`))
+			return
+		}
+		w.WriteHeader(404)
+		w.Write([]byte(`{"message":"Branch not found"}`))
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL, "t", 5*time.Second)
+
+	for refPath, want := range map[string][2]string{
+		"feature/x/docs/a.md": {"feature/x", "docs/a.md"},
+		"v1.0/docs/a.md":      {"v1.0", "docs/a.md"},
+		"main":                {"main", ""},
+	} {
+		ref, path, err := c.SplitRefPath("o", "r", refPath)
+		if err != nil || ref != want[0] || path != want[1] {
+			t.Errorf("%s: got %q %q %v, want %q %q", refPath, ref, path, err, want[0], want[1])
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Package jq evaluates the jq expressions given to --jq, with the full jq
+// language of github.com/itchyny/gojq, as the gh CLI does. Values are the
+// generic form produced by encoding/json (map[string]any, []any, float64,
+// string, bool and nil).
+package jq
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/itchyny/gojq"
+)
+
+// Query is a compiled jq expression.
+type Query struct {
+	src  string
+	code *gojq.Code
+}
+
+// Parse compiles a jq expression. Environment variables are available as
+// $ENV and env.
+func Parse(src string) (*Query, error) {
+	parsed, err := gojq.Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("jq: %w", err)
+	}
+	code, err := gojq.Compile(parsed, gojq.WithEnvironLoader(os.Environ))
+	if err != nil {
+		return nil, fmt.Errorf("jq: %w", err)
+	}
+	return &Query{src: src, code: code}, nil
+}
+
+// String returns the source of the query.
+func (q *Query) String() string {
+	return q.src
+}
+
+// Run evaluates the query against v and returns every output.
+func (q *Query) Run(v any) ([]any, error) {
+	var out []any
+	iter := q.code.Run(v)
+	for {
+		r, ok := iter.Next()
+		if !ok {
+			return out, nil
+		}
+		if err, isErr := r.(error); isErr {
+			return nil, fmt.Errorf("jq: %w", err)
+		}
+		out = append(out, r)
+	}
+}
@@ -0,0 +1,134 @@
+package jq
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const entriesJSON = `[
+  {"type": "file", "path": "docs/a.md", "sha": "aaa", "size": 10},
+  {"type": "dir", "path": "docs/sub", "sha": "bbb", "size": 0},
+  {"type": "file", "path": "docs/b.go", "sha": "ccc", "size": 30}
+]`
+
+func run(t *testing.T, expr, input string) string {
+	t.Helper()
+	q, err := Parse(expr)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", expr, err)
+	}
+	var v any
+	if err := json.Unmarshal([]byte(input), &v); err != nil {
+		t.Fatalf("bad input: %v", err)
+	}
+	out, err := q.Run(v)
+	if err != nil {
+		t.Fatalf("Run(%q): %v", expr, err)
+	}
+	parts := make([]string, len(out))
+	for i, o := range out {
+		data, _ := json.Marshal(o)
+		parts[i] = string(data)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{".", `[{"path":"docs/a.md","sha":"aaa","size":10,"type":"file"},{"path":"docs/sub","sha":"bbb","size":0,"type":"dir"},{"path":"docs/b.go","sha":"ccc","size":30,"type":"file"}]`},
+		{".[].path", `"docs/a.md" "docs/sub" "docs/b.go"`},
+		{".[0].sha", `"aaa"`},
+		{".[-1].size", `30`},
+		{".[1:].[0].type", `"dir"`},
+		{`.[] | select(.type == "file") | .path`, `"docs/a.md" "docs/b.go"`},
+		{`map(select(.size > 5)) | length`, `2`},
+		{`map(.size) | add`, `40`},
+		{`[.[] | {path, kb: (.size / 10)}] | .[0]`, `{"kb":1,"path":"docs/a.md"}`},
+		{`.[] | select(.path | endswith(".go")) | .sha`, `"ccc"`},
+		{`map(.path | test("\\.md$")) | any`, `true`},
+		{`sort_by(.size) | reverse | .[0].path`, `"docs/b.go"`},
+		{`group_by(.type) | map(length)`, `[1,2]`},
+		{`[.[].type] | unique | join(",")`, `"dir,file"`},
+		{`.[0] | keys`, `["path","sha","size","type"]`},
+		{`.[0] | to_entries | map(.key) | .[0]`, `"path"`},
+		{`.[0] | with_entries(select(.key == "sha"))`, `{"sha":"aaa"}`},
+		{`.[0] | has("sha"), has("nope")`, `true false`},
+		{`.[0].missing // "default"`, `"default"`},
+		{`.[] | if .type == "dir" then "D" elif .size > 20 then "big" else "small" end`, `"small" "D" "big"`},
+		{`.[0] | .size > 5 and .type == "file"`, `true`},
+		{`.[1] | .size > 5 or not`, `false`},
+		{`[.[] | .path | split("/") | .[1]] | @csv`, `"\"a.md\",\"sub\",\"b.go\""`},
+		{`.[0] | [.path, .size] | @tsv`, `"docs/a.md\t10"`},
+		{`[.[].size] | max, min`, `30 0`},
+		{`[limit(2; .[].sha)]`, `["aaa","bbb"]`},
+		{`first(.[].sha), [range(3)]`, `"aaa" [0,1,2]`},
+		{`.[0].path | ascii_upcase | ltrimstr("DOCS/")`, `"A.MD"`},
+		{`.[0] | tojson | fromjson | .sha`, `"aaa"`},
+		{`-(.[2].size) + 1`, `-29`},
+		{`[..] | length`, `16`},
+		{`.[] | .path | .[0:4]`, `"docs" "docs" "docs"`},
+		{`.["0"]?`, ``},
+		{`{"a": 1} + {"b": 2}`, `{"a":1,"b":2}`},
+		{`[1, 2, 3] - [2]`, `[1,3]`},
+		{`"a,b" / ","`, `["a","b"]`},
+		{`{(.[0].sha): .[0].size}`, `{"aaa":10}`},
+		{`.[0] | contains({type: "fi"})`, `true`},
+		{`[.[] | .size] | map(. * 2) | .[2]`, `60`},
+		{`.[] | select(.size >= 10) | .size % 7`, `3 2`},
+		{`.[] | "\(.path) (\(.size) bytes)"`, `"docs/a.md (10 bytes)" "docs/sub (0 bytes)" "docs/b.go (30 bytes)"`},
+		{`.[0].size as $s | [.[] | select(.size > $s) | .sha]`, `["ccc"]`},
+		{`reduce .[] as $e (0; . + $e.size)`, `40`},
+		{`try error("boom") catch .`, `"boom"`},
+		{`.[0].size |= . + 1 | .[0].size`, `11`},
+		{`def kb: . / 10; [.[].size | kb]`, `[1,0,3]`},
+		{`[.[0] | paths] | length`, `4`},
+		{`env | type`, `"object"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			if got := run(t, tt.expr, entriesJSON); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, expr := range []string{
+		".[",
+		"map(",
+		"nosuchfn",
+		"select(.a; .b)",
+		`"unterminated`,
+		"$x",
+		"if . then 1",
+		". |",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q): expected error", expr)
+		}
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	for _, expr := range []string{
+		".[0].path.x",
+		".[] | .size + .path",
+		".[0] | keys | .x",
+		"length | not | length",
+	} {
+		q, err := Parse(expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", expr, err)
+		}
+		var v any
+		json.Unmarshal([]byte(entriesJSON), &v)
+		if _, err := q.Run(v); err == nil {
+			t.Errorf("Run(%q): expected error", expr)
+		}
+	}
+}
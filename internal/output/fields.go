@@ -0,0 +1,128 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	clerrors "githubRAGCli/internal/exitcode"
+	"githubRAGCli/internal/jq"
+)
+
+// project keeps only the given JSON keys of v, or of each element when v is
+// a slice. Keys keep the order they have in v. Requesting a key the data
+// type does not define is an error listing the available ones.
+func project(v any, fields []string) (any, error) {
+	if known := FieldNames(v); known != nil {
+		var unknown []string
+		for _, f := range fields {
+			if !contains(known, f) {
+				unknown = append(unknown, f)
+			}
+		}
+		if len(unknown) > 0 {
+			return nil, clerrors.NewBadArgs(fmt.Sprintf("unknown field(s) %s (available: %s)",
+				strings.Join(unknown, ", "), strings.Join(known, ", ")), nil)
+		}
+	}
+
+	doc, err := toOrdered(v)
+	if err != nil {
+		return nil, err
+	}
+	pick := func(item any) any {
+		obj, ok := item.(*object)
+		if !ok {
+			return item
+		}
+		out := &object{vals: make(map[string]any)}
+		for _, k := range obj.keys {
+			if contains(fields, k) {
+				out.keys = append(out.keys, k)
+				out.vals[k] = obj.vals[k]
+			}
+		}
+		return out
+	}
+	if items, ok := doc.([]any); ok {
+		for i := range items {
+			items[i] = pick(items[i])
+		}
+		return items, nil
+	}
+	return pick(doc), nil
+}
+
+// FieldNames returns the JSON keys defined by the struct type of v, or of
+// its elements when v is a slice, sorted. It returns nil for other types.
+func FieldNames(v any) []string {
+	t := reflect.TypeOf(v)
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runQuery evaluates q against the JSON form of v and writes each result on
+// its own line: strings raw, everything else as compact JSON.
+func runQuery(w io.Writer, q *jq.Query, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	results, err := q.Run(doc)
+	if err != nil {
+		return clerrors.NewBadArgs(err.Error(), nil)
+	}
+	for _, r := range results {
+		if s, ok := r.(string); ok {
+			if _, err := fmt.Fprintln(w, s); err != nil {
+				return err
+			}
+			continue
+		}
+		line, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s\n", line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	clerrors "githubRAGCli/internal/exitcode"
+	"githubRAGCli/internal/jq"
+)
+
+func TestRender_Fields(t *testing.T) {
+	var buf bytes.Buffer
+	f := Format{Name: "ndjson", Fields: []string{"sha", "path"}}
+	if err := Render(&buf, f, sampleEntries); err != nil {
+		t.Fatal(err)
+	}
+	// Keys keep their declaration order, not the order requested.
+	want := `{"path":"a.md","sha":"aaa"}` + "\n" + `{"path":"sub","sha":"bbb"}` + "\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestRender_FieldsUnknown(t *testing.T) {
+	var buf bytes.Buffer
+	err := Render(&buf, Format{Name: "json", Fields: []string{"path", "nope"}}, sampleEntries)
+	var ce *clerrors.CLIError
+	if !errors.As(err, &ce) || ce.Cat != clerrors.CatBadArgs {
+		t.Fatalf("expected bad-args error, got %v", err)
+	}
+	if !strings.Contains(ce.Message, "nope") || !strings.Contains(ce.Message, "download_url") {
+		t.Errorf("message should name the unknown and available fields: %q", ce.Message)
+	}
+}
+
+func TestRender_Query(t *testing.T) {
+	q, err := jq.Parse(`.[] | select(.type == "file") | .path, {size}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Render(&buf, Format{Name: "json", Query: q}, sampleEntries); err != nil {
+		t.Fatal(err)
+	}
+	if want := "a.md\n{\"size\":10}\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestPrintError_QueryFallback(t *testing.T) {
+	q, _ := jq.Parse(".error")
+	var buf bytes.Buffer
+	PrintError(&buf, ErrorData{Error: "boom", ExitCode: 1}, Format{Name: "json", Query: q, Fields: []string{"path"}})
+	if want := "boom\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	q, _ = jq.Parse(".[0]")
+	buf.Reset()
+	PrintError(&buf, ErrorData{Error: "boom", ExitCode: 1}, Format{Name: "json", Query: q})
+	if !strings.Contains(buf.String(), `"error": "boom"`) {
+		t.Errorf("failed query should fall back to the plain error, got %q", buf.String())
+	}
+}
+
+func TestPrintError_Fields(t *testing.T) {
+	var buf bytes.Buffer
+	e := ErrorData{Error: "boom", Category: "not_found", ExitCode: 12, HTTPStatus: 404, RequestID: "R1"}
+	PrintError(&buf, e, Format{Name: "ndjson", Fields: []string{"path", "category"}})
+	if want := `{"error":"boom","category":"not_found","exit_code":12}` + "\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestFieldNames(t *testing.T) {
+	got := FieldNames([]MutationResultData{})
+	if want := "action,branch,path,sha"; strings.Join(got, ",") != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+	if FieldNames(map[string]string{}) != nil {
+		t.Error("maps have no fixed field names")
+	}
+}
@@ -0,0 +1,381 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+
+	"githubRAGCli/internal/jq"
+)
+
+// Format selects how printers render their data. The zero value is text.
+type Format struct {
+	Name     string             // registry key, e.g. "json" or "table"
+	Template *template.Template // set for the "template" format
+	Fields   []string           // if set, keep only these JSON keys (--fields)
+	Query    *jq.Query          // if set, filter the JSON form through it (--jq)
+}
+
+// Predefined formats for callers that do not parse user input.
+var (
+	FormatText = Format{Name: "text"}
+	FormatJSON = Format{Name: "json"}
+)
+
+// IsText reports whether f is the command-specific text format.
+func (f Format) IsText() bool {
+	return f.Name == "" || f.Name == "text"
+}
+
+// Encoder renders any JSON-serializable value in a structure-driven format.
+type Encoder func(w io.Writer, v any, f Format) error
+
+// encoders maps format names to their encoder. "text" is not registered:
+// each printer supplies its own text rendering.
+var encoders = map[string]Encoder{
+	"json":     encodeJSON,
+	"ndjson":   encodeNDJSON,
+	"yaml":     encodeYAML,
+	"table":    encodeTable,
+	"csv":      encodeCSV,
+	"template": encodeTemplate,
+}
+
+// Register adds or replaces the encoder for a format name.
+func Register(name string, e Encoder) {
+	encoders[name] = e
+}
+
+// FormatNames returns the accepted --format names, "text" first.
+func FormatNames() []string {
+	names := make([]string, 0, len(encoders)+1)
+	for name := range encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append([]string{"text"}, names...)
+}
+
+// ParseFormat parses a --format value. Besides the registered names it
+// accepts "template=<tmpl>", or a bare Go template such as
+// "{{.path}} {{.size}}", whose fields are the JSON keys of each item.
+func ParseFormat(s string) (Format, error) {
+	tmpl, isTemplate := strings.CutPrefix(s, "template=")
+	if !isTemplate && strings.Contains(s, "{{") {
+		tmpl, isTemplate = s, true
+	}
+	if isTemplate {
+		t, err := template.New("format").Funcs(templateFuncs).Parse(tmpl)
+		if err != nil {
+			return Format{}, fmt.Errorf("invalid --format template: %w", err)
+		}
+		return Format{Name: "template", Template: t}, nil
+	}
+
+	if s == "" || s == "text" {
+		return FormatText, nil
+	}
+	if s == "template" {
+		return Format{}, fmt.Errorf("--format template needs a template, e.g. --format 'template={{.path}}'")
+	}
+	if _, ok := encoders[s]; !ok {
+		return Format{}, fmt.Errorf("unknown --format %q (want one of %s, or a Go template)", s, strings.Join(FormatNames(), ", "))
+	}
+	return Format{Name: s}, nil
+}
+
+// Render writes v in the structured format f. Printers call it for every
+// format except text. Field selection applies first, then the jq query,
+// whose results replace the encoder.
+func Render(w io.Writer, f Format, v any) error {
+	if len(f.Fields) > 0 {
+		projected, err := project(v, f.Fields)
+		if err != nil {
+			return err
+		}
+		v = projected
+	}
+	if f.Query != nil {
+		return runQuery(w, f.Query, v)
+	}
+	enc, ok := encoders[f.Name]
+	if !ok {
+		return fmt.Errorf("unknown output format %q", f.Name)
+	}
+	return enc(w, v, f)
+}
+
+func encodeJSON(w io.Writer, v any, _ Format) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// encodeNDJSON writes one compact JSON document per line: one per element
+// for slices, a single line otherwise. Each element is encoded and written
+// on its own, so nothing is buffered beyond a line.
+func encodeNDJSON(w io.Writer, v any, _ Format) error {
+	enc := json.NewEncoder(w)
+	rv := reflect.ValueOf(v)
+	if _, ok := v.(json.Marshaler); ok || (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) {
+		return enc.Encode(v)
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if err := enc.Encode(rv.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeYAML(w io.Writer, v any, _ Format) error {
+	doc, err := toOrdered(v)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	writeYAML(&buf, doc, 0)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// encodeTable writes aligned columns named after the JSON keys, one row per
+// item. Nested values are shown as compact JSON.
+func encodeTable(w io.Writer, v any, _ Format) error {
+	header, rows, err := tabulate(v)
+	if err != nil {
+		return err
+	}
+	widths := make([]int, len(header))
+	for i, h := range header {
+		header[i] = strings.ToUpper(h)
+		widths[i] = len(header[i])
+	}
+	for _, r := range rows {
+		for i, c := range r {
+			widths[i] = max(widths[i], len(c))
+		}
+	}
+	for _, r := range append([][]string{header}, rows...) {
+		var line strings.Builder
+		for i, c := range r {
+			if i == len(r)-1 {
+				line.WriteString(c)
+				break
+			}
+			fmt.Fprintf(&line, "%-*s  ", widths[i], c)
+		}
+		if _, err := fmt.Fprintln(w, strings.TrimRight(line.String(), " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeCSV(w io.Writer, v any, _ Format) error {
+	header, rows, err := tabulate(v)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Write(header)
+	cw.WriteAll(rows)
+	return cw.Error()
+}
+
+// encodeTemplate executes f.Template once per item (or once for a single
+// object), each followed by a newline. Items are the generic JSON form of v,
+// so fields are addressed by their JSON keys.
+func encodeTemplate(w io.Writer, v any, f Format) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return err
+	}
+	items, ok := doc.([]any)
+	if !ok {
+		items = []any{doc}
+	}
+	for _, item := range items {
+		if err := f.Template.Execute(w, item); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// templateFuncs are available to --format templates.
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"join": func(sep string, v []any) string {
+		parts := make([]string, len(v))
+		for i, p := range v {
+			parts[i] = fmt.Sprint(p)
+		}
+		return strings.Join(parts, sep)
+	},
+}
+
+// tabulate flattens v into a header and rows for table and CSV output.
+// Columns are the union of the items' keys in first-seen order.
+func tabulate(v any) ([]string, [][]string, error) {
+	doc, err := toOrdered(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	items, ok := doc.([]any)
+	if !ok {
+		items = []any{doc}
+	}
+
+	var header []string
+	seen := make(map[string]bool)
+	for _, item := range items {
+		obj, ok := item.(*object)
+		if !ok {
+			continue
+		}
+		for _, k := range obj.keys {
+			if !seen[k] {
+				seen[k] = true
+				header = append(header, k)
+			}
+		}
+	}
+
+	rows := make([][]string, 0, len(items))
+	if header == nil {
+		// A list of scalars becomes a single "value" column.
+		header = []string{"value"}
+		for _, item := range items {
+			rows = append(rows, []string{cellString(item)})
+		}
+		return header, rows, nil
+	}
+	for _, item := range items {
+		obj, _ := item.(*object)
+		row := make([]string, len(header))
+		for i, k := range header {
+			if obj != nil {
+				row[i] = cellString(obj.vals[k])
+			}
+		}
+		rows = append(rows, row)
+	}
+	return header, rows, nil
+}
+
+func cellString(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case json.Number:
+		return t.String()
+	case bool:
+		return fmt.Sprint(t)
+	default:
+		data, _ := json.Marshal(t)
+		return string(data)
+	}
+}
+
+// object is a JSON object that remembers its key order, so structured
+// formats list fields in the order the Go struct declares them.
+type object struct {
+	keys []string
+	vals map[string]any
+}
+
+// MarshalJSON writes the object with its keys in their original order.
+func (o *object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		buf.Write(key)
+		buf.WriteByte(':')
+		val, err := json.Marshal(o.vals[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// toOrdered converts v to its generic JSON form: *object, []any, string,
+// json.Number, bool or nil.
+func toOrdered(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return decodeOrdered(dec)
+}
+
+func decodeOrdered(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+	switch delim {
+	case '{':
+		obj := &object{vals: make(map[string]any)}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := keyTok.(string)
+			val, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.keys = append(obj.keys, key)
+			obj.vals[key] = val
+		}
+		_, err := dec.Token() // closing brace
+		return obj, err
+	case '[':
+		arr := []any{}
+		for dec.More() {
+			val, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, val)
+		}
+		_, err := dec.Token() // closing bracket
+		return arr, err
+	}
+	return nil, fmt.Errorf("unexpected JSON delimiter %q", delim)
+}
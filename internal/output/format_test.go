@@ -0,0 +1,126 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var sampleEntries = []EntryData{
+	{Type: "file", Path: "a.md", SHA: "aaa", Size: 10},
+	{Type: "dir", Path: "sub", SHA: "bbb"},
+}
+
+func render(t *testing.T, format string, v any) string {
+	t.Helper()
+	f, err := ParseFormat(format)
+	if err != nil {
+		t.Fatalf("ParseFormat(%q): %v", format, err)
+	}
+	var buf bytes.Buffer
+	if err := Render(&buf, f, v); err != nil {
+		t.Fatalf("Render(%q): %v", format, err)
+	}
+	return buf.String()
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, s := range []string{"", "text", "json", "ndjson", "yaml", "table", "csv", "{{.path}}", "template={{.path}}"} {
+		if _, err := ParseFormat(s); err != nil {
+			t.Errorf("ParseFormat(%q): unexpected error %v", s, err)
+		}
+	}
+	for _, s := range []string{"xml", "template", "{{.path"} {
+		if _, err := ParseFormat(s); err == nil {
+			t.Errorf("ParseFormat(%q): expected error", s)
+		}
+	}
+	if f, _ := ParseFormat(""); !f.IsText() {
+		t.Error("empty format should be text")
+	}
+}
+
+func TestRender_NDJSON(t *testing.T) {
+	got := render(t, "ndjson", sampleEntries)
+	want := `{"type":"file","path":"a.md","sha":"aaa","size":10}` + "\n" +
+		`{"type":"dir","path":"sub","sha":"bbb","size":0}` + "\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	if got := render(t, "ndjson", AuthResult{Status: "ok"}); strings.Count(got, "\n") != 1 {
+		t.Errorf("single object should be one line, got %q", got)
+	}
+}
+
+func TestRender_Table(t *testing.T) {
+	got := render(t, "table", sampleEntries)
+	want := "" +
+		"TYPE  PATH  SHA  SIZE\n" +
+		"file  a.md  aaa  10\n" +
+		"dir   sub   bbb  0\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRender_CSV(t *testing.T) {
+	got := render(t, "csv", []MutationResultData{{Action: "created", Path: "a, b.md", SHA: "c1"}})
+	want := "action,path,sha\ncreated,\"a, b.md\",c1\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRender_Template(t *testing.T) {
+	got := render(t, "{{.path}} {{.size}}", sampleEntries)
+	if want := "a.md 10\nsub 0\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got = render(t, "template={{.user}}", AuthResult{User: "octocat"})
+	if want := "octocat\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRender_TableNested(t *testing.T) {
+	r := VerifyResultData{Status: "drift", Drift: []DriftData{{Path: "a", Status: "missing"}}}
+	got := render(t, "table", r)
+	if !strings.Contains(got, `[{"path":"a","status":"missing"}]`) {
+		t.Errorf("nested value should render as compact JSON, got:\n%s", got)
+	}
+}
+
+func TestPrintEntries_FormatYAML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := PrintEntries(&buf, sampleEntries, Format{Name: "yaml"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "" +
+		"- type: file\n" +
+		"  path: a.md\n" +
+		"  sha: aaa\n" +
+		"  size: 10\n" +
+		"- type: dir\n" +
+		"  path: sub\n" +
+		"  sha: bbb\n" +
+		"  size: 0\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestPrintError_Formats(t *testing.T) {
+	var buf bytes.Buffer
+	PrintError(&buf, ErrorData{Error: "boom", ExitCode: 1}, Format{Name: "yaml"})
+	if want := "error: boom\nexit_code: 1\n"; buf.String() != want {
+		t.Errorf("yaml: got %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	PrintError(&buf, ErrorData{Error: "boom", ExitCode: 1}, Format{Name: "table"})
+	if want := "error: boom\n"; buf.String() != want {
+		t.Errorf("table: got %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,154 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Sort keys accepted by SortEntries.
+var SortKeys = []string{"name", "size", "type"}
+
+// LongOptions controls the ls -l long listing format.
+type LongOptions struct {
+	Human bool // print sizes as 1.5K, 12M, ...
+}
+
+// SortEntries orders entries in place by key ("name", "size" or "type").
+// Ties fall back to the path so the order is deterministic.
+func SortEntries(entries []EntryData, key string, reverse bool) error {
+	var less func(a, b EntryData) bool
+	switch key {
+	case "name":
+		less = func(a, b EntryData) bool { return a.Path < b.Path }
+	case "size":
+		less = func(a, b EntryData) bool {
+			if sa, sb := entrySize(a), entrySize(b); sa != sb {
+				return sa < sb
+			}
+			return a.Path < b.Path
+		}
+	case "type":
+		less = func(a, b EntryData) bool {
+			if a.Type != b.Type {
+				return a.Type < b.Type
+			}
+			return a.Path < b.Path
+		}
+	default:
+		return fmt.Errorf("unknown sort key %q (want one of %s)", key, strings.Join(SortKeys, ", "))
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if reverse {
+			return less(entries[j], entries[i])
+		}
+		return less(entries[i], entries[j])
+	})
+	return nil
+}
+
+// PrintEntriesLong writes entries as aligned mode, type, size, short SHA and
+// name columns. Directories show their recursive size when it is known and
+// "-" otherwise.
+func PrintEntriesLong(w io.Writer, entries []EntryData, opts LongOptions) error {
+	rows := make([][5]string, 0, len(entries))
+	var widths [4]int
+	for _, e := range entries {
+		row := [5]string{
+			entryMode(e),
+			e.Type,
+			formatEntrySize(e, opts.Human),
+			shortSHA(e.SHA),
+			entryName(e),
+		}
+		for i := range widths {
+			widths[i] = max(widths[i], len(row[i]))
+		}
+		rows = append(rows, row)
+	}
+	for _, r := range rows {
+		// The size column is right-aligned like ls; the others pad on the right.
+		if _, err := fmt.Fprintf(w, "%-*s  %-*s  %*s  %-*s  %s\n",
+			widths[0], r[0], widths[1], r[1], widths[2], r[2], widths[3], r[3], r[4]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HumanSize formats n bytes with a binary unit suffix the way ls -h does:
+// one decimal below 10 units, none above.
+func HumanSize(n int64) string {
+	if n < 1024 {
+		return strconv.FormatInt(n, 10)
+	}
+	v := float64(n)
+	units := "KMGTPE"
+	i := -1
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	if v < 10 {
+		return fmt.Sprintf("%.1f%c", v, units[i])
+	}
+	return fmt.Sprintf("%.0f%c", v, units[i])
+}
+
+// entryMode returns the git mode of e, falling back to the mode implied by
+// its type when the listing did not report one.
+func entryMode(e EntryData) string {
+	if e.Mode != "" {
+		return e.Mode
+	}
+	switch e.Type {
+	case "dir":
+		return "040000"
+	case "symlink":
+		return "120000"
+	case "submodule":
+		return "160000"
+	default:
+		return "100644"
+	}
+}
+
+// entrySize is the size used for sorting: the recursive size for
+// directories, the blob size otherwise.
+func entrySize(e EntryData) int64 {
+	if e.Type == "dir" {
+		return e.TotalSize
+	}
+	return e.Size
+}
+
+func formatEntrySize(e EntryData, human bool) string {
+	if (e.Type == "dir" && e.TotalSize == 0 && e.FileCount == 0) || e.Type == "submodule" {
+		return "-"
+	}
+	n := entrySize(e)
+	if human {
+		return HumanSize(n)
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+func entryName(e EntryData) string {
+	switch {
+	case e.Type == "symlink" && e.Target != "":
+		return e.Path + " -> " + e.Target
+	case e.Type == "dir":
+		return e.Path + "/"
+	default:
+		return e.Path
+	}
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,98 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrintEntriesLong(t *testing.T) {
+	var buf bytes.Buffer
+	entries := []EntryData{
+		{Type: "file", Path: "run.sh", SHA: "0123456789abcdef", Size: 2048, Mode: "100755"},
+		{Type: "dir", Path: "docs", SHA: "fedcba9876543210", TotalSize: 1536, FileCount: 2},
+		{Type: "symlink", Path: "link", SHA: "aaaaaaaaaa", Size: 6, Target: "run.sh"},
+		{Type: "submodule", Path: "lib", SHA: "c0ffee0000"},
+	}
+	if err := PrintEntriesLong(&buf, entries, LongOptions{Human: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := "" +
+		"100755  file       2.0K  0123456  run.sh\n" +
+		"040000  dir        1.5K  fedcba9  docs/\n" +
+		"120000  symlink       6  aaaaaaa  link -> run.sh\n" +
+		"160000  submodule     -  c0ffee0  lib\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestPrintEntriesLong_DirWithoutSize(t *testing.T) {
+	var buf bytes.Buffer
+	if err := PrintEntriesLong(&buf, []EntryData{{Type: "dir", Path: "sub", SHA: "abc"}}, LongOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "040000  dir  -  abc  sub/\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestHumanSize(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0"},
+		{1023, "1023"},
+		{1024, "1.0K"},
+		{1536, "1.5K"},
+		{10 * 1024, "10K"},
+		{5 * 1024 * 1024, "5.0M"},
+		{3 << 30, "3.0G"},
+	}
+	for _, tt := range tests {
+		if got := HumanSize(tt.n); got != tt.want {
+			t.Errorf("HumanSize(%d): got %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestSortEntries(t *testing.T) {
+	entries := func() []EntryData {
+		return []EntryData{
+			{Type: "file", Path: "b", Size: 1},
+			{Type: "dir", Path: "c", TotalSize: 50},
+			{Type: "file", Path: "a", Size: 10},
+		}
+	}
+	paths := func(es []EntryData) string {
+		var s string
+		for _, e := range es {
+			s += e.Path
+		}
+		return s
+	}
+
+	tests := []struct {
+		key     string
+		reverse bool
+		want    string
+	}{
+		{"name", false, "abc"},
+		{"name", true, "cba"},
+		{"size", false, "bac"},
+		{"type", false, "cab"},
+	}
+	for _, tt := range tests {
+		es := entries()
+		if err := SortEntries(es, tt.key, tt.reverse); err != nil {
+			t.Fatalf("SortEntries(%q): %v", tt.key, err)
+		}
+		if got := paths(es); got != tt.want {
+			t.Errorf("SortEntries(%q, reverse=%v): got %s, want %s", tt.key, tt.reverse, got, tt.want)
+		}
+	}
+
+	if err := SortEntries(entries(), "date", false); err == nil {
+		t.Error("expected error for unknown sort key")
+	}
+}
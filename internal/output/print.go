@@ -1,57 +1,169 @@
 package output
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
+	"time"
+
+	clerrors "githubRAGCli/internal/exitcode"
 )
 
 // AuthResult holds the data for an auth check response.
 type AuthResult struct {
-	Status             string `json:"status"`
-	User               string `json:"user"`
-	RateLimitRemaining int    `json:"rate_limit_remaining"`
+	Status             string          `json:"status"`
+	User               string          `json:"user"`
+	RateLimitRemaining int             `json:"rate_limit_remaining"`
+	TokenType          string          `json:"token_type,omitempty"`
+	Scopes             []string        `json:"scopes"`               // null when the token type has no scopes
+	ExpiresAt          string          `json:"expires_at,omitempty"` // RFC 3339
+	Repository         *RepoAccessData `json:"repository,omitempty"`
+	Hints              []string        `json:"hints,omitempty"`
+	Server             string          `json:"server,omitempty"` // GitHub Enterprise Server and its version
+}
+
+// RepoAccessData is the token's access to the repository given to auth check.
+type RepoAccessData struct {
+	Name          string `json:"name"`
+	Permission    string `json:"permission"` // admin, maintain, write, triage, read or none
+	DefaultBranch string `json:"default_branch,omitempty"`
+	Protected     bool   `json:"protected"`
 }
 
-// PrintAuth writes the auth check result to w in text or JSON format.
-func PrintAuth(w io.Writer, r AuthResult, asJSON bool) error {
-	if asJSON {
-		enc := json.NewEncoder(w)
-		enc.SetIndent("", "  ")
-		return enc.Encode(r)
+// PrintAuth writes the auth check result to w in the selected format.
+func PrintAuth(w io.Writer, r AuthResult, f Format) error {
+	if !f.IsText() {
+		return Render(w, f, r)
 	}
 	fmt.Fprintf(w, "auth: %s\n", r.Status)
 	fmt.Fprintf(w, "user: %s\n", r.User)
 	fmt.Fprintf(w, "rate_limit_remaining: %d\n", r.RateLimitRemaining)
+	if r.Server != "" {
+		fmt.Fprintf(w, "server: %s\n", r.Server)
+	}
+	if r.TokenType != "" {
+		fmt.Fprintf(w, "token_type: %s\n", r.TokenType)
+	}
+	if r.Scopes != nil {
+		fmt.Fprintf(w, "scopes: %s\n", strings.Join(r.Scopes, ", "))
+	}
+	if r.ExpiresAt != "" {
+		fmt.Fprintf(w, "expires_at: %s\n", r.ExpiresAt)
+	}
+	if repo := r.Repository; repo != nil {
+		fmt.Fprintf(w, "repository: %s\n", repo.Name)
+		fmt.Fprintf(w, "permission: %s\n", repo.Permission)
+		if repo.DefaultBranch != "" {
+			protected := ""
+			if repo.Protected {
+				protected = " (protected)"
+			}
+			fmt.Fprintf(w, "default_branch: %s%s\n", repo.DefaultBranch, protected)
+		}
+	}
+	for _, h := range r.Hints {
+		fmt.Fprintf(w, "hint: %s\n", h)
+	}
 	return nil
 }
 
-// PrintError writes an error message to w in text or JSON format.
-func PrintError(w io.Writer, msg string, asJSON bool) {
-	if asJSON {
-		enc := json.NewEncoder(w)
-		enc.SetIndent("", "  ")
-		_ = enc.Encode(map[string]string{"error": msg})
+// ErrorData is the structured form of an error. Field names are stable so
+// scripts can branch on category or exit_code instead of the message.
+type ErrorData struct {
+	Error            string `json:"error"`
+	Category         string `json:"category,omitempty"`
+	ExitCode         int    `json:"exit_code"`
+	HTTPStatus       int    `json:"http_status,omitempty"`
+	GitHubMessage    string `json:"github_message,omitempty"`
+	DocumentationURL string `json:"documentation_url,omitempty"`
+	RequestID        string `json:"request_id,omitempty"`
+	RateLimitReset   string `json:"rate_limit_reset,omitempty"` // RFC 3339
+	RetryAfter       int    `json:"retry_after,omitempty"`      // seconds
+}
+
+// NewErrorData builds ErrorData from err, filling the API details when err
+// is (or wraps) a CLIError. Other errors get exit code 1.
+func NewErrorData(err error) ErrorData {
+	d := ErrorData{Error: err.Error(), ExitCode: 1}
+	var ce *clerrors.CLIError
+	if !errors.As(err, &ce) {
+		return d
+	}
+	d.Category = ce.Cat.String()
+	d.ExitCode = ce.ExitCode()
+	d.HTTPStatus = ce.Status
+	d.GitHubMessage = ce.APIMessage
+	d.DocumentationURL = ce.DocumentationURL
+	d.RequestID = ce.RequestID
+	if !ce.RateLimitReset.IsZero() {
+		d.RateLimitReset = ce.RateLimitReset.UTC().Format(time.RFC3339)
+	}
+	d.RetryAfter = int(ce.RetryAfter / time.Second)
+	return d
+}
+
+// PrintError writes an error to w in the selected format.
+func PrintError(w io.Writer, e ErrorData, f Format) {
+	switch f.Name {
+	case "json", "ndjson", "yaml":
+		// Structured formats get a structured error; tabular and template
+		// formats fall back to text, which is what a reader of stderr expects.
+		// --fields keeps the error fields it names, always with error and
+		// exit_code so that the object still reads as an error; fields of
+		// the command's output are ignored. If --jq does not apply to the
+		// error, the object is printed without it.
+		if len(f.Fields) > 0 {
+			keep := []string{"error", "exit_code"}
+			for _, name := range f.Fields {
+				if contains(FieldNames(e), name) && !contains(keep, name) {
+					keep = append(keep, name)
+				}
+			}
+			f.Fields = keep
+		}
+		if err := Render(w, f, e); err != nil {
+			f.Query = nil
+			_ = Render(w, f, e)
+		}
 		return
 	}
-	fmt.Fprintf(w, "error: %s\n", msg)
+	fmt.Fprintf(w, "error: %s\n", e.Error)
 }
 
 // EntryData represents a content entry for output formatting.
 type EntryData struct {
-	Type        string `json:"type"`
-	Path        string `json:"path"`
+	Type         string `json:"type"`
+	Path         string `json:"path"`
+	SHA          string `json:"sha"`
+	Size         int64  `json:"size"`
+	Mode         string `json:"mode,omitempty"`
+	DownloadURL  string `json:"download_url,omitempty"`
+	LastModified string `json:"last_modified,omitempty"` // RFC 3339
+	Target       string `json:"target,omitempty"`        // symlink target
+	SubmoduleURL string `json:"submodule_url,omitempty"`
+
+	Children  int   `json:"children,omitempty"`
+	FileCount int   `json:"file_count,omitempty"`
+	TotalSize int64 `json:"total_size,omitempty"`
+
+	LastCommit *CommitData `json:"last_commit,omitempty"`
+	Permalink  string      `json:"permalink,omitempty"`
+}
+
+// CommitData describes the last commit that touched an entry.
+type CommitData struct {
 	SHA         string `json:"sha"`
-	Size        int64  `json:"size"`
-	DownloadURL string `json:"download_url,omitempty"`
+	AuthorName  string `json:"author_name"`
+	AuthorEmail string `json:"author_email,omitempty"`
+	Date        string `json:"date"` // RFC 3339
+	Message     string `json:"message"`
 }
 
-// PrintEntry writes a single entry to w in text or JSON format.
-func PrintEntry(w io.Writer, e EntryData, asJSON bool) error {
-	if asJSON {
-		enc := json.NewEncoder(w)
-		enc.SetIndent("", "  ")
-		return enc.Encode(e)
+// PrintEntry writes a single entry to w in the selected format.
+func PrintEntry(w io.Writer, e EntryData, f Format) error {
+	if !f.IsText() {
+		return Render(w, f, e)
 	}
 	fmt.Fprintf(w, "type: %s\n", e.Type)
 	fmt.Fprintf(w, "path: %s\n", e.Path)
@@ -60,18 +172,51 @@ func PrintEntry(w io.Writer, e EntryData, asJSON bool) error {
 	if e.DownloadURL != "" {
 		fmt.Fprintf(w, "download_url: %s\n", e.DownloadURL)
 	}
+	if e.LastModified != "" {
+		fmt.Fprintf(w, "last_modified: %s\n", e.LastModified)
+	}
+	if e.Target != "" {
+		fmt.Fprintf(w, "target: %s\n", e.Target)
+	}
+	if e.SubmoduleURL != "" {
+		fmt.Fprintf(w, "submodule_url: %s\n", e.SubmoduleURL)
+	}
+	if e.Type == "dir" {
+		fmt.Fprintf(w, "children: %d\n", e.Children)
+		fmt.Fprintf(w, "file_count: %d\n", e.FileCount)
+		fmt.Fprintf(w, "total_size: %d\n", e.TotalSize)
+	}
+	if c := e.LastCommit; c != nil {
+		fmt.Fprintf(w, "last_commit: %s\n", c.SHA)
+		if c.AuthorEmail != "" {
+			fmt.Fprintf(w, "last_commit_author: %s <%s>\n", c.AuthorName, c.AuthorEmail)
+		} else {
+			fmt.Fprintf(w, "last_commit_author: %s\n", c.AuthorName)
+		}
+		fmt.Fprintf(w, "last_commit_date: %s\n", c.Date)
+		subject, _, _ := strings.Cut(c.Message, "\n")
+		fmt.Fprintf(w, "last_commit_message: %s\n", subject)
+	}
+	if e.Permalink != "" {
+		fmt.Fprintf(w, "permalink: %s\n", e.Permalink)
+	}
 	return nil
 }
 
-// PrintEntries writes a list of entries to w in text or JSON format.
-func PrintEntries(w io.Writer, entries []EntryData, asJSON bool) error {
-	if asJSON {
-		enc := json.NewEncoder(w)
-		enc.SetIndent("", "  ")
-		return enc.Encode(entries)
+// PrintEntries writes a list of entries to w in the selected format.
+func PrintEntries(w io.Writer, entries []EntryData, f Format) error {
+	if !f.IsText() {
+		return Render(w, f, entries)
 	}
 	for _, e := range entries {
-		fmt.Fprintf(w, "%s\t%s\n", e.Type, e.Path)
+		switch {
+		case e.Type == "symlink" && e.Target != "":
+			fmt.Fprintf(w, "%s\t%s -> %s\n", e.Type, e.Path, e.Target)
+		case e.Type == "submodule":
+			fmt.Fprintf(w, "%s\t%s @ %s\n", e.Type, e.Path, e.SHA)
+		default:
+			fmt.Fprintf(w, "%s\t%s\n", e.Type, e.Path)
+		}
 	}
 	return nil
 }
@@ -84,12 +229,10 @@ type MutationResultData struct {
 	Branch string `json:"branch,omitempty"`
 }
 
-// PrintMutationResult writes a mutation result to w in text or JSON format.
-func PrintMutationResult(w io.Writer, r MutationResultData, asJSON bool) error {
-	if asJSON {
-		enc := json.NewEncoder(w)
-		enc.SetIndent("", "  ")
-		return enc.Encode(r)
+// PrintMutationResult writes a mutation result to w in the selected format.
+func PrintMutationResult(w io.Writer, r MutationResultData, f Format) error {
+	if !f.IsText() {
+		return Render(w, f, r)
 	}
 	fmt.Fprintf(w, "action: %s\n", r.Action)
 	fmt.Fprintf(w, "path: %s\n", r.Path)
@@ -99,3 +242,154 @@ func PrintMutationResult(w io.Writer, r MutationResultData, asJSON bool) error {
 	}
 	return nil
 }
+
+// DownloadedData describes a file or symlink written by get.
+type DownloadedData struct {
+	Type         string `json:"type"` // "file" or "symlink"
+	RemotePath   string `json:"remote_path"`
+	LocalPath    string `json:"local_path"`
+	SHA          string `json:"sha"`
+	Size         int64  `json:"size"`
+	Mode         string `json:"mode"`
+	Target       string `json:"target,omitempty"`
+	LastModified string `json:"last_modified,omitempty"` // RFC 3339; not known while streaming
+}
+
+// PrintDownloaded writes the files a download wrote to w in the selected
+// format; the text form is one local path per line.
+func PrintDownloaded(w io.Writer, files []DownloadedData, f Format) error {
+	if !f.IsText() {
+		return Render(w, f, files)
+	}
+	for _, d := range files {
+		fmt.Fprintln(w, d.LocalPath)
+	}
+	return nil
+}
+
+// DriftData describes a local file that differs from its manifest entry.
+type DriftData struct {
+	Path     string `json:"path"`
+	Status   string `json:"status"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+// VerifyResultData represents the result of checking files against a manifest.
+type VerifyResultData struct {
+	Status     string      `json:"status"` // "ok" or "drift"
+	Repository string      `json:"repository"`
+	Commit     string      `json:"commit"`
+	Checked    int         `json:"checked"`
+	Drift      []DriftData `json:"drift"`
+}
+
+// PrintVerifyResult writes a manifest verification result to w in the selected format.
+func PrintVerifyResult(w io.Writer, r VerifyResultData, f Format) error {
+	if !f.IsText() {
+		if r.Drift == nil {
+			r.Drift = []DriftData{}
+		}
+		return Render(w, f, r)
+	}
+	for _, d := range r.Drift {
+		fmt.Fprintf(w, "%s\t%s\n", d.Status, d.Path)
+	}
+	fmt.Fprintf(w, "status: %s\n", r.Status)
+	fmt.Fprintf(w, "repository: %s\n", r.Repository)
+	fmt.Fprintf(w, "commit: %s\n", r.Commit)
+	fmt.Fprintf(w, "checked: %d\n", r.Checked)
+	return nil
+}
+
+// SettingData is one configuration key and its value.
+type SettingData struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// PrintSettings writes configuration settings to w, as key=value lines in text.
+func PrintSettings(w io.Writer, settings []SettingData, f Format) error {
+	if !f.IsText() {
+		if settings == nil {
+			settings = []SettingData{}
+		}
+		return Render(w, f, settings)
+	}
+	for _, s := range settings {
+		fmt.Fprintf(w, "%s=%s\n", s.Key, s.Value)
+	}
+	return nil
+}
+
+// CredentialData describes a login stored by auth login. The token itself
+// is never part of it.
+type CredentialData struct {
+	Host      string `json:"host"`
+	User      string `json:"user"`
+	Storage   string `json:"storage"` // "keyring" or "file"
+	Scopes    string `json:"scopes"`
+	CreatedAt string `json:"created_at"` // RFC 3339
+}
+
+// PrintLogin writes the result of auth login to w.
+func PrintLogin(w io.Writer, c CredentialData, f Format) error {
+	if !f.IsText() {
+		return Render(w, f, c)
+	}
+	fmt.Fprintf(w, "logged in to %s as %s (token stored in %s)\n", c.Host, c.User, c.Storage)
+	return nil
+}
+
+// AuthStatusData is the result of auth status.
+type AuthStatusData struct {
+	Host        string           `json:"host"`
+	TokenSource string           `json:"token_source"` // empty when no source has a token
+	Logins      []CredentialData `json:"logins"`
+}
+
+// PrintAuthStatus writes the auth status to w.
+func PrintAuthStatus(w io.Writer, s AuthStatusData, f Format) error {
+	if !f.IsText() {
+		if s.Logins == nil {
+			s.Logins = []CredentialData{}
+		}
+		return Render(w, f, s)
+	}
+	source := s.TokenSource
+	if source == "" {
+		source = "none"
+	}
+	fmt.Fprintf(w, "host: %s\n", s.Host)
+	fmt.Fprintf(w, "token_source: %s\n", source)
+	if len(s.Logins) == 0 {
+		fmt.Fprintln(w, "logins: none")
+		return nil
+	}
+	fmt.Fprintln(w, "logins:")
+	for _, c := range s.Logins {
+		scopes := c.Scopes
+		if scopes == "" {
+			scopes = "none"
+		}
+		fmt.Fprintf(w, "  %s: %s (%s, scopes: %s, since %s)\n", c.Host, c.User, c.Storage, scopes, c.CreatedAt)
+	}
+	return nil
+}
+
+// RateLimitData is the quota of one rate-limit resource.
+type RateLimitData struct {
+	Resource  string `json:"resource"`
+	Limit     int    `json:"limit"`
+	Used      int    `json:"used"`
+	Remaining int    `json:"remaining"`
+	Reset     string `json:"reset"` // RFC 3339
+}
+
+// PrintRateLimits writes the rate-limit quotas to w; text output is a table.
+func PrintRateLimits(w io.Writer, limits []RateLimitData, f Format) error {
+	if !f.IsText() {
+		return Render(w, f, limits)
+	}
+	return encodeTable(w, limits, f)
+}
@@ -3,13 +3,19 @@ package output
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
 	"testing"
+
+	clerrors "githubRAGCli/internal/exitcode"
 )
 
 func TestPrintAuth_Text(t *testing.T) {
 	var buf bytes.Buffer
 	r := AuthResult{Status: "ok", User: "octocat", RateLimitRemaining: 4999}
-	if err := PrintAuth(&buf, r, false); err != nil {
+	if err := PrintAuth(&buf, r, FormatText); err != nil {
 		t.Fatal(err)
 	}
 	want := "auth: ok\nuser: octocat\nrate_limit_remaining: 4999\n"
@@ -21,7 +27,7 @@ func TestPrintAuth_Text(t *testing.T) {
 func TestPrintAuth_JSON(t *testing.T) {
 	var buf bytes.Buffer
 	r := AuthResult{Status: "ok", User: "octocat", RateLimitRemaining: 4999}
-	if err := PrintAuth(&buf, r, true); err != nil {
+	if err := PrintAuth(&buf, r, FormatJSON); err != nil {
 		t.Fatal(err)
 	}
 	var decoded AuthResult
@@ -33,9 +39,29 @@ func TestPrintAuth_JSON(t *testing.T) {
 	}
 }
 
+func TestPrintAuth_TextAccess(t *testing.T) {
+	var buf bytes.Buffer
+	r := AuthResult{
+		Status: "ok", User: "octocat", RateLimitRemaining: 4999,
+		TokenType: "classic", Scopes: []string{"repo", "gist"}, ExpiresAt: "2026-11-01T12:00:00Z",
+		Repository: &RepoAccessData{Name: "o/r", Permission: "read", DefaultBranch: "main", Protected: true},
+		Hints:      []string{"ask for write access"},
+	}
+	if err := PrintAuth(&buf, r, FormatText); err != nil {
+		t.Fatal(err)
+	}
+	want := "auth: ok\nuser: octocat\nrate_limit_remaining: 4999\n" +
+		"token_type: classic\nscopes: repo, gist\nexpires_at: 2026-11-01T12:00:00Z\n" +
+		"repository: o/r\npermission: read\ndefault_branch: main (protected)\n" +
+		"hint: ask for write access\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
 func TestPrintError_Text(t *testing.T) {
 	var buf bytes.Buffer
-	PrintError(&buf, "something broke", false)
+	PrintError(&buf, ErrorData{Error: "something broke", ExitCode: 1}, FormatText)
 	want := "error: something broke\n"
 	if buf.String() != want {
 		t.Errorf("got %q, want %q", buf.String(), want)
@@ -44,13 +70,53 @@ func TestPrintError_Text(t *testing.T) {
 
 func TestPrintError_JSON(t *testing.T) {
 	var buf bytes.Buffer
-	PrintError(&buf, "something broke", true)
-	var decoded map[string]string
+	PrintError(&buf, ErrorData{Error: "something broke", ExitCode: 1}, FormatJSON)
+	var decoded map[string]any
 	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
 		t.Fatalf("invalid JSON: %v", err)
 	}
-	if decoded["error"] != "something broke" {
-		t.Errorf("unexpected error field: %q", decoded["error"])
+	if decoded["error"] != "something broke" || decoded["exit_code"] != 1.0 {
+		t.Errorf("unexpected error object: %v", decoded)
+	}
+	if _, ok := decoded["http_status"]; ok {
+		t.Errorf("http_status should be omitted for non-API errors: %v", decoded)
+	}
+}
+
+func TestNewErrorData_API(t *testing.T) {
+	ce := clerrors.ClassifyResponse(403, http.Header{
+		"X-Ratelimit-Remaining": {"0"},
+		"X-Ratelimit-Reset":     {"1700000000"},
+		"X-Github-Request-Id":   {"ABCD:1234"},
+	}, []byte(`{"message":"API rate limit exceeded","documentation_url":"https://docs.github.com/rest"}`))
+	got := NewErrorData(fmt.Errorf("listing: %w", ce))
+	want := ErrorData{
+		Error:            "listing: rate limit exceeded",
+		Category:         "rate_limit",
+		ExitCode:         15,
+		HTTPStatus:       403,
+		GitHubMessage:    "API rate limit exceeded",
+		DocumentationURL: "https://docs.github.com/rest",
+		RequestID:        "ABCD:1234",
+		RateLimitReset:   "2023-11-14T22:13:20Z",
+	}
+	if got != want {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	var buf bytes.Buffer
+	PrintError(&buf, got, FormatJSON)
+	for _, key := range []string{`"category": "rate_limit"`, `"http_status": 403`, `"request_id": "ABCD:1234"`, `"rate_limit_reset": "2023-11-14T22:13:20Z"`} {
+		if !strings.Contains(buf.String(), key) {
+			t.Errorf("JSON error missing %s:\n%s", key, buf.String())
+		}
+	}
+}
+
+func TestNewErrorData_Plain(t *testing.T) {
+	got := NewErrorData(errors.New("unknown flag"))
+	if got != (ErrorData{Error: "unknown flag", ExitCode: 1}) {
+		t.Errorf("got %+v", got)
 	}
 }
 
@@ -63,7 +129,7 @@ func TestPrintEntry_Text(t *testing.T) {
 		Size:        42,
 		DownloadURL: "https://example.com/README.md",
 	}
-	if err := PrintEntry(&buf, e, false); err != nil {
+	if err := PrintEntry(&buf, e, FormatText); err != nil {
 		t.Fatal(err)
 	}
 	want := "type: file\npath: README.md\nsha: abc123\nsize: 42\ndownload_url: https://example.com/README.md\n"
@@ -72,6 +138,36 @@ func TestPrintEntry_Text(t *testing.T) {
 	}
 }
 
+func TestPrintEntry_TextDirWithCommit(t *testing.T) {
+	var buf bytes.Buffer
+	e := EntryData{
+		Type:      "dir",
+		Path:      "docs",
+		SHA:       "tree1",
+		Children:  2,
+		FileCount: 5,
+		TotalSize: 1024,
+		LastCommit: &CommitData{
+			SHA:        "c0ffee",
+			AuthorName: "Octo",
+			Date:       "2024-01-02T03:04:05Z",
+			Message:    "Update docs\n\nLonger body.",
+		},
+		Permalink: "https://github.com/owner/repo/tree/c0ffee/docs",
+	}
+	if err := PrintEntry(&buf, e, FormatText); err != nil {
+		t.Fatal(err)
+	}
+	want := "type: dir\npath: docs\nsha: tree1\nsize: 0\n" +
+		"children: 2\nfile_count: 5\ntotal_size: 1024\n" +
+		"last_commit: c0ffee\nlast_commit_author: Octo\nlast_commit_date: 2024-01-02T03:04:05Z\n" +
+		"last_commit_message: Update docs\n" +
+		"permalink: https://github.com/owner/repo/tree/c0ffee/docs\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
 func TestPrintEntry_JSON(t *testing.T) {
 	var buf bytes.Buffer
 	e := EntryData{
@@ -80,7 +176,7 @@ func TestPrintEntry_JSON(t *testing.T) {
 		SHA:  "abc123",
 		Size: 42,
 	}
-	if err := PrintEntry(&buf, e, true); err != nil {
+	if err := PrintEntry(&buf, e, FormatJSON); err != nil {
 		t.Fatal(err)
 	}
 	var decoded EntryData
@@ -98,7 +194,7 @@ func TestPrintEntries_Text(t *testing.T) {
 		{Type: "file", Path: "a.md"},
 		{Type: "dir", Path: "sub"},
 	}
-	if err := PrintEntries(&buf, entries, false); err != nil {
+	if err := PrintEntries(&buf, entries, FormatText); err != nil {
 		t.Fatal(err)
 	}
 	want := "file\ta.md\ndir\tsub\n"
@@ -107,13 +203,28 @@ func TestPrintEntries_Text(t *testing.T) {
 	}
 }
 
+func TestPrintEntries_TextLinks(t *testing.T) {
+	var buf bytes.Buffer
+	entries := []EntryData{
+		{Type: "symlink", Path: "docs/readme.md", Target: "../README.md"},
+		{Type: "submodule", Path: "lib", SHA: "c0ffee"},
+	}
+	if err := PrintEntries(&buf, entries, FormatText); err != nil {
+		t.Fatal(err)
+	}
+	want := "symlink\tdocs/readme.md -> ../README.md\nsubmodule\tlib @ c0ffee\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
 func TestPrintEntries_JSON(t *testing.T) {
 	var buf bytes.Buffer
 	entries := []EntryData{
 		{Type: "file", Path: "a.md", SHA: "aaa", Size: 10},
 		{Type: "dir", Path: "sub", SHA: "bbb", Size: 0},
 	}
-	if err := PrintEntries(&buf, entries, true); err != nil {
+	if err := PrintEntries(&buf, entries, FormatJSON); err != nil {
 		t.Fatal(err)
 	}
 	var decoded []EntryData
@@ -136,7 +247,7 @@ func TestPrintMutationResult_Text(t *testing.T) {
 		SHA:    "abc123",
 		Branch: "main",
 	}
-	if err := PrintMutationResult(&buf, r, false); err != nil {
+	if err := PrintMutationResult(&buf, r, FormatText); err != nil {
 		t.Fatal(err)
 	}
 	want := "action: created\npath: new-file.txt\nsha: abc123\nbranch: main\n"
@@ -152,7 +263,7 @@ func TestPrintMutationResult_TextNoBranch(t *testing.T) {
 		Path:   "old-file.txt",
 		SHA:    "def456",
 	}
-	if err := PrintMutationResult(&buf, r, false); err != nil {
+	if err := PrintMutationResult(&buf, r, FormatText); err != nil {
 		t.Fatal(err)
 	}
 	want := "action: deleted\npath: old-file.txt\nsha: def456\n"
@@ -169,7 +280,7 @@ func TestPrintMutationResult_JSON(t *testing.T) {
 		SHA:    "sha789",
 		Branch: "dev",
 	}
-	if err := PrintMutationResult(&buf, r, true); err != nil {
+	if err := PrintMutationResult(&buf, r, FormatJSON); err != nil {
 		t.Fatal(err)
 	}
 	var decoded MutationResultData
@@ -180,3 +291,96 @@ func TestPrintMutationResult_JSON(t *testing.T) {
 		t.Errorf("unexpected decoded result: %+v", decoded)
 	}
 }
+
+func TestPrintVerifyResult_Text(t *testing.T) {
+	var buf bytes.Buffer
+	r := VerifyResultData{
+		Status:     "drift",
+		Repository: "owner/repo",
+		Commit:     "abc",
+		Checked:    2,
+		Drift:      []DriftData{{Path: "docs/a.md", Status: "modified"}},
+	}
+	if err := PrintVerifyResult(&buf, r, FormatText); err != nil {
+		t.Fatal(err)
+	}
+	want := "modified\tdocs/a.md\nstatus: drift\nrepository: owner/repo\ncommit: abc\nchecked: 2\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestPrintVerifyResult_JSONEmptyDrift(t *testing.T) {
+	var buf bytes.Buffer
+	if err := PrintVerifyResult(&buf, VerifyResultData{Status: "ok", Checked: 1}, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if drift, ok := decoded["drift"].([]any); !ok || len(drift) != 0 {
+		t.Errorf("drift: got %v, want empty array", decoded["drift"])
+	}
+}
+
+func TestPrintSettings(t *testing.T) {
+	settings := []SettingData{{Key: "default_profile", Value: "work"}, {Key: "work.timeout", Value: "30s"}}
+	var buf bytes.Buffer
+	if err := PrintSettings(&buf, settings, FormatText); err != nil {
+		t.Fatal(err)
+	}
+	if want := "default_profile=work\nwork.timeout=30s\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := PrintSettings(&buf, nil, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	if want := "[]\n"; buf.String() != want {
+		t.Errorf("empty JSON list: got %q", buf.String())
+	}
+}
+
+func TestPrintAuthStatus(t *testing.T) {
+	s := AuthStatusData{
+		Host:        "github.com",
+		TokenSource: "auth login",
+		Logins:      []CredentialData{{Host: "github.com", User: "octocat", Storage: "keyring", Scopes: "repo", CreatedAt: "2026-01-02T03:04:05Z"}},
+	}
+	var buf bytes.Buffer
+	if err := PrintAuthStatus(&buf, s, FormatText); err != nil {
+		t.Fatal(err)
+	}
+	want := "host: github.com\ntoken_source: auth login\nlogins:\n  github.com: octocat (keyring, scopes: repo, since 2026-01-02T03:04:05Z)\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := PrintAuthStatus(&buf, AuthStatusData{Host: "github.com"}, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"logins": []`) || !strings.Contains(buf.String(), `"token_source": ""`) {
+		t.Errorf("empty status JSON: %s", buf.String())
+	}
+}
+
+func TestPrintRateLimits_Text(t *testing.T) {
+	var buf bytes.Buffer
+	limits := []RateLimitData{
+		{Resource: "core", Limit: 5000, Used: 10, Remaining: 4990, Reset: "2026-01-01T00:00:00Z"},
+		{Resource: "search", Limit: 30, Used: 0, Remaining: 30, Reset: "2026-01-01T00:00:00Z"},
+	}
+	if err := PrintRateLimits(&buf, limits, FormatText); err != nil {
+		t.Fatal(err)
+	}
+	want := "" +
+		"RESOURCE  LIMIT  USED  REMAINING  RESET\n" +
+		"core      5000   10    4990       2026-01-01T00:00:00Z\n" +
+		"search    30     0     30         2026-01-01T00:00:00Z\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,35 @@
+package output
+
+import "io"
+
+// Streams reports whether lists in format f can be printed one item at a
+// time as a command produces them. That holds for NDJSON without --jq,
+// whose query needs the whole list.
+func Streams(f Format) bool {
+	return f.Name == "ndjson" && f.Query == nil
+}
+
+// Stream prints list items as they arrive, for formats where Streams holds.
+type Stream struct {
+	w   io.Writer
+	f   Format
+	err error
+}
+
+// NewStream returns a Stream writing to w in format f.
+func NewStream(w io.Writer, f Format) *Stream {
+	return &Stream{w: w, f: f}
+}
+
+// Add prints one item. A write error is kept and returned by Err, so that
+// producers need not check each call; later items are dropped.
+func (s *Stream) Add(item any) {
+	if s.err == nil {
+		s.err = Render(s.w, s.f, item)
+	}
+}
+
+// Err returns the first error Add met.
+func (s *Stream) Err() error {
+	return s.err
+}
@@ -0,0 +1,61 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"githubRAGCli/internal/jq"
+)
+
+// lineWriter records what each Write call received.
+type lineWriter struct{ writes []string }
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+func TestStream(t *testing.T) {
+	f := Format{Name: "ndjson", Fields: []string{"path"}}
+	if !Streams(f) {
+		t.Fatal("ndjson should stream")
+	}
+	var w lineWriter
+	s := NewStream(&w, f)
+	s.Add(sampleEntries[0])
+	if len(w.writes) != 1 || w.writes[0] != `{"path":"a.md"}`+"\n" {
+		t.Fatalf("first item not written at once: %q", w.writes)
+	}
+	s.Add(sampleEntries[1])
+	if err := s.Err(); err != nil || len(w.writes) != 2 {
+		t.Errorf("writes %q, err %v", w.writes, err)
+	}
+
+	q, err := jq.Parse(".[]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []Format{FormatText, FormatJSON, {Name: "ndjson", Query: q}} {
+		if Streams(f) {
+			t.Errorf("%+v should not stream", f)
+		}
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) { return 0, errors.New("broken pipe") }
+
+func TestStream_Err(t *testing.T) {
+	s := NewStream(failWriter{}, Format{Name: "ndjson"})
+	s.Add(sampleEntries[0])
+	s.Add(sampleEntries[1])
+	if err := s.Err(); err == nil || err.Error() != "broken pipe" {
+		t.Errorf("got %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Render(&buf, Format{Name: "ndjson"}, []EntryData{}); err != nil || buf.Len() != 0 {
+		t.Errorf("empty list: %q, %v", buf.String(), err)
+	}
+}
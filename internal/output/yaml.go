@@ -0,0 +1,119 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// writeYAML emits the generic JSON form of a value (see toOrdered) as block
+// style YAML. Strings that YAML would read as another type, or that contain
+// special characters, are double-quoted using JSON escapes, which YAML
+// accepts.
+func writeYAML(buf *bytes.Buffer, v any, indent int) {
+	pad := strings.Repeat("  ", indent)
+	switch t := v.(type) {
+	case *object:
+		if len(t.keys) == 0 {
+			buf.WriteString(pad + "{}\n")
+			return
+		}
+		for _, k := range t.keys {
+			buf.WriteString(pad + yamlScalar(k) + ":")
+			writeYAMLValue(buf, t.vals[k], indent+1)
+		}
+	case []any:
+		if len(t) == 0 {
+			buf.WriteString(pad + "[]\n")
+			return
+		}
+		for _, item := range t {
+			buf.WriteString(pad + "-")
+			writeYAMLItem(buf, item, indent+1)
+		}
+	default:
+		buf.WriteString(pad + yamlScalar(v) + "\n")
+	}
+}
+
+// writeYAMLValue writes a mapping value after its "key:".
+func writeYAMLValue(buf *bytes.Buffer, v any, indent int) {
+	switch t := v.(type) {
+	case *object:
+		if len(t.keys) == 0 {
+			buf.WriteString(" {}\n")
+			return
+		}
+		buf.WriteString("\n")
+		writeYAML(buf, t, indent)
+	case []any:
+		if len(t) == 0 {
+			buf.WriteString(" []\n")
+			return
+		}
+		buf.WriteString("\n")
+		writeYAML(buf, t, indent)
+	default:
+		buf.WriteString(" " + yamlScalar(v) + "\n")
+	}
+}
+
+// writeYAMLItem writes a sequence item after its "-". The first key of an
+// object item shares the dash's line.
+func writeYAMLItem(buf *bytes.Buffer, v any, indent int) {
+	obj, ok := v.(*object)
+	if !ok || len(obj.keys) == 0 {
+		writeYAMLValue(buf, v, indent)
+		return
+	}
+	var rest bytes.Buffer
+	writeYAML(&rest, obj, indent)
+	buf.WriteString(" " + strings.TrimPrefix(rest.String(), strings.Repeat("  ", indent)))
+}
+
+func yamlScalar(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(t)
+	case json.Number:
+		return t.String()
+	case string:
+		if yamlNeedsQuotes(t) {
+			q, _ := json.Marshal(t)
+			return string(q)
+		}
+		return t
+	default:
+		data, _ := json.Marshal(t)
+		return string(data)
+	}
+}
+
+// yamlNeedsQuotes reports whether s must be quoted to round-trip as a string.
+func yamlNeedsQuotes(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s {
+		return true
+	}
+	switch strings.ToLower(s) {
+	case "null", "~", "true", "false", "yes", "no", "on", "off", "y", "n":
+		return true
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") {
+		return true
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return true
+	}
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
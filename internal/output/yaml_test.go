@@ -0,0 +1,56 @@
+package output
+
+import (
+	"testing"
+)
+
+func TestRender_YAMLNested(t *testing.T) {
+	v := map[string]any{
+		"commit": map[string]any{"sha": "abc", "message": "fix: thing\nbody"},
+		"drift":  []any{},
+		"empty":  map[string]any{},
+		"list":   []any{"x", "1", true, nil, []any{"z"}},
+	}
+	got := render(t, "yaml", v)
+	want := "" +
+		"commit:\n" +
+		"  message: \"fix: thing\\nbody\"\n" +
+		"  sha: abc\n" +
+		"drift: []\n" +
+		"empty: {}\n" +
+		"list:\n" +
+		"  - x\n" +
+		"  - \"1\"\n" +
+		"  - true\n" +
+		"  - null\n" +
+		"  -\n" +
+		"    - z\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestYAMLNeedsQuotes(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"plain", false},
+		{"docs/a.md", false},
+		{"", true},
+		{"yes", true},
+		{"null", true},
+		{"42", true},
+		{"1e3", true},
+		{"-dash", true},
+		{"key: value", true},
+		{"a #comment", true},
+		{" padded", true},
+		{"line\nbreak", true},
+	}
+	for _, tt := range tests {
+		if got := yamlNeedsQuotes(tt.s); got != tt.want {
+			t.Errorf("yamlNeedsQuotes(%q): got %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	clerrors "githubRAGCli/internal/exitcode"
+	"githubRAGCli/internal/githubapi"
+)
+
+// expiryWarning is how close to its expiry a token must be before auth
+// check warns about it.
+const expiryWarning = 7 * 24 * time.Hour
+
+// AccessReport gathers what auth check learned about the token.
+type AccessReport struct {
+	TokenType string
+	Scopes    []string // classic and OAuth tokens only; nil when unknown
+	ExpiresAt time.Time
+	Repo      *githubapi.RepoAccess // nil when no repository was given
+}
+
+// Hints returns one line of advice per problem found, each naming the
+// commands that will fail because of it.
+func (r AccessReport) Hints(now time.Time) []string {
+	var hints []string
+	if r.Scopes != nil {
+		switch {
+		case hasScope(r.Scopes, "repo"):
+		case hasScope(r.Scopes, "public_repo"):
+			hints = append(hints, "token has public_repo but not repo: private repositories are invisible to every command; add the repo scope")
+		default:
+			hints = append(hints, "token has neither repo nor public_repo scope: put and rm will fail, and private repositories are invisible; add the repo scope")
+		}
+	}
+	if !r.ExpiresAt.IsZero() {
+		switch left := r.ExpiresAt.Sub(now); {
+		case left <= 0:
+			hints = append(hints, fmt.Sprintf("token has expired: every command will fail with exit code %d; create a new token", clerrors.ExitAuthFailure))
+		case left < expiryWarning:
+			hints = append(hints, fmt.Sprintf("token expires in %s: renew it before then", left.Round(time.Hour)))
+		}
+	}
+
+	if r.Repo != nil {
+		switch r.Repo.Permission {
+		case githubapi.PermNone:
+			hints = append(hints, fmt.Sprintf("%s is not visible to this token: every command on it will fail with exit code %d; check the name and that the token can access it", r.Repo.Repository, clerrors.ExitNotFound))
+		case githubapi.PermRead, githubapi.PermTriage:
+			hints = append(hints, fmt.Sprintf("%s permission on %s: put and rm will fail with exit code %d; ask for write access", r.Repo.Permission, r.Repo.Repository, clerrors.ExitPermission))
+		}
+		if r.Repo.Protected {
+			hints = append(hints, fmt.Sprintf("default branch %s is protected: put and rm on it may be rejected; write to another branch with -b and open a pull request", r.Repo.DefaultBranch))
+		}
+		if r.TokenType == githubapi.TokenFineGrained && r.Repo.Permission != githubapi.PermNone {
+			hints = append(hints, "fine-grained token: the permission shown is your role in the repository; put and rm also need the token's Contents permission set to read and write")
+		}
+	}
+	return hints
+}
+
+func hasScope(scopes []string, want string) bool {
+	for _, s := range scopes {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
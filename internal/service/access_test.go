@@ -0,0 +1,46 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"githubRAGCli/internal/githubapi"
+)
+
+func TestAccessReport_Hints(t *testing.T) {
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name   string
+		report AccessReport
+		want   []string // one substring per expected hint, in order
+	}{
+		{"all good", AccessReport{TokenType: githubapi.TokenClassic, Scopes: []string{"repo", "read:org"},
+			Repo: &githubapi.RepoAccess{Repository: "o/r", Permission: githubapi.PermWrite, DefaultBranch: "main"}}, nil},
+		{"unknown scopes", AccessReport{TokenType: githubapi.TokenInstallation}, nil},
+		{"no scopes", AccessReport{Scopes: []string{}}, []string{"neither repo nor public_repo"}},
+		{"public only", AccessReport{Scopes: []string{"public_repo"}}, []string{"private repositories are invisible"}},
+		{"expiring", AccessReport{ExpiresAt: now.Add(48 * time.Hour)}, []string{"expires in 48h0m0s"}},
+		{"expired", AccessReport{ExpiresAt: now.Add(-time.Hour)}, []string{"has expired"}},
+		{"far expiry", AccessReport{ExpiresAt: now.Add(30 * 24 * time.Hour)}, nil},
+		{"read only", AccessReport{Repo: &githubapi.RepoAccess{Repository: "o/r", Permission: githubapi.PermRead}},
+			[]string{"read permission on o/r: put and rm will fail with exit code 11"}},
+		{"not visible", AccessReport{TokenType: githubapi.TokenFineGrained, Repo: &githubapi.RepoAccess{Repository: "o/r", Permission: githubapi.PermNone}},
+			[]string{"o/r is not visible"}},
+		{"protected fine-grained", AccessReport{TokenType: githubapi.TokenFineGrained,
+			Repo: &githubapi.RepoAccess{Repository: "o/r", Permission: githubapi.PermAdmin, DefaultBranch: "main", Protected: true}},
+			[]string{"default branch main is protected", "Contents permission"}},
+	}
+	for _, c := range cases {
+		got := c.report.Hints(now)
+		if len(got) != len(c.want) {
+			t.Errorf("%s: got %q, want %d hints", c.name, got, len(c.want))
+			continue
+		}
+		for i := range c.want {
+			if !strings.Contains(got[i], c.want[i]) {
+				t.Errorf("%s: hint %d = %q, want it to contain %q", c.name, i, got[i], c.want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,593 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+// DownloadOptions controls how Download materializes repository content.
+type DownloadOptions struct {
+	Overwrite         bool
+	RecurseSubmodules bool // download submodules at their pinned commit instead of recording them
+	Resume            bool // continue an interrupted download using its journal
+	Follow            bool // materialize symlink targets instead of recreating links
+	LastModified      bool // look up each file's last commit date for the manifest
+	PreserveTimes     bool // set local mtimes to the last commit date (implies LastModified)
+
+	// OnEntry, if set, is called with each file and symlink as soon as it
+	// is written, before last-commit dates are known.
+	OnEntry func(ManifestFile)
+}
+
+// SubmoduleRef records a submodule pointer found while downloading.
+type SubmoduleRef struct {
+	Path       string `json:"path"`
+	SHA        string `json:"sha"` // pinned commit
+	URL        string `json:"url,omitempty"`
+	Downloaded bool   `json:"downloaded"`
+}
+
+// DownloadResult summarizes what a download wrote to disk.
+type DownloadResult struct {
+	Files      int
+	Resumed    int // files already present from an earlier, interrupted run
+	Verified   int // files whose blob SHA was checked after download
+	Symlinks   int
+	Skipped    []string // symlinks refused because they would escape the output root
+	Submodules []SubmoduleRef
+	Entries    []ManifestFile // every file and symlink written, with local paths as on disk
+}
+
+// downloader carries per-invocation state through a (possibly nested) download.
+type downloader struct {
+	opts       DownloadOptions
+	root       string // top-level output directory; symlinks may not point outside it
+	result     *DownloadResult
+	gitmodules map[string]map[string]string // parsed .gitmodules keyed by owner/repo@ref
+	journal    *journal                     // nil for single-file downloads
+	written    []writtenFile
+	prefix     string          // remote path of the submodule being downloaded, if any
+	followed   map[string]bool // directories already expanded through a followed symlink
+
+	pendingTimes []pendingTime
+}
+
+// writtenFile is a downloaded file awaiting final verification.
+type writtenFile struct {
+	localPath string
+	sha       string
+}
+
+// Download writes repository content to the local filesystem.
+// For a file, it writes the decoded content to outPath.
+// For a directory, it recursively lists and downloads all files.
+func (s *RepoService) Download(ref, remotePath, outPath string, overwrite bool) error {
+	_, err := s.DownloadWithOptions(ref, remotePath, outPath, DownloadOptions{Overwrite: overwrite})
+	return err
+}
+
+// DownloadWithOptions is like Download but honors tree modes: executables keep
+// their executable bit, symlinks are recreated, and submodules are either
+// recorded in the result or downloaded at their pinned commit.
+func (s *RepoService) DownloadWithOptions(ref, remotePath, outPath string, opts DownloadOptions) (*DownloadResult, error) {
+	if opts.Follow || opts.RecurseSubmodules {
+		var err error
+		s, ref, remotePath, err = s.Resolve(ref, remotePath, ResolveOptions{
+			FollowSymlinks: opts.Follow,
+			IntoSubmodules: opts.RecurseSubmodules,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Determine if the path is a file or directory.
+	raw, err := s.Client.GetContents(s.Owner, s.Repo, remotePath, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &downloader{opts: opts, result: &DownloadResult{}}
+
+	var item contentsItem
+	if err := json.Unmarshal(raw, &item); err == nil && item.SHA != "" {
+		d.root = filepath.Dir(outPath)
+		switch item.Type {
+		case "file":
+			err = d.downloadFile(s, ref, Entry{Path: remotePath, SHA: item.SHA, Mode: s.lookupMode(ref, remotePath)}, outPath)
+		case "symlink":
+			err = d.writeSymlink(Entry{Path: item.Path, SHA: item.SHA, Mode: modeSymlink}, outPath, item.Target)
+		case "submodule":
+			d.root = outPath
+			err = d.downloadSubmodule(s, ref, Entry{Type: "submodule", Path: item.Path, SHA: item.SHA}, item.SubmoduleURL, outPath)
+		default:
+			err = clerrors.NewBadArgs(fmt.Sprintf("path %q is a %s and cannot be downloaded", remotePath, item.Type), nil)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := d.finish(); err != nil {
+			return nil, err
+		}
+		return d.result, nil
+	}
+
+	// Directory download.
+	d.root = outPath
+	if opts.Resume {
+		removeStaleTemps(outPath)
+	}
+	hdr := journalHeader{Repo: s.Owner + "/" + s.Repo, Ref: ref, Path: remotePath}
+	if d.journal, err = openJournal(outPath, hdr, opts.Resume); err != nil {
+		return nil, err
+	}
+	if err := d.downloadDir(s, ref, remotePath, outPath); err != nil {
+		d.journal.close(false)
+		return nil, err
+	}
+	if err := d.finish(); err != nil {
+		d.journal.close(false)
+		return nil, err
+	}
+	d.journal.close(true)
+	return d.result, nil
+}
+
+// finish runs the post-download steps: last-commit dates, then verification.
+func (d *downloader) finish() error {
+	if d.opts.LastModified || d.opts.PreserveTimes {
+		if err := d.applyTimes(); err != nil {
+			return err
+		}
+	}
+	return d.verify()
+}
+
+func (d *downloader) downloadFile(s *RepoService, ref string, entry Entry, outPath string) error {
+	if d.opts.Resume && d.alreadyDownloaded(entry, outPath) {
+		var size int64
+		if info, err := os.Stat(outPath); err == nil {
+			size = info.Size()
+		}
+		d.result.Resumed++
+		d.written = append(d.written, writtenFile{localPath: outPath, sha: entry.SHA})
+		d.addEntry(s, ref, "file", entry, outPath, size, "")
+		return nil
+	}
+
+	if !d.opts.Overwrite {
+		if _, err := os.Lstat(outPath); err == nil {
+			return clerrors.NewLocalWriteErr(fmt.Sprintf("file already exists: %s (use --overwrite to replace)", outPath), nil)
+		}
+	}
+
+	data, err := s.ReadFile(ref, entry.Path)
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(outPath, data, fileModePerm(entry.Mode)); err != nil {
+		return err
+	}
+	if d.journal != nil {
+		if err := d.journal.record(d.relPath(outPath), entry.SHA); err != nil {
+			return err
+		}
+	}
+
+	d.result.Files++
+	d.written = append(d.written, writtenFile{localPath: outPath, sha: entry.SHA})
+	d.addEntry(s, ref, "file", entry, outPath, int64(len(data)), "")
+	return nil
+}
+
+// addEntry records a written file or symlink for the download manifest.
+func (d *downloader) addEntry(s *RepoService, ref, typ string, entry Entry, localPath string, size int64, target string) {
+	if typ == "file" {
+		d.pendingTimes = append(d.pendingTimes, pendingTime{svc: s, ref: ref, remotePath: entry.Path, entry: len(d.result.Entries)})
+	}
+	mode := entry.Mode
+	if mode == "" {
+		mode = "100644"
+	}
+	f := ManifestFile{
+		Type:       typ,
+		RemotePath: joinPath(d.prefix, entry.Path),
+		LocalPath:  localPath,
+		SHA:        entry.SHA,
+		Size:       size,
+		Mode:       mode,
+		Target:     target,
+	}
+	d.result.Entries = append(d.result.Entries, f)
+	if d.opts.OnEntry != nil {
+		d.opts.OnEntry(f)
+	}
+}
+
+// alreadyDownloaded reports whether a resumed download can skip entry: either
+// the journal records it, or the local file already has the expected content.
+func (d *downloader) alreadyDownloaded(entry Entry, outPath string) bool {
+	if _, err := os.Lstat(outPath); err != nil {
+		return false
+	}
+	if d.journal != nil && d.journal.completed(d.relPath(outPath), entry.SHA) {
+		return true
+	}
+	sha, err := hashLocalFile(outPath)
+	return err == nil && sha == entry.SHA
+}
+
+// verify re-hashes every downloaded file and compares it with the blob SHA
+// from the repository tree.
+func (d *downloader) verify() error {
+	for _, wf := range d.written {
+		if wf.sha == "" {
+			continue
+		}
+		got, err := hashLocalFile(wf.localPath)
+		if err != nil {
+			return clerrors.NewLocalWriteErr("failed to verify file: "+wf.localPath, err)
+		}
+		if got != wf.sha {
+			return clerrors.NewLocalWriteErr(fmt.Sprintf("verification failed for %s: blob sha %s, expected %s", wf.localPath, got, wf.sha), nil)
+		}
+		d.result.Verified++
+	}
+	return nil
+}
+
+// relPath returns localPath relative to the download root, in slash form.
+func (d *downloader) relPath(localPath string) string {
+	rel, err := filepath.Rel(d.root, localPath)
+	if err != nil {
+		return filepath.ToSlash(localPath)
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (d *downloader) downloadDir(s *RepoService, ref, remotePath, outPath string) error {
+	entries, err := s.listRecursive(ref, remotePath)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		// Compute relative path within the downloaded directory.
+		relPath := entry.Path
+		if remotePath != "" && remotePath != "." && remotePath != "/" {
+			relPath = strings.TrimPrefix(entry.Path, remotePath+"/")
+		}
+		localPath := filepath.Join(outPath, relPath)
+
+		switch entry.Type {
+		case "file":
+			err = d.downloadFile(s, ref, entry, localPath)
+		case "symlink":
+			if d.opts.Follow {
+				err = d.followSymlink(s, ref, entry, localPath)
+				break
+			}
+			var target string
+			target, err = s.readSymlinkTarget(entry.SHA)
+			if err == nil {
+				err = d.writeSymlink(entry, localPath, target)
+			}
+		case "submodule":
+			err = d.downloadSubmodule(s, ref, entry, "", localPath)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeSymlink recreates a repository symlink at localPath. Targets are
+// cleaned before writing so that ".." can only appear as a leading component,
+// and links that would resolve outside the download root are skipped.
+func (d *downloader) writeSymlink(entry Entry, localPath, target string) error {
+	remotePath := joinPath(d.prefix, entry.Path)
+	cleaned := filepath.Clean(filepath.FromSlash(target))
+	if !symlinkWithin(d.root, localPath, cleaned) {
+		slog.Warn("skipping symlink: target escapes the download", "path", remotePath, "target", target, "root", d.root)
+		d.result.Skipped = append(d.result.Skipped, remotePath)
+		return nil
+	}
+
+	if _, err := os.Lstat(localPath); err == nil {
+		if !d.opts.Overwrite {
+			return clerrors.NewLocalWriteErr(fmt.Sprintf("file already exists: %s (use --overwrite to replace)", localPath), nil)
+		}
+		if err := os.Remove(localPath); err != nil {
+			return clerrors.NewLocalWriteErr("failed to replace file: "+localPath, err)
+		}
+	}
+
+	dir := filepath.Dir(localPath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return clerrors.NewLocalWriteErr("failed to create directory: "+dir, err)
+	}
+	if err := os.Symlink(cleaned, localPath); err != nil {
+		return clerrors.NewLocalWriteErr("failed to create symlink: "+localPath, err)
+	}
+
+	d.result.Symlinks++
+	d.addEntry(nil, "", "symlink", entry, localPath, int64(len(target)), cleaned)
+	return nil
+}
+
+// followSymlink downloads whatever a symlink resolves to into localPath.
+// Links that dangle, leave the repository, or point at one of their own
+// ancestor directories are skipped with a warning.
+func (d *downloader) followSymlink(s *RepoService, ref string, entry Entry, localPath string) error {
+	remotePath := joinPath(d.prefix, entry.Path)
+	skip := func(reason string) error {
+		slog.Warn("skipping symlink", "path", remotePath, "reason", reason)
+		d.result.Skipped = append(d.result.Skipped, remotePath)
+		return nil
+	}
+
+	ts, tref, tpath, err := s.Resolve(ref, entry.Path, ResolveOptions{FollowSymlinks: true, IntoSubmodules: d.opts.RecurseSubmodules})
+	if err != nil {
+		var ce *clerrors.CLIError
+		if errors.As(err, &ce) && (ce.Cat == clerrors.CatNotFound || ce.Cat == clerrors.CatBadArgs) {
+			return skip(ce.Message)
+		}
+		return err
+	}
+
+	item, isDir, err := ts.contentsItemAt(tref, tpath)
+	if err != nil {
+		return err
+	}
+
+	if isDir {
+		sameRepo := ts.Owner == s.Owner && ts.Repo == s.Repo && tref == ref
+		if sameRepo && (tpath == "" || entry.Path == tpath || strings.HasPrefix(entry.Path, tpath+"/")) {
+			return skip("target is an ancestor directory")
+		}
+		key := ts.Owner + "/" + ts.Repo + "@" + tref + ":" + tpath
+		if d.followed[key] {
+			return skip("target directory already expanded")
+		}
+		if d.followed == nil {
+			d.followed = make(map[string]bool)
+		}
+		d.followed[key] = true
+		return d.downloadDir(ts, tref, tpath, localPath)
+	}
+
+	switch item.Type {
+	case "file":
+		return d.downloadFile(ts, tref, Entry{Path: tpath, SHA: item.SHA, Mode: ts.lookupMode(tref, tpath)}, localPath)
+	case "submodule":
+		return d.downloadSubmodule(ts, tref, Entry{Type: "submodule", Path: item.Path, SHA: item.SHA}, item.SubmoduleURL, localPath)
+	default:
+		return skip("unsupported target type " + item.Type)
+	}
+}
+
+// downloadSubmodule records a submodule pointer and, when requested, downloads
+// the submodule repository at its pinned commit into localPath.
+func (d *downloader) downloadSubmodule(s *RepoService, ref string, entry Entry, url, localPath string) error {
+	if url == "" {
+		url = d.submoduleURL(s, ref, entry.Path)
+	}
+	sm := SubmoduleRef{Path: joinPath(d.prefix, entry.Path), SHA: entry.SHA, URL: url}
+
+	if d.opts.RecurseSubmodules {
+		host, owner, repo, ok := parseSubmoduleURL(url, s.Owner)
+		switch {
+		case !ok:
+			slog.Warn("cannot download submodule: unsupported url", "path", entry.Path, "url", url)
+		case !s.sameHost(host):
+			slog.Warn("skipping submodule on another host", "path", entry.Path, "url", url)
+		default:
+			sub := &RepoService{Client: s.Client, Owner: owner, Repo: repo}
+			outer := d.prefix
+			d.prefix = sm.Path
+			err := d.downloadDir(sub, entry.SHA, "", localPath)
+			d.prefix = outer
+			if err != nil {
+				return err
+			}
+			sm.Downloaded = true
+		}
+	}
+
+	d.result.Submodules = append(d.result.Submodules, sm)
+	return nil
+}
+
+// readSymlinkTarget returns the link target stored in a symlink blob.
+func (s *RepoService) readSymlinkTarget(sha string) (string, error) {
+	blob, err := s.Client.GetBlob(s.Owner, s.Repo, sha)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(blob.Content, "\n", ""))
+	if err != nil {
+		return "", clerrors.NewTransport("failed to decode base64 content", err)
+	}
+	return string(data), nil
+}
+
+// submoduleURL looks up the remote URL of the submodule at subPath in the
+// superproject's .gitmodules at ref. It returns "" if unknown.
+func (d *downloader) submoduleURL(s *RepoService, ref, subPath string) string {
+	key := s.Owner + "/" + s.Repo + "@" + ref
+	urls, ok := d.gitmodules[key]
+	if !ok {
+		urls = s.gitmodulesURLs(ref)
+		if d.gitmodules == nil {
+			d.gitmodules = make(map[string]map[string]string)
+		}
+		d.gitmodules[key] = urls
+	}
+	return urls[subPath]
+}
+
+// lookupMode returns the git tree mode of a single file. The lookup is
+// best-effort: on any failure the file is treated as a regular,
+// non-executable file.
+func (s *RepoService) lookupMode(ref, filePath string) string {
+	parent := path.Dir(filePath)
+	if parent == "." {
+		parent = ""
+	}
+	parentSHA, err := s.getDirSHA(ref, parent)
+	if err != nil {
+		return "100644"
+	}
+	tree, err := s.Client.GetTree(s.Owner, s.Repo, parentSHA, false)
+	if err != nil {
+		return "100644"
+	}
+	base := path.Base(filePath)
+	for _, te := range tree.Tree {
+		if te.Path == base {
+			return te.Mode
+		}
+	}
+	return "100644"
+}
+
+// fileModePerm maps a Git tree mode to local file permissions.
+func fileModePerm(mode string) os.FileMode {
+	if mode == modeExecutable {
+		return 0o755
+	}
+	return 0o644
+}
+
+// symlinkWithin reports whether a link at linkPath pointing to target stays
+// inside root. target must already be cleaned.
+func symlinkWithin(root, linkPath, target string) bool {
+	if filepath.IsAbs(target) {
+		return false
+	}
+	resolved := filepath.Join(filepath.Dir(linkPath), target)
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// parseGitmodules extracts a path -> url map from a .gitmodules file.
+func parseGitmodules(content string) map[string]string {
+	urls := make(map[string]string)
+	var curPath, curURL string
+	flush := func() {
+		if curPath != "" && curURL != "" {
+			urls[curPath] = curURL
+		}
+		curPath, curURL = "", ""
+	}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			flush()
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "path":
+			curPath = strings.TrimSpace(value)
+		case "url":
+			curURL = strings.TrimSpace(value)
+		}
+	}
+	flush()
+	return urls
+}
+
+// parseSubmoduleURL extracts the host and owner/repo from a submodule remote
+// URL. Relative URLs ("../other.git") are resolved against the superproject
+// owner and have an empty host: they live on the superproject's server.
+func parseSubmoduleURL(rawURL, owner string) (host, subOwner, repo string, ok bool) {
+	u := strings.TrimSuffix(strings.TrimSuffix(rawURL, "/"), ".git")
+	switch {
+	case strings.HasPrefix(u, "../"):
+		rest := strings.TrimPrefix(u, "../")
+		if strings.HasPrefix(rest, "../") {
+			// "../../owner/repo" climbs out of the owner namespace.
+			rest = strings.TrimPrefix(rest, "../")
+			parts := strings.Split(rest, "/")
+			if len(parts) == 2 && parts[0] != "" && parts[1] != "" {
+				return "", parts[0], parts[1], true
+			}
+			return "", "", "", false
+		}
+		if rest != "" && !strings.Contains(rest, "/") {
+			return "", owner, rest, true
+		}
+		return "", "", "", false
+	case strings.Contains(u, "://"):
+		parsed, err := url.Parse(u)
+		if err != nil || parsed.Hostname() == "" {
+			return "", "", "", false
+		}
+		host, u = parsed.Hostname(), strings.TrimPrefix(parsed.Path, "/")
+	case strings.Contains(u, "@") && strings.Contains(u, ":"):
+		host, u, _ = strings.Cut(u, ":") // scp-like git@host:owner/repo
+		host = host[strings.LastIndex(host, "@")+1:]
+	default:
+		return "", "", "", false
+	}
+	parts := strings.Split(u, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", "", false
+	}
+	return strings.ToLower(host), parts[0], parts[1], true
+}
+
+// sameHost reports whether a submodule on host is served by the server s
+// talks to; only those can be fetched through its API. The port is ignored,
+// since ssh URLs name the ssh port.
+func (s *RepoService) sameHost(host string) bool {
+	if host == "" {
+		return true
+	}
+	web, err := url.Parse(s.Client.WebURL())
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(web.Hostname(), host)
+}
+
+// DownloadFileFromURL downloads a file from a raw URL (e.g., download_url)
+// through the service's client; see githubapi.Client.GetRaw.
+func (s *RepoService) DownloadFileFromURL(url, outPath string, overwrite bool) error {
+	if !overwrite {
+		if _, err := os.Stat(outPath); err == nil {
+			return clerrors.NewLocalWriteErr(fmt.Sprintf("file already exists: %s (use --overwrite to replace)", outPath), nil)
+		}
+	}
+
+	data, err := s.Client.GetRaw(url)
+	if err != nil {
+		return err
+	}
+
+	return writeAtomic(outPath, 0o644, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
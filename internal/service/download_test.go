@@ -0,0 +1,450 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+// newModesServer serves a "pkg" directory containing an executable script,
+// a symlink inside the tree, a symlink escaping it and a submodule.
+func newModesServer(t *testing.T, submoduleURL string) *httptest.Server {
+	t.Helper()
+	b64 := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+	scriptSHA := GitBlobSHA([]byte("#!/bin/sh"))
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/contents/pkg":
+			json.NewEncoder(w).Encode([]map[string]any{
+				{"type": "file", "path": "pkg/run.sh", "sha": scriptSHA, "size": 9},
+			})
+		case "/repos/owner/repo/contents/":
+			json.NewEncoder(w).Encode([]map[string]any{
+				{"type": "dir", "path": "pkg", "sha": "treeSHA", "size": 0},
+			})
+		case "/repos/owner/repo/git/trees/treeSHA":
+			json.NewEncoder(w).Encode(map[string]any{
+				"sha": "treeSHA",
+				"tree": []map[string]any{
+					{"path": "run.sh", "mode": "100755", "type": "blob", "sha": GitBlobSHA([]byte("#!/bin/sh")), "size": 9},
+					{"path": "link", "mode": "120000", "type": "blob", "sha": "l1", "size": 6},
+					{"path": "evil", "mode": "120000", "type": "blob", "sha": "l2", "size": 12},
+					{"path": "vendor/lib", "mode": "160000", "type": "commit", "sha": "c0ffee"},
+				},
+			})
+		case "/repos/owner/repo/contents/pkg/run.sh":
+			json.NewEncoder(w).Encode(map[string]any{
+				"type": "file", "path": "pkg/run.sh", "sha": scriptSHA, "size": 9,
+				"content": b64("#!/bin/sh"), "encoding": "base64",
+			})
+		case "/repos/owner/repo/contents/pkg/link":
+			json.NewEncoder(w).Encode(map[string]any{"type": "symlink", "path": "pkg/link", "sha": "l1", "target": "run.sh"})
+		case "/repos/owner/repo/contents/pkg/evil":
+			json.NewEncoder(w).Encode(map[string]any{"type": "symlink", "path": "pkg/evil", "sha": "l2", "target": "../../etc/pw"})
+		case "/repos/owner/repo/git/blobs/l1":
+			json.NewEncoder(w).Encode(map[string]any{"sha": "l1", "content": b64("run.sh"), "encoding": "base64"})
+		case "/repos/owner/repo/git/blobs/l2":
+			json.NewEncoder(w).Encode(map[string]any{"sha": "l2", "content": b64("../../etc/pw"), "encoding": "base64"})
+		case "/repos/owner/repo/contents/.gitmodules":
+			json.NewEncoder(w).Encode(map[string]any{
+				"type": "file", "path": ".gitmodules", "sha": "gm",
+				"content":  b64("[submodule \"lib\"]\n\tpath = pkg/vendor/lib\n\turl = " + submoduleURL + "\n"),
+				"encoding": "base64",
+			})
+		case "/repos/owner/lib/contents/":
+			json.NewEncoder(w).Encode([]map[string]any{
+				{"type": "file", "path": "README.md", "sha": "rd", "size": 3},
+			})
+		case "/repos/owner/lib/git/trees/c0ffee":
+			json.NewEncoder(w).Encode(map[string]any{
+				"sha": "c0ffee",
+				"tree": []map[string]any{
+					{"path": "README.md", "mode": "100644", "type": "blob", "sha": GitBlobSHA([]byte("lib")), "size": 3},
+				},
+			})
+		case "/repos/owner/lib/contents/README.md":
+			json.NewEncoder(w).Encode(map[string]any{
+				"type": "file", "path": "README.md", "sha": GitBlobSHA([]byte("lib")), "size": 3,
+				"content": b64("lib"), "encoding": "base64",
+			})
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(404)
+		}
+	}))
+}
+
+func TestDownloadWithOptions_ModesSymlinksSubmodules(t *testing.T) {
+	srv := newModesServer(t, "https://github.com/other/lib.git")
+	defer srv.Close()
+
+	outPath := filepath.Join(t.TempDir(), "pkg")
+	svc := newTestService(srv.URL)
+	var streamed []string
+	result, err := svc.DownloadWithOptions("", "pkg", outPath, DownloadOptions{
+		OnEntry: func(f ManifestFile) { streamed = append(streamed, f.RemotePath) },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(streamed) != 2 || streamed[0] != "pkg/run.sh" || streamed[1] != "pkg/link" {
+		t.Errorf("OnEntry: got %v", streamed)
+	}
+
+	info, err := os.Stat(filepath.Join(outPath, "run.sh"))
+	if err != nil {
+		t.Fatalf("stat run.sh: %v", err)
+	}
+	if info.Mode().Perm() != 0o755 {
+		t.Errorf("run.sh mode: got %v, want 0755", info.Mode().Perm())
+	}
+
+	target, err := os.Readlink(filepath.Join(outPath, "link"))
+	if err != nil {
+		t.Fatalf("readlink: %v", err)
+	}
+	if target != "run.sh" {
+		t.Errorf("link target: got %q, want run.sh", target)
+	}
+
+	if _, err := os.Lstat(filepath.Join(outPath, "evil")); !os.IsNotExist(err) {
+		t.Errorf("escaping symlink should not be created, got err=%v", err)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0] != "pkg/evil" {
+		t.Errorf("skipped: got %v", result.Skipped)
+	}
+
+	if len(result.Entries) != 2 || result.Entries[0].Mode != "100755" || result.Entries[1].Type != "symlink" {
+		t.Errorf("entries: got %+v", result.Entries)
+	}
+
+	if len(result.Submodules) != 1 {
+		t.Fatalf("expected 1 submodule, got %d", len(result.Submodules))
+	}
+	sm := result.Submodules[0]
+	if sm.Path != "pkg/vendor/lib" || sm.SHA != "c0ffee" || sm.URL != "https://github.com/other/lib.git" || sm.Downloaded {
+		t.Errorf("submodule: got %+v", sm)
+	}
+}
+
+func TestDownloadWithOptions_RecurseSubmodules(t *testing.T) {
+	tests := []struct {
+		name           string
+		url            string
+		wantDownloaded bool
+	}{
+		{"relative url", "../lib.git", true},
+		{"another host", "https://gitlab.com/owner/lib.git", false},
+		{"scp-like url on another host", "git@github.com:owner/lib.git", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newModesServer(t, tt.url)
+			defer srv.Close()
+
+			outPath := filepath.Join(t.TempDir(), "pkg")
+			svc := newTestService(srv.URL)
+			result, err := svc.DownloadWithOptions("", "pkg", outPath, DownloadOptions{RecurseSubmodules: true})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(result.Submodules) != 1 {
+				t.Fatalf("expected 1 submodule, got %d", len(result.Submodules))
+			}
+			if sm := result.Submodules[0]; sm.URL != tt.url || sm.Downloaded != tt.wantDownloaded {
+				t.Errorf("submodule: got %+v", sm)
+			}
+			data, err := os.ReadFile(filepath.Join(outPath, "vendor", "lib", "README.md"))
+			if tt.wantDownloaded && string(data) != "lib" {
+				t.Errorf("submodule file: got %q, %v", data, err)
+			}
+			if !tt.wantDownloaded && !os.IsNotExist(err) {
+				t.Errorf("submodule on another host should not be downloaded, got err=%v", err)
+			}
+		})
+	}
+}
+
+func TestDownloadWithOptions_Follow(t *testing.T) {
+	srv := newModesServer(t, "https://github.com/other/lib.git")
+	defer srv.Close()
+
+	outPath := filepath.Join(t.TempDir(), "pkg")
+	svc := newTestService(srv.URL)
+	result, err := svc.DownloadWithOptions("", "pkg", outPath, DownloadOptions{Follow: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	info, err := os.Lstat(filepath.Join(outPath, "link"))
+	if err != nil {
+		t.Fatalf("lstat link: %v", err)
+	}
+	if !info.Mode().IsRegular() {
+		t.Errorf("followed link should be a regular file, got mode %v", info.Mode())
+	}
+	data, _ := os.ReadFile(filepath.Join(outPath, "link"))
+	if string(data) != "#!/bin/sh" {
+		t.Errorf("link content: got %q", data)
+	}
+	if result.Files != 2 {
+		t.Errorf("files: got %d, want 2", result.Files)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0] != "pkg/evil" {
+		t.Errorf("skipped: got %v", result.Skipped)
+	}
+}
+
+func TestSymlinkWithin(t *testing.T) {
+	root := filepath.FromSlash("/out")
+	tests := []struct {
+		link   string
+		target string
+		want   bool
+	}{
+		{"/out/a", "b", true},
+		{"/out/d/a", "../b", true},
+		{"/out/d/a", "../../b", false},
+		{"/out/a", "/etc/passwd", false},
+		{"/out/a", "..", false},
+	}
+	for _, tt := range tests {
+		got := symlinkWithin(root, filepath.FromSlash(tt.link), filepath.FromSlash(tt.target))
+		if got != tt.want {
+			t.Errorf("symlinkWithin(%q, %q): got %v, want %v", tt.link, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestParseSubmoduleURL(t *testing.T) {
+	tests := []struct {
+		url       string
+		wantHost  string
+		wantOwner string
+		wantRepo  string
+		wantOK    bool
+	}{
+		{"https://github.com/o/r.git", "github.com", "o", "r", true},
+		{"https://GitHub.com/o/r", "github.com", "o", "r", true},
+		{"git@github.com:o/r.git", "github.com", "o", "r", true},
+		{"ssh://git@ghe.example.com:22/o/r.git", "ghe.example.com", "o", "r", true},
+		{"https://gitlab.com/o/r.git", "gitlab.com", "o", "r", true},
+		{"../sibling.git", "", "owner", "sibling", true},
+		{"../../other/repo.git", "", "other", "repo", true},
+		{"file:///tmp/x", "", "", "", false},
+		{"plain", "", "", "", false},
+	}
+	for _, tt := range tests {
+		host, owner, repo, ok := parseSubmoduleURL(tt.url, "owner")
+		if ok != tt.wantOK || host != tt.wantHost || owner != tt.wantOwner || repo != tt.wantRepo {
+			t.Errorf("parseSubmoduleURL(%q): got (%q, %q, %q, %v), want (%q, %q, %q, %v)",
+				tt.url, host, owner, repo, ok, tt.wantHost, tt.wantOwner, tt.wantRepo, tt.wantOK)
+		}
+	}
+}
+
+// newDocsServer serves a "docs" directory with a.md and b.md. Requests for
+// file contents are counted per path.
+func newDocsServer(t *testing.T, treeSHAs map[string]string, hits map[string]int) *httptest.Server {
+	t.Helper()
+	contents := map[string]string{"a.md": "alpha", "b.md": "bravo"}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path]++
+		switch r.URL.Path {
+		case "/repos/owner/repo/contents/docs":
+			json.NewEncoder(w).Encode([]map[string]any{
+				{"type": "file", "path": "docs/a.md", "sha": treeSHAs["a.md"], "size": 5},
+			})
+		case "/repos/owner/repo/contents/":
+			json.NewEncoder(w).Encode([]map[string]any{
+				{"type": "dir", "path": "docs", "sha": "treeSHA", "size": 0},
+			})
+		case "/repos/owner/repo/git/trees/treeSHA":
+			json.NewEncoder(w).Encode(map[string]any{
+				"sha": "treeSHA",
+				"tree": []map[string]any{
+					{"path": "a.md", "mode": "100644", "type": "blob", "sha": treeSHAs["a.md"], "size": 5},
+					{"path": "b.md", "mode": "100644", "type": "blob", "sha": treeSHAs["b.md"], "size": 5},
+				},
+			})
+		case "/repos/owner/repo/contents/docs/a.md", "/repos/owner/repo/contents/docs/b.md":
+			name := filepath.Base(r.URL.Path)
+			json.NewEncoder(w).Encode(map[string]any{
+				"type": "file", "path": "docs/" + name, "sha": treeSHAs[name], "size": 5,
+				"content":  base64.StdEncoding.EncodeToString([]byte(contents[name])),
+				"encoding": "base64",
+			})
+		case "/graphql":
+			// Last-commit dates for a.md (f0) and b.md (f1).
+			w.Write([]byte(`{"data":{"repository":{"object":{
+				"f0":{"nodes":[{"oid":"c1","committedDate":"2020-05-06T07:08:09Z"}]},
+				"f1":{"nodes":[{"oid":"c2","committedDate":"2021-05-06T07:08:09Z"}]}
+			}}}}`))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(404)
+		}
+	}))
+}
+
+func TestDownloadWithOptions_Resume(t *testing.T) {
+	shas := map[string]string{"a.md": GitBlobSHA([]byte("alpha")), "b.md": GitBlobSHA([]byte("bravo"))}
+	hits := map[string]int{}
+	srv := newDocsServer(t, shas, hits)
+	defer srv.Close()
+
+	outPath := filepath.Join(t.TempDir(), "docs")
+	if err := os.MkdirAll(outPath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// Simulate an interrupted run: a.md finished, b.md left a temp file behind.
+	if err := os.WriteFile(filepath.Join(outPath, "a.md"), []byte("alpha"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stale := filepath.Join(outPath, ".b.md"+tempInfix+"123")
+	if err := os.WriteFile(stale, []byte("bra"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	journalData := `{"repo":"owner/repo","ref":"","path":"docs"}` + "\n" +
+		`{"path":"a.md","sha":"` + shas["a.md"] + `"}` + "\n" +
+		`{"path":"b.md","sh`
+	if err := os.WriteFile(journalPath(outPath), []byte(journalData), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := newTestService(srv.URL)
+	result, err := svc.DownloadWithOptions("", "docs", outPath, DownloadOptions{Resume: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if hits["/repos/owner/repo/contents/docs/a.md"] != 0 {
+		t.Errorf("a.md should not be fetched again")
+	}
+	if result.Resumed != 1 || result.Files != 1 || result.Verified != 2 {
+		t.Errorf("result: got %+v", result)
+	}
+	data, err := os.ReadFile(filepath.Join(outPath, "b.md"))
+	if err != nil || string(data) != "bravo" {
+		t.Errorf("b.md: got %q, %v", data, err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale temp file should be removed")
+	}
+	if _, err := os.Stat(journalPath(outPath)); !os.IsNotExist(err) {
+		t.Errorf("journal should be removed after success")
+	}
+}
+
+func TestDownloadWithOptions_VerifyMismatch(t *testing.T) {
+	shas := map[string]string{"a.md": GitBlobSHA([]byte("alpha")), "b.md": GitBlobSHA([]byte("other"))}
+	srv := newDocsServer(t, shas, map[string]int{})
+	defer srv.Close()
+
+	outPath := filepath.Join(t.TempDir(), "docs")
+	svc := newTestService(srv.URL)
+	_, err := svc.DownloadWithOptions("", "docs", outPath, DownloadOptions{})
+	if err == nil {
+		t.Fatal("expected verification error")
+	}
+	ce, ok := err.(*clerrors.CLIError)
+	if !ok {
+		t.Fatalf("expected CLIError, got %T", err)
+	}
+	if ce.ExitCode() != clerrors.ExitLocalWriteErr {
+		t.Errorf("exit code: got %d, want %d", ce.ExitCode(), clerrors.ExitLocalWriteErr)
+	}
+	// The journal is kept so the download can be resumed.
+	if _, err := os.Stat(journalPath(outPath)); err != nil {
+		t.Errorf("journal should be kept after failure: %v", err)
+	}
+}
+
+func TestGitBlobSHA(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{"", "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391"},
+		{"hello\n", "ce013625030ba8dba906f756967f9e9ca394464a"},
+	}
+	for _, tt := range tests {
+		if got := GitBlobSHA([]byte(tt.data)); got != tt.want {
+			t.Errorf("GitBlobSHA(%q): got %s, want %s", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadWithOptions_PreserveTimes(t *testing.T) {
+	shas := map[string]string{"a.md": GitBlobSHA([]byte("alpha")), "b.md": GitBlobSHA([]byte("bravo"))}
+	hits := map[string]int{}
+	srv := newDocsServer(t, shas, hits)
+	defer srv.Close()
+
+	outPath := filepath.Join(t.TempDir(), "docs")
+	svc := newTestService(srv.URL)
+	result, err := svc.DownloadWithOptions("", "docs", outPath, DownloadOptions{PreserveTimes: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hits["/graphql"] != 1 {
+		t.Errorf("expected one batched graphql call, got %d", hits["/graphql"])
+	}
+
+	info, err := os.Stat(filepath.Join(outPath, "a.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2020, 5, 6, 7, 8, 9, 0, time.UTC)
+	if !info.ModTime().Equal(want) {
+		t.Errorf("a.md mtime: got %v, want %v", info.ModTime(), want)
+	}
+	if e := result.Entries[1]; e.LastModified == nil || e.LastModified.Year() != 2021 {
+		t.Errorf("b.md last modified: got %v", e.LastModified)
+	}
+}
+
+func TestDownloadFileFromURL_UsesClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"Bad credentials"}`))
+			return
+		}
+		if r.URL.Path != "/raw/owner/repo/main/a.txt" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte("private content"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	out := filepath.Join(dir, "a.txt")
+	svc := NewRepoService(srv.URL, "tok", 5*time.Second, "owner", "repo")
+	if err := svc.DownloadFileFromURL(srv.URL+"/raw/owner/repo/main/a.txt", out, false); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(out); string(data) != "private content" {
+		t.Errorf("got %q", data)
+	}
+
+	err := NewRepoService(srv.URL, "wrong", 5*time.Second, "owner", "repo").DownloadFileFromURL(srv.URL+"/raw/owner/repo/main/a.txt", filepath.Join(dir, "b.txt"), false)
+	if ce, ok := err.(*clerrors.CLIError); !ok || ce.ExitCode() != clerrors.ExitAuthFailure {
+		t.Errorf("wrong token: got %v, want auth failure", err)
+	}
+
+	// The token must not be sent to another host.
+	err = svc.DownloadFileFromURL("https://example.com/a.txt", filepath.Join(dir, "c.txt"), false)
+	if ce, ok := err.(*clerrors.CLIError); !ok || ce.Cat != clerrors.CatBadArgs {
+		t.Errorf("foreign host: got %v, want bad args", err)
+	}
+}
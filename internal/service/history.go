@@ -0,0 +1,97 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	clerrors "githubRAGCli/internal/exitcode"
+	"githubRAGCli/internal/githubapi"
+)
+
+// LastCommits returns the last commit touching each path at ref. Paths are
+// looked up in GraphQL batches of githubapi.MaxHistoryBatch rather than one
+// REST request per path.
+func (s *RepoService) LastCommits(ref string, paths []string) (map[string]githubapi.CommitInfo, error) {
+	result := make(map[string]githubapi.CommitInfo, len(paths))
+	for start := 0; start < len(paths); start += githubapi.MaxHistoryBatch {
+		end := min(start+githubapi.MaxHistoryBatch, len(paths))
+		batch, err := s.Client.LastCommits(s.Owner, s.Repo, ref, paths[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for p, c := range batch {
+			result[p] = c
+		}
+	}
+	return result, nil
+}
+
+// LastCommit returns the last commit touching path at ref.
+func (s *RepoService) LastCommit(ref, path string) (*githubapi.CommitInfo, error) {
+	if path == "." || path == "/" {
+		path = ""
+	}
+	commits, err := s.LastCommits(ref, []string{path})
+	if err != nil {
+		return nil, err
+	}
+	c, ok := commits[path]
+	if !ok {
+		return nil, clerrors.NewNotFound(fmt.Sprintf("no commit history for %q", path), nil)
+	}
+	return &c, nil
+}
+
+// pendingTime is a downloaded file whose last-commit date is still unknown.
+type pendingTime struct {
+	svc        *RepoService
+	ref        string
+	remotePath string // path within svc's repository
+	entry      int    // index into DownloadResult.Entries
+}
+
+// applyTimes looks up the last commit date of every downloaded file, records
+// it in the result entries and, with PreserveTimes, sets it as the local mtime.
+func (d *downloader) applyTimes() error {
+	type repoRef struct {
+		svc *RepoService
+		ref string
+	}
+	groups := make(map[repoRef][]pendingTime)
+	var order []repoRef
+	for _, p := range d.pendingTimes {
+		k := repoRef{p.svc, p.ref}
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], p)
+	}
+
+	for _, k := range order {
+		pending := groups[k]
+		paths := make([]string, 0, len(pending))
+		for _, p := range pending {
+			paths = append(paths, p.remotePath)
+		}
+		commits, err := k.svc.LastCommits(k.ref, paths)
+		if err != nil {
+			return err
+		}
+		for _, p := range pending {
+			c, ok := commits[p.remotePath]
+			if !ok {
+				continue
+			}
+			date := c.Date.UTC()
+			e := &d.result.Entries[p.entry]
+			e.LastModified = &date
+			if d.opts.PreserveTimes {
+				if err := os.Chtimes(e.LocalPath, time.Time{}, date); err != nil {
+					return clerrors.NewLocalWriteErr("failed to set modification time: "+e.LocalPath, err)
+				}
+			}
+		}
+	}
+	return nil
+}
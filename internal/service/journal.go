@@ -0,0 +1,227 @@
+package service
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+// journalSuffix is appended to the --out path to name the download journal.
+const journalSuffix = ".ghrepo-journal"
+
+// tempInfix marks in-flight temporary files written next to their final path.
+const tempInfix = ".ghrepo-tmp-"
+
+// journalHeader is the first line of a journal and identifies the download.
+type journalHeader struct {
+	Repo string `json:"repo"`
+	Ref  string `json:"ref"`
+	Path string `json:"path"`
+}
+
+// journalRecord marks one file as completely written.
+type journalRecord struct {
+	Path string `json:"path"` // local path relative to the output root
+	SHA  string `json:"sha"`
+}
+
+// journal is an append-only JSON-lines log of completed files. Each line is
+// written once the file has been renamed into place, so a crash can at worst
+// lose the last record, which resume then redoes.
+type journal struct {
+	path string
+	f    *os.File
+	done map[string]string
+}
+
+// journalPath returns the journal location for a download to outPath.
+func journalPath(outPath string) string {
+	return filepath.Clean(outPath) + journalSuffix
+}
+
+// openJournal opens the journal for a download. When resume is set and an
+// existing journal matches hdr, its records are loaded; otherwise the journal
+// is started afresh.
+func openJournal(outPath string, hdr journalHeader, resume bool) (*journal, error) {
+	j := &journal{path: journalPath(outPath), done: make(map[string]string)}
+
+	if resume {
+		if err := j.load(hdr); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o755); err != nil {
+		return nil, clerrors.NewLocalWriteErr("failed to create directory: "+filepath.Dir(j.path), err)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if len(j.done) == 0 {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(j.path, flags, 0o644)
+	if err != nil {
+		return nil, clerrors.NewLocalWriteErr("failed to open journal: "+j.path, err)
+	}
+	j.f = f
+
+	if len(j.done) == 0 {
+		if err := j.writeLine(hdr); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return j, nil
+}
+
+// load reads a previous journal. A missing journal or one describing a
+// different download leaves j empty.
+func (j *journal) load(hdr journalHeader) error {
+	f, err := os.Open(j.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return clerrors.NewLocalWriteErr("failed to read journal: "+j.path, err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	if !sc.Scan() {
+		return nil
+	}
+	var got journalHeader
+	if err := json.Unmarshal(sc.Bytes(), &got); err != nil || got != hdr {
+		slog.Warn("journal belongs to a different download, starting over", "journal", j.path)
+		return nil
+	}
+	for sc.Scan() {
+		var rec journalRecord
+		// A torn final line from a crash is simply ignored.
+		if err := json.Unmarshal(sc.Bytes(), &rec); err == nil && rec.Path != "" {
+			j.done[rec.Path] = rec.SHA
+		}
+	}
+	return nil
+}
+
+// completed reports whether relPath was recorded with the given blob SHA.
+func (j *journal) completed(relPath, sha string) bool {
+	got, ok := j.done[relPath]
+	return ok && got == sha
+}
+
+// record appends a completion record for relPath.
+func (j *journal) record(relPath, sha string) error {
+	j.done[relPath] = sha
+	return j.writeLine(journalRecord{Path: relPath, SHA: sha})
+}
+
+func (j *journal) writeLine(v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return clerrors.NewLocalWriteErr("failed to encode journal record", err)
+	}
+	if _, err := j.f.Write(append(line, '\n')); err != nil {
+		return clerrors.NewLocalWriteErr("failed to write journal: "+j.path, err)
+	}
+	return nil
+}
+
+// close closes the journal file, removing it when the download succeeded.
+func (j *journal) close(success bool) {
+	j.f.Close()
+	if success {
+		os.Remove(j.path)
+	}
+}
+
+// writeFileAtomic writes data to a temporary file in the destination
+// directory and renames it into place, so outPath is never left truncated.
+func writeFileAtomic(outPath string, data []byte, perm os.FileMode) error {
+	return writeAtomic(outPath, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// writeAtomic streams content produced by fill into outPath via a temporary
+// file that is synced and renamed once complete.
+func writeAtomic(outPath string, perm os.FileMode, fill func(io.Writer) error) error {
+	dir := filepath.Dir(outPath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return clerrors.NewLocalWriteErr("failed to create directory: "+dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(outPath)+tempInfix+"*")
+	if err != nil {
+		return clerrors.NewLocalWriteErr("failed to create file: "+outPath, err)
+	}
+	tmpPath := tmp.Name()
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmpPath)
+	}
+
+	if err := fill(tmp); err != nil {
+		cleanup()
+		return clerrors.NewLocalWriteErr("failed to write file: "+outPath, err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		cleanup()
+		return clerrors.NewLocalWriteErr("failed to set file mode: "+outPath, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		cleanup()
+		return clerrors.NewLocalWriteErr("failed to write file: "+outPath, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return clerrors.NewLocalWriteErr("failed to write file: "+outPath, err)
+	}
+	if err := os.Rename(tmpPath, outPath); err != nil {
+		os.Remove(tmpPath)
+		return clerrors.NewLocalWriteErr("failed to write file: "+outPath, err)
+	}
+	return nil
+}
+
+// removeStaleTemps deletes temporary files left behind by an interrupted
+// download under root.
+func removeStaleTemps(root string) {
+	filepath.WalkDir(root, func(p string, de os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !de.IsDir() && strings.Contains(de.Name(), tempInfix) {
+			os.Remove(p)
+		}
+		return nil
+	})
+}
+
+// GitBlobSHA returns the git object ID of data stored as a blob.
+func GitBlobSHA(data []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(data))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashLocalFile returns the git blob SHA of the file at p.
+func hashLocalFile(p string) (string, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return "", err
+	}
+	return GitBlobSHA(data), nil
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+// ManifestFile describes one downloaded file or symlink.
+type ManifestFile struct {
+	Type         string     `json:"type"` // "file" or "symlink"
+	RemotePath   string     `json:"remote_path"`
+	LocalPath    string     `json:"local_path"` // relative to the manifest's directory
+	SHA          string     `json:"sha"`        // git blob SHA
+	Size         int64      `json:"size"`
+	Mode         string     `json:"mode"`
+	Target       string     `json:"target,omitempty"`        // symlink target as written locally
+	LastModified *time.Time `json:"last_modified,omitempty"` // date of the last commit touching the file
+}
+
+// Manifest records the provenance of a download. Only finished downloads
+// get one: a walk that could not list the whole tree fails instead. Entries
+// the download left out on purpose are listed in Skipped, and Incomplete
+// is then set, so that the manifest never claims more than was written.
+type Manifest struct {
+	Repository string         `json:"repository"`
+	Ref        string         `json:"ref,omitempty"`
+	Commit     string         `json:"commit"`
+	Path       string         `json:"path"`
+	FetchedAt  time.Time      `json:"fetched_at"`
+	Incomplete bool           `json:"incomplete,omitempty"`
+	Files      []ManifestFile `json:"files"`
+	Skipped    []string       `json:"skipped,omitempty"`
+	Submodules []SubmoduleRef `json:"submodules,omitempty"`
+}
+
+// Drift describes a local file that no longer matches its manifest entry.
+type Drift struct {
+	Path     string `json:"path"`
+	Status   string `json:"status"` // "missing", "modified", "mode_changed", "type_changed"
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+// ResolveCommit resolves ref (empty for the default branch) to a commit SHA.
+func (s *RepoService) ResolveCommit(ref string) (string, error) {
+	commit, err := s.Client.GetCommit(s.Owner, s.Repo, ref)
+	if err != nil {
+		return "", err
+	}
+	return commit.SHA, nil
+}
+
+// NewManifest builds a manifest for a finished download. Local paths in the
+// result are rewritten relative to the directory that will hold the manifest.
+func NewManifest(repository, ref, commit, remotePath, manifestPath string, result *DownloadResult) *Manifest {
+	base := manifestBaseDir(manifestPath)
+	files := make([]ManifestFile, 0, len(result.Entries))
+	for _, f := range result.Entries {
+		f.LocalPath = relativeTo(base, f.LocalPath)
+		files = append(files, f)
+	}
+	return &Manifest{
+		Repository: repository,
+		Ref:        ref,
+		Commit:     commit,
+		Path:       remotePath,
+		FetchedAt:  time.Now().UTC().Truncate(time.Second),
+		Incomplete: len(result.Skipped) > 0,
+		Files:      files,
+		Skipped:    result.Skipped,
+		Submodules: result.Submodules,
+	}
+}
+
+// WriteManifest writes m as indented JSON to manifestPath.
+func WriteManifest(manifestPath string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return clerrors.NewLocalWriteErr("failed to encode manifest", err)
+	}
+	return writeFileAtomic(manifestPath, append(data, '\n'), 0o644)
+}
+
+// LoadManifest reads a manifest written by WriteManifest.
+func LoadManifest(manifestPath string) (*Manifest, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, clerrors.NewBadArgs(fmt.Sprintf("failed to read manifest %q", manifestPath), err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, clerrors.NewBadArgs(fmt.Sprintf("invalid manifest %q", manifestPath), err)
+	}
+	return &m, nil
+}
+
+// VerifyManifest re-hashes the local files listed in m and returns every
+// entry that drifted. Relative local paths resolve against the manifest's
+// directory.
+func VerifyManifest(manifestPath string, m *Manifest) ([]Drift, error) {
+	base := manifestBaseDir(manifestPath)
+	var drift []Drift
+	for _, f := range m.Files {
+		p := f.LocalPath
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(base, filepath.FromSlash(p))
+		}
+
+		info, err := os.Lstat(p)
+		if os.IsNotExist(err) {
+			drift = append(drift, Drift{Path: f.LocalPath, Status: "missing"})
+			continue
+		}
+		if err != nil {
+			return nil, clerrors.NewLocalWriteErr("failed to stat "+p, err)
+		}
+
+		if f.Type == "symlink" {
+			if info.Mode()&os.ModeSymlink == 0 {
+				drift = append(drift, Drift{Path: f.LocalPath, Status: "type_changed", Expected: "symlink", Actual: "file"})
+				continue
+			}
+			target, err := os.Readlink(p)
+			if err != nil {
+				return nil, clerrors.NewLocalWriteErr("failed to read symlink "+p, err)
+			}
+			if target != f.Target {
+				drift = append(drift, Drift{Path: f.LocalPath, Status: "modified", Expected: f.Target, Actual: target})
+			}
+			continue
+		}
+
+		if !info.Mode().IsRegular() {
+			drift = append(drift, Drift{Path: f.LocalPath, Status: "type_changed", Expected: "file", Actual: info.Mode().Type().String()})
+			continue
+		}
+		sha, err := hashLocalFile(p)
+		if err != nil {
+			return nil, clerrors.NewLocalWriteErr("failed to read "+p, err)
+		}
+		if sha != f.SHA {
+			drift = append(drift, Drift{Path: f.LocalPath, Status: "modified", Expected: f.SHA, Actual: sha})
+			continue
+		}
+		if want := fileModePerm(f.Mode); info.Mode().Perm()&0o111 != want&0o111 {
+			drift = append(drift, Drift{Path: f.LocalPath, Status: "mode_changed", Expected: f.Mode, Actual: info.Mode().Perm().String()})
+		}
+	}
+	return drift, nil
+}
+
+// manifestBaseDir returns the absolute directory containing manifestPath.
+func manifestBaseDir(manifestPath string) string {
+	dir := filepath.Dir(manifestPath)
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
+
+// relativeTo expresses p relative to base in slash form, falling back to an
+// absolute path when no relative form exists.
+func relativeTo(base, p string) string {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return filepath.ToSlash(p)
+	}
+	rel, err := filepath.Rel(base, abs)
+	if err != nil {
+		return filepath.ToSlash(abs)
+	}
+	return filepath.ToSlash(rel)
+}
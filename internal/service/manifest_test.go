@@ -0,0 +1,108 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManifest_RoundTripAndVerify(t *testing.T) {
+	dir := t.TempDir()
+	outDir := filepath.Join(dir, "out")
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{"a.md": "alpha", "b.md": "bravo", "c.md": "charlie"}
+	result := &DownloadResult{}
+	for name, content := range files {
+		p := filepath.Join(outDir, name)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		result.Entries = append(result.Entries, ManifestFile{
+			Type:       "file",
+			RemotePath: "docs/" + name,
+			LocalPath:  p,
+			SHA:        GitBlobSHA([]byte(content)),
+			Size:       int64(len(content)),
+			Mode:       "100644",
+		})
+	}
+
+	manifestPath := filepath.Join(dir, "manifest.json")
+	m := NewManifest("owner/repo", "main", "c0ffee", "docs", manifestPath, result)
+	if err := WriteManifest(manifestPath, m); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+
+	loaded, err := LoadManifest(manifestPath)
+	if err != nil {
+		t.Fatalf("load manifest: %v", err)
+	}
+	if loaded.Repository != "owner/repo" || loaded.Commit != "c0ffee" || len(loaded.Files) != 3 {
+		t.Fatalf("unexpected manifest: %+v", loaded)
+	}
+	for _, f := range loaded.Files {
+		if filepath.IsAbs(f.LocalPath) || filepath.Dir(f.LocalPath) != "out" {
+			t.Errorf("local path should be relative to the manifest: %q", f.LocalPath)
+		}
+	}
+
+	drift, err := VerifyManifest(manifestPath, loaded)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if len(drift) != 0 {
+		t.Fatalf("expected no drift, got %+v", drift)
+	}
+
+	if err := os.WriteFile(filepath.Join(outDir, "a.md"), []byte("changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(outDir, "b.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	drift, err = VerifyManifest(manifestPath, loaded)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	statuses := map[string]string{}
+	for _, d := range drift {
+		statuses[d.Path] = d.Status
+	}
+	if statuses["out/a.md"] != "modified" || statuses["out/b.md"] != "missing" || len(drift) != 2 {
+		t.Errorf("unexpected drift: %+v", drift)
+	}
+}
+
+func TestManifest_Incomplete(t *testing.T) {
+	dir := t.TempDir()
+	manifestPath := filepath.Join(dir, "manifest.json")
+	complete := NewManifest("owner/repo", "main", "c0ffee", "pkg", manifestPath, &DownloadResult{})
+	if complete.Incomplete {
+		t.Error("download without skipped entries marked incomplete")
+	}
+
+	result := &DownloadResult{Skipped: []string{"pkg/evil"}}
+	if err := WriteManifest(manifestPath, NewManifest("owner/repo", "main", "c0ffee", "pkg", manifestPath, result)); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadManifest(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Incomplete || len(loaded.Skipped) != 1 || loaded.Skipped[0] != "pkg/evil" {
+		t.Errorf("got incomplete=%v skipped=%v", loaded.Incomplete, loaded.Skipped)
+	}
+}
+
+func TestLoadManifest_Invalid(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "manifest.json")
+	if err := os.WriteFile(p, []byte("not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadManifest(p); err == nil {
+		t.Fatal("expected error for invalid manifest")
+	}
+}
@@ -4,9 +4,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
+	"log/slog"
 	"path/filepath"
 	"strings"
 	"time"
@@ -17,22 +15,37 @@ import (
 
 // Entry is the unified model for repository content metadata.
 type Entry struct {
-	Type        string `json:"type"`
-	Path        string `json:"path"`
-	SHA         string `json:"sha"`
-	Size        int64  `json:"size"`
-	DownloadURL string `json:"download_url,omitempty"`
+	Type         string     `json:"type"`
+	Path         string     `json:"path"`
+	SHA          string     `json:"sha"`
+	Size         int64      `json:"size"`
+	Mode         string     `json:"mode,omitempty"` // git file mode, set when listed via the Trees API
+	DownloadURL  string     `json:"download_url,omitempty"`
+	LastModified *time.Time `json:"last_modified,omitempty"` // set when requested by the caller
+	Target       string     `json:"target,omitempty"`        // symlink target
+	SubmoduleURL string     `json:"submodule_url,omitempty"` // submodule remote; SHA is the pinned commit
+
+	// Directory metadata, populated by Stat (and by SumDirSizes for
+	// recursive listings, which leave Children unset).
+	Children  int   `json:"children,omitempty"`   // immediate entries
+	FileCount int   `json:"file_count,omitempty"` // files in the whole subtree
+	TotalSize int64 `json:"total_size,omitempty"` // bytes in the whole subtree
+
+	LastCommit *githubapi.CommitInfo `json:"last_commit,omitempty"`
+	Permalink  string                `json:"permalink,omitempty"`
 }
 
 // contentsItem maps the JSON returned by the GitHub Contents API.
 type contentsItem struct {
-	Type        string `json:"type"` // "file", "dir", "symlink", "submodule"
-	Path        string `json:"path"`
-	SHA         string `json:"sha"`
-	Size        int64  `json:"size"`
-	DownloadURL string `json:"download_url"`
-	Content     string `json:"content"`
-	Encoding    string `json:"encoding"`
+	Type         string `json:"type"` // "file", "dir", "symlink", "submodule"
+	Path         string `json:"path"`
+	SHA          string `json:"sha"`
+	Size         int64  `json:"size"`
+	DownloadURL  string `json:"download_url"`
+	Content      string `json:"content"`
+	Encoding     string `json:"encoding"`
+	Target       string `json:"target"`            // symlink target
+	SubmoduleURL string `json:"submodule_git_url"` // submodule remote
 }
 
 // RepoService provides business logic for repository content operations.
@@ -67,26 +80,88 @@ func (s *RepoService) Stat(ref, path string) (*Entry, error) {
 	}
 
 	// It might be a directory — API returns an array.
-	// For stat on a directory, we need to call the API with trailing slash behavior.
-	// The Contents API on a dir path returns the children; we derive dir metadata.
+	// The Contents API on a dir path returns the children; the tree SHA and
+	// subtree totals come from the Trees API.
 	var items []contentsItem
 	if err := json.Unmarshal(raw, &items); err == nil {
-		return &Entry{
-			Type: "dir",
-			Path: path,
-		}, nil
+		return s.statDir(ref, path, len(items))
 	}
 
 	return nil, clerrors.NewTransport("unexpected contents response format", nil)
 }
 
+// statDir builds directory metadata: the real tree SHA, the number of
+// immediate children, and the file count and total size of the subtree.
+func (s *RepoService) statDir(ref, path string, children int) (*Entry, error) {
+	dirSHA, err := s.getDirSHA(ref, path)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Entry{
+		Type:     "dir",
+		Path:     path,
+		SHA:      dirSHA,
+		Children: children,
+	}
+	err = s.walkTreeSHA(dirSHA, func(te githubapi.TreeEntry) {
+		if te.Type == "blob" {
+			e.FileCount++
+			e.TotalSize += te.Size
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// Permalink returns a web URL for e pinned to the commit ref resolves to.
+func (s *RepoService) Permalink(ref string, e *Entry) (string, error) {
+	commit, err := s.ResolveCommit(ref)
+	if err != nil {
+		return "", err
+	}
+	kind := "blob"
+	if e.Type == "dir" {
+		kind = "tree"
+	}
+	url := fmt.Sprintf("%s/%s/%s/%s/%s", s.Client.WebURL(), githubapi.EscapePath(s.Owner), githubapi.EscapePath(s.Repo), kind, commit)
+	if p := strings.Trim(e.Path, "/"); p != "" && p != "." {
+		url += "/" + githubapi.EscapePath(p)
+	}
+	return url, nil
+}
+
 // List returns directory entries. Non-recursive uses the Contents API;
-// recursive uses the Trees API.
+// recursive uses the Trees API. Symlink targets are left out; see
+// AnnotateLinks.
 func (s *RepoService) List(ref, path string, recursive bool) ([]Entry, error) {
-	if !recursive {
-		return s.listFlat(ref, path)
+	entries := []Entry{}
+	err := s.ListEach(ref, path, recursive, func(e Entry) {
+		entries = append(entries, e)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ListEach is List passing each entry to fn as it is produced, so callers
+// can print results as they arrive.
+func (s *RepoService) ListEach(ref, path string, recursive bool, fn func(Entry)) error {
+	if recursive {
+		return s.walkTree(ref, path, fn)
 	}
-	return s.listRecursive(ref, path)
+	entries, err := s.listFlat(ref, path)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		fn(e)
+	}
+	return nil
 }
 
 func (s *RepoService) listFlat(ref, path string) ([]Entry, error) {
@@ -114,16 +189,29 @@ func (s *RepoService) listFlat(ref, path string) ([]Entry, error) {
 }
 
 func (s *RepoService) listRecursive(ref, path string) ([]Entry, error) {
+	entries := []Entry{}
+	err := s.walkTree(ref, path, func(e Entry) {
+		entries = append(entries, e)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// walkTree passes every entry below the directory path to fn, using the
+// Trees API.
+func (s *RepoService) walkTree(ref, path string, fn func(Entry)) error {
 	// First, get the directory SHA via Contents API.
 	raw, err := s.Client.GetContents(s.Owner, s.Repo, path, ref)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// Check if it's a single file (not listable).
 	var single contentsItem
 	if err := json.Unmarshal(raw, &single); err == nil && single.SHA != "" && single.Type != "dir" {
-		return nil, clerrors.NewBadArgs(fmt.Sprintf("path %q is a file, not a directory", path), nil)
+		return clerrors.NewBadArgs(fmt.Sprintf("path %q is a file, not a directory", path), nil)
 	}
 
 	// For a directory, we need its tree SHA. The Contents API for a dir returns children
@@ -132,30 +220,105 @@ func (s *RepoService) listRecursive(ref, path string) ([]Entry, error) {
 	// We'll call GetContents on the parent to find this dir's SHA, or if path is root, use ref.
 	dirSHA, err := s.getDirSHA(ref, path)
 	if err != nil {
-		return nil, err
-	}
-
-	tree, err := s.Client.GetTree(s.Owner, s.Repo, dirSHA, true)
-	if err != nil {
-		return nil, err
+		return err
 	}
 
-	entries := make([]Entry, 0, len(tree.Tree))
-	for _, te := range tree.Tree {
-		e := Entry{
-			Type: treeTypeToEntryType(te.Type),
+	return s.walkTreeSHA(dirSHA, func(te githubapi.TreeEntry) {
+		fn(Entry{
+			Type: treeEntryType(te),
 			Path: joinPath(path, te.Path),
 			SHA:  te.SHA,
 			Size: te.Size,
+			Mode: te.Mode,
+		})
+	})
+}
+
+// walkTreeSHA passes every entry below the tree sha to fn, with paths
+// relative to it, parents before their contents. When GitHub truncates the
+// recursive listing, the tree is walked again one directory at a time so
+// that no entry is missed.
+func (s *RepoService) walkTreeSHA(sha string, fn func(githubapi.TreeEntry)) error {
+	tree, err := s.Client.GetTree(s.Owner, s.Repo, sha, true)
+	if err != nil {
+		return err
+	}
+	if !tree.Truncated {
+		for _, te := range tree.Tree {
+			fn(te)
 		}
-		entries = append(entries, e)
+		return nil
 	}
+	slog.Info("recursive tree listing truncated; listing one directory at a time", "repo", s.Owner+"/"+s.Repo, "tree", sha)
+	return s.walkSubtrees(sha, "", fn)
+}
 
+// walkSubtrees is walkTreeSHA with one non-recursive Trees API call per
+// directory. A directory too large even for that is an error, as a partial
+// listing would pass for a complete one.
+func (s *RepoService) walkSubtrees(sha, prefix string, fn func(githubapi.TreeEntry)) error {
+	tree, err := s.Client.GetTree(s.Owner, s.Repo, sha, false)
+	if err != nil {
+		return err
+	}
 	if tree.Truncated {
-		fmt.Fprintf(os.Stderr, "warning: tree listing was truncated by GitHub API\n")
+		return clerrors.NewServer(fmt.Sprintf("directory %q of %s/%s has too many entries for the Trees API", prefix, s.Owner, s.Repo), nil)
 	}
+	for _, te := range tree.Tree {
+		te.Path = joinPath(prefix, te.Path)
+		fn(te)
+		if te.Type == "tree" {
+			if err := s.walkSubtrees(te.SHA, te.Path, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
-	return entries, nil
+// FillModes sets the git file mode of entries listed non-recursively from
+// path, which the Contents API does not report. It costs one Trees API call.
+func (s *RepoService) FillModes(ref, path string, entries []Entry) error {
+	dirSHA, err := s.getDirSHA(ref, path)
+	if err != nil {
+		return err
+	}
+	tree, err := s.Client.GetTree(s.Owner, s.Repo, dirSHA, false)
+	if err != nil {
+		return err
+	}
+	modes := make(map[string]string, len(tree.Tree))
+	for _, te := range tree.Tree {
+		modes[joinPath(path, te.Path)] = te.Mode
+	}
+	for i := range entries {
+		if m, ok := modes[entries[i].Path]; ok {
+			entries[i].Mode = m
+		}
+	}
+	return nil
+}
+
+// SumDirSizes fills FileCount and TotalSize for every directory in a
+// recursive listing from the blobs below it.
+func SumDirSizes(entries []Entry) {
+	dirs := make(map[string]*Entry)
+	for i := range entries {
+		if entries[i].Type == "dir" {
+			dirs[entries[i].Path] = &entries[i]
+		}
+	}
+	for _, e := range entries {
+		if e.Type != "file" && e.Type != "symlink" {
+			continue
+		}
+		for p := filepath.ToSlash(filepath.Dir(e.Path)); p != "." && p != "/"; p = filepath.ToSlash(filepath.Dir(p)) {
+			if d, ok := dirs[p]; ok {
+				d.FileCount++
+				d.TotalSize += e.Size
+			}
+		}
+	}
 }
 
 // getDirSHA resolves the git tree SHA for a directory path.
@@ -207,6 +370,9 @@ func (s *RepoService) ReadFile(ref, path string) ([]byte, error) {
 		return nil, clerrors.NewTransport("unexpected contents response format", err)
 	}
 
+	if item.Type == "symlink" {
+		return nil, clerrors.NewBadArgs(fmt.Sprintf("path %q is a symlink to %q (use --follow)", path, item.Target), nil)
+	}
 	if item.Type != "file" {
 		return nil, clerrors.NewBadArgs(fmt.Sprintf("path %q is a %s, not a file", path, item.Type), nil)
 	}
@@ -225,129 +391,39 @@ func (s *RepoService) ReadFile(ref, path string) ([]byte, error) {
 	return data, nil
 }
 
-// Download writes repository content to the local filesystem.
-// For a file, it writes the decoded content to outPath.
-// For a directory, it recursively lists and downloads all files.
-func (s *RepoService) Download(ref, remotePath, outPath string, overwrite bool) error {
-	// Determine if the path is a file or directory.
-	raw, err := s.Client.GetContents(s.Owner, s.Repo, remotePath, ref)
-	if err != nil {
-		return err
-	}
-
-	var item contentsItem
-	if err := json.Unmarshal(raw, &item); err == nil && item.SHA != "" && item.Type == "file" {
-		return s.downloadFile(ref, remotePath, outPath, overwrite)
-	}
-
-	// Directory download.
-	return s.downloadDir(ref, remotePath, outPath, overwrite)
-}
-
-func (s *RepoService) downloadFile(ref, remotePath, outPath string, overwrite bool) error {
-	if !overwrite {
-		if _, err := os.Stat(outPath); err == nil {
-			return clerrors.NewLocalWriteErr(fmt.Sprintf("file already exists: %s (use --overwrite to replace)", outPath), nil)
-		}
-	}
-
-	data, err := s.ReadFile(ref, remotePath)
-	if err != nil {
-		return err
-	}
-
-	dir := filepath.Dir(outPath)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return clerrors.NewLocalWriteErr("failed to create directory: "+dir, err)
-	}
-
-	if err := os.WriteFile(outPath, data, 0o644); err != nil {
-		return clerrors.NewLocalWriteErr("failed to write file: "+outPath, err)
-	}
-
-	return nil
-}
-
-func (s *RepoService) downloadDir(ref, remotePath, outPath string, overwrite bool) error {
-	entries, err := s.List(ref, remotePath, true)
-	if err != nil {
-		return err
-	}
-
-	for _, entry := range entries {
-		if entry.Type != "file" {
-			continue
-		}
-
-		// Compute relative path within the downloaded directory.
-		relPath := entry.Path
-		if remotePath != "" && remotePath != "." && remotePath != "/" {
-			relPath = strings.TrimPrefix(entry.Path, remotePath+"/")
-		}
-
-		localPath := filepath.Join(outPath, relPath)
-		if err := s.downloadFile(ref, entry.Path, localPath, overwrite); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-// DownloadFileFromURL downloads a file from a raw URL (e.g., download_url).
-func (s *RepoService) DownloadFileFromURL(url, outPath string, overwrite bool) error {
-	if !overwrite {
-		if _, err := os.Stat(outPath); err == nil {
-			return clerrors.NewLocalWriteErr(fmt.Sprintf("file already exists: %s (use --overwrite to replace)", outPath), nil)
-		}
-	}
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return clerrors.NewTransport("failed to download file", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return clerrors.NewTransport(fmt.Sprintf("download failed with HTTP %d", resp.StatusCode), nil)
-	}
-
-	dir := filepath.Dir(outPath)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return clerrors.NewLocalWriteErr("failed to create directory: "+dir, err)
-	}
-
-	f, err := os.Create(outPath)
-	if err != nil {
-		return clerrors.NewLocalWriteErr("failed to create file: "+outPath, err)
-	}
-	defer f.Close()
-
-	if _, err := io.Copy(f, resp.Body); err != nil {
-		return clerrors.NewLocalWriteErr("failed to write file: "+outPath, err)
-	}
-
-	return nil
-}
-
 func itemToEntry(item *contentsItem) *Entry {
 	return &Entry{
-		Type:        item.Type,
-		Path:        item.Path,
-		SHA:         item.SHA,
-		Size:        item.Size,
-		DownloadURL: item.DownloadURL,
+		Type:         item.Type,
+		Path:         item.Path,
+		SHA:          item.SHA,
+		Size:         item.Size,
+		DownloadURL:  item.DownloadURL,
+		Target:       item.Target,
+		SubmoduleURL: item.SubmoduleURL,
 	}
 }
 
-func treeTypeToEntryType(t string) string {
-	switch t {
-	case "blob":
+// Git tree modes that need special handling on download.
+const (
+	modeExecutable = "100755"
+	modeSymlink    = "120000"
+	modeSubmodule  = "160000"
+)
+
+// treeEntryType maps a Git tree entry onto the Contents API type names,
+// using the mode to tell symlinks apart from regular blobs.
+func treeEntryType(te githubapi.TreeEntry) string {
+	switch {
+	case te.Mode == modeSymlink:
+		return "symlink"
+	case te.Type == "commit" || te.Mode == modeSubmodule:
+		return "submodule"
+	case te.Type == "blob":
 		return "file"
-	case "tree":
+	case te.Type == "tree":
 		return "dir"
 	default:
-		return t
+		return te.Type
 	}
 }
 
@@ -8,6 +8,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -57,11 +58,30 @@ func TestStat_File(t *testing.T) {
 
 func TestStat_Dir(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Contents API returns array for directories.
-		json.NewEncoder(w).Encode([]map[string]any{
-			{"type": "file", "path": "docs/a.md", "sha": "aaa", "size": 10},
-			{"type": "file", "path": "docs/b.md", "sha": "bbb", "size": 20},
-		})
+		switch r.URL.Path {
+		case "/repos/owner/repo/contents/docs":
+			// Contents API returns array for directories.
+			json.NewEncoder(w).Encode([]map[string]any{
+				{"type": "file", "path": "docs/a.md", "sha": "aaa", "size": 10},
+				{"type": "dir", "path": "docs/sub", "sha": "sss", "size": 0},
+			})
+		case "/repos/owner/repo/contents/":
+			json.NewEncoder(w).Encode([]map[string]any{
+				{"type": "dir", "path": "docs", "sha": "treeSHA", "size": 0},
+			})
+		case "/repos/owner/repo/git/trees/treeSHA":
+			json.NewEncoder(w).Encode(map[string]any{
+				"sha": "treeSHA",
+				"tree": []map[string]any{
+					{"path": "a.md", "type": "blob", "sha": "aaa", "size": 10},
+					{"path": "sub", "type": "tree", "sha": "sss"},
+					{"path": "sub/b.md", "type": "blob", "sha": "bbb", "size": 20},
+				},
+			})
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(404)
+		}
 	}))
 	defer srv.Close()
 
@@ -73,6 +93,41 @@ func TestStat_Dir(t *testing.T) {
 	if entry.Type != "dir" {
 		t.Errorf("type: got %q, want dir", entry.Type)
 	}
+	if entry.SHA != "treeSHA" {
+		t.Errorf("sha: got %q, want treeSHA", entry.SHA)
+	}
+	if entry.Children != 2 || entry.FileCount != 2 || entry.TotalSize != 30 {
+		t.Errorf("dir metadata: got children=%d files=%d size=%d", entry.Children, entry.FileCount, entry.TotalSize)
+	}
+}
+
+func TestPermalink(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/commits/main" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"sha": "c0ffee"})
+	}))
+	defer srv.Close()
+
+	svc := newTestService(srv.URL)
+	got, err := svc.Permalink("main", &Entry{Type: "file", Path: "docs/a.md"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := srv.URL + "/owner/repo/blob/c0ffee/docs/a.md"
+	if got != want {
+		t.Errorf("permalink: got %q, want %q", got, want)
+	}
+
+	got, err = svc.Permalink("main", &Entry{Type: "file", Path: "docs/a b#1?%.md"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want = srv.URL + "/owner/repo/blob/c0ffee/docs/a%20b%231%3F%25.md"
+	if got != want {
+		t.Errorf("permalink: got %q, want %q", got, want)
+	}
 }
 
 func TestStat_NotFound(t *testing.T) {
@@ -169,6 +224,165 @@ func TestList_Recursive(t *testing.T) {
 	}
 }
 
+// newTruncatedTreeServer serves docs/{a.md,sub/b.md}, whose recursive tree
+// listing GitHub truncates after a.md. With bigSub the sub directory is
+// truncated even when listed on its own.
+func newTruncatedTreeServer(t *testing.T, bigSub bool) *httptest.Server {
+	t.Helper()
+	content := map[string]string{"docs/a.md": "hello", "docs/sub/b.md": "world!"}
+	sha := func(p string) string { return GitBlobSHA([]byte(content[p])) }
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recursive := r.URL.Query().Get("recursive") != ""
+		switch {
+		case r.URL.Path == "/repos/owner/repo/contents/docs":
+			json.NewEncoder(w).Encode([]map[string]any{
+				{"type": "file", "path": "docs/a.md", "sha": sha("docs/a.md"), "size": 5},
+				{"type": "dir", "path": "docs/sub", "sha": "subSHA", "size": 0},
+			})
+		case r.URL.Path == "/repos/owner/repo/contents/":
+			json.NewEncoder(w).Encode([]map[string]any{
+				{"type": "dir", "path": "docs", "sha": "treeSHA", "size": 0},
+			})
+		case r.URL.Path == "/repos/owner/repo/git/trees/treeSHA" && recursive:
+			json.NewEncoder(w).Encode(map[string]any{
+				"sha": "treeSHA",
+				"tree": []map[string]any{
+					{"path": "a.md", "mode": "100644", "type": "blob", "sha": sha("docs/a.md"), "size": 5},
+				},
+				"truncated": true,
+			})
+		case r.URL.Path == "/repos/owner/repo/git/trees/treeSHA":
+			json.NewEncoder(w).Encode(map[string]any{
+				"sha": "treeSHA",
+				"tree": []map[string]any{
+					{"path": "a.md", "mode": "100644", "type": "blob", "sha": sha("docs/a.md"), "size": 5},
+					{"path": "sub", "mode": "040000", "type": "tree", "sha": "subSHA"},
+				},
+			})
+		case r.URL.Path == "/repos/owner/repo/git/trees/subSHA" && !recursive:
+			json.NewEncoder(w).Encode(map[string]any{
+				"sha": "subSHA",
+				"tree": []map[string]any{
+					{"path": "b.md", "mode": "100644", "type": "blob", "sha": sha("docs/sub/b.md"), "size": 6},
+				},
+				"truncated": bigSub,
+			})
+		case strings.HasPrefix(r.URL.Path, "/repos/owner/repo/contents/docs/"):
+			p := strings.TrimPrefix(r.URL.Path, "/repos/owner/repo/contents/")
+			json.NewEncoder(w).Encode(map[string]any{
+				"type": "file", "path": p, "sha": sha(p), "size": len(content[p]),
+				"content": base64.StdEncoding.EncodeToString([]byte(content[p])), "encoding": "base64",
+			})
+		default:
+			t.Errorf("unexpected request: %s", r.URL)
+			w.WriteHeader(404)
+		}
+	}))
+}
+
+func TestTruncatedTree_WalksSubtrees(t *testing.T) {
+	srv := newTruncatedTreeServer(t, false)
+	defer srv.Close()
+	svc := newTestService(srv.URL)
+
+	entries, err := svc.List("", "docs", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, e := range entries {
+		paths = append(paths, e.Path)
+	}
+	if got := strings.Join(paths, ","); got != "docs/a.md,docs/sub,docs/sub/b.md" {
+		t.Errorf("listed %s", got)
+	}
+
+	e, err := svc.Stat("", "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.FileCount != 2 || e.TotalSize != 11 {
+		t.Errorf("stat: file_count=%d total_size=%d, want 2 and 11", e.FileCount, e.TotalSize)
+	}
+
+	out := filepath.Join(t.TempDir(), "docs")
+	if err := svc.Download("", "docs", out, false); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(out, "sub", "b.md")); err != nil || string(data) != "world!" {
+		t.Errorf("sub/b.md: %q, %v", data, err)
+	}
+}
+
+func TestTruncatedTree_DirectoryTooLarge(t *testing.T) {
+	srv := newTruncatedTreeServer(t, true)
+	defer srv.Close()
+	svc := newTestService(srv.URL)
+
+	for name, run := range map[string]func() error{
+		"list": func() error { _, err := svc.List("", "docs", true); return err },
+		"stat": func() error { _, err := svc.Stat("", "docs"); return err },
+		"get":  func() error { return svc.Download("", "docs", filepath.Join(t.TempDir(), "docs"), false) },
+	} {
+		err := run()
+		if ce, ok := err.(*clerrors.CLIError); !ok || ce.Cat != clerrors.CatServer {
+			t.Errorf("%s: got %v, want a server error", name, err)
+		}
+	}
+}
+
+func TestSumDirSizes(t *testing.T) {
+	entries := []Entry{
+		{Type: "file", Path: "docs/a.md", Size: 10},
+		{Type: "dir", Path: "docs/sub"},
+		{Type: "file", Path: "docs/sub/b.md", Size: 20},
+		{Type: "dir", Path: "docs/sub/deep"},
+		{Type: "symlink", Path: "docs/sub/deep/l", Size: 4},
+		{Type: "submodule", Path: "docs/sub/lib"},
+	}
+	SumDirSizes(entries)
+	if got := entries[1]; got.FileCount != 2 || got.TotalSize != 24 {
+		t.Errorf("docs/sub: got %d files, %d bytes", got.FileCount, got.TotalSize)
+	}
+	if got := entries[3]; got.FileCount != 1 || got.TotalSize != 4 {
+		t.Errorf("docs/sub/deep: got %d files, %d bytes", got.FileCount, got.TotalSize)
+	}
+}
+
+func TestFillModes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/contents/":
+			json.NewEncoder(w).Encode([]map[string]any{
+				{"type": "dir", "path": "bin", "sha": "binSHA"},
+			})
+		case "/repos/owner/repo/git/trees/binSHA":
+			if r.URL.Query().Get("recursive") != "" {
+				t.Errorf("FillModes should not list recursively")
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"sha": "binSHA",
+				"tree": []map[string]any{
+					{"path": "run", "mode": "100755", "type": "blob", "sha": "r1"},
+					{"path": "cfg", "mode": "100644", "type": "blob", "sha": "c1"},
+				},
+			})
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(404)
+		}
+	}))
+	defer srv.Close()
+
+	entries := []Entry{{Type: "file", Path: "bin/run"}, {Type: "file", Path: "bin/cfg"}}
+	if err := newTestService(srv.URL).FillModes("", "bin", entries); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entries[0].Mode != "100755" || entries[1].Mode != "100644" {
+		t.Errorf("modes: got %q, %q", entries[0].Mode, entries[1].Mode)
+	}
+}
+
 func TestList_FilePathFails(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(map[string]any{
@@ -247,7 +461,7 @@ func TestDownload_SingleFile(t *testing.T) {
 		json.NewEncoder(w).Encode(map[string]any{
 			"type":     "file",
 			"path":     "README.md",
-			"sha":      "abc123",
+			"sha":      GitBlobSHA([]byte(content)),
 			"size":     len(content),
 			"content":  encoded,
 			"encoding": "base64",
@@ -280,7 +494,7 @@ func TestDownload_OverwriteBlocked(t *testing.T) {
 		json.NewEncoder(w).Encode(map[string]any{
 			"type":     "file",
 			"path":     "README.md",
-			"sha":      "abc123",
+			"sha":      GitBlobSHA([]byte(content)),
 			"size":     len(content),
 			"content":  encoded,
 			"encoding": "base64",
@@ -318,7 +532,7 @@ func TestDownload_OverwriteAllowed(t *testing.T) {
 		json.NewEncoder(w).Encode(map[string]any{
 			"type":     "file",
 			"path":     "README.md",
-			"sha":      "abc123",
+			"sha":      GitBlobSHA([]byte(content)),
 			"size":     len(content),
 			"content":  encoded,
 			"encoding": "base64",
@@ -370,7 +584,7 @@ func TestDownload_Directory(t *testing.T) {
 			json.NewEncoder(w).Encode(map[string]any{
 				"sha": "treeSHA",
 				"tree": []map[string]any{
-					{"path": "a.md", "type": "blob", "sha": "aaa", "size": 5},
+					{"path": "a.md", "type": "blob", "sha": GitBlobSHA([]byte(fileContent)), "size": 5},
 				},
 				"truncated": false,
 			})
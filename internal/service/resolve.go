@@ -0,0 +1,213 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+// maxSymlinkHops bounds symlink resolution, mirroring the kernel's ELOOP limit.
+const maxSymlinkHops = 40
+
+// ResolveOptions selects which indirections Resolve follows.
+type ResolveOptions struct {
+	FollowSymlinks bool // resolve symlinks, including symlinked parent directories
+	IntoSubmodules bool // continue inside submodules at their pinned commit
+}
+
+// Resolve returns the service, ref and path holding the object p refers to
+// after following the indirections selected by opts. Symlinks may not point
+// outside the repository.
+func (s *RepoService) Resolve(ref, p string, opts ResolveOptions) (*RepoService, string, string, error) {
+	cur, curRef, curPath := s, ref, cleanRepoPath(p)
+	for hops := 0; hops <= maxSymlinkHops; hops++ {
+		next, nextRef, nextPath, done, err := cur.resolveStep(curRef, curPath, opts)
+		if err != nil {
+			return nil, "", "", err
+		}
+		if done {
+			return cur, curRef, curPath, nil
+		}
+		cur, curRef, curPath = next, nextRef, nextPath
+	}
+	return nil, "", "", clerrors.NewBadArgs(fmt.Sprintf("too many levels of symbolic links resolving %q", p), nil)
+}
+
+// resolveStep performs one resolution hop. done is true when p already names
+// an object that opts does not ask to look through.
+func (s *RepoService) resolveStep(ref, p string, opts ResolveOptions) (*RepoService, string, string, bool, error) {
+	item, isDir, err := s.contentsItemAt(ref, p)
+	if err == nil {
+		if isDir {
+			return nil, "", "", true, nil
+		}
+		switch item.Type {
+		case "symlink":
+			if !opts.FollowSymlinks {
+				return nil, "", "", true, nil
+			}
+			target, err := resolveLink(p, item.Target)
+			if err != nil {
+				return nil, "", "", false, err
+			}
+			return s, ref, target, false, nil
+		case "submodule":
+			if !opts.IntoSubmodules {
+				return nil, "", "", true, nil
+			}
+			sub, err := s.submoduleService(ref, item)
+			if err != nil {
+				return nil, "", "", false, err
+			}
+			return sub, item.SHA, "", false, nil
+		default:
+			return nil, "", "", true, nil
+		}
+	}
+	if !isNotFound(err) {
+		return nil, "", "", false, err
+	}
+
+	// The full path does not exist as such; a parent component may be a
+	// symlink or a submodule. Walk the prefixes to find it.
+	notFound := err
+	comps := strings.Split(p, "/")
+	for i := 1; i < len(comps); i++ {
+		prefix := strings.Join(comps[:i], "/")
+		rest := strings.Join(comps[i:], "/")
+
+		item, isDir, err := s.contentsItemAt(ref, prefix)
+		if err != nil {
+			if isNotFound(err) {
+				return nil, "", "", false, notFound
+			}
+			return nil, "", "", false, err
+		}
+		if isDir {
+			continue
+		}
+		switch item.Type {
+		case "symlink":
+			if !opts.FollowSymlinks {
+				return nil, "", "", false, clerrors.NewNotFound(fmt.Sprintf("path %q is below symlink %q (use --follow)", p, prefix), nil)
+			}
+			target, err := resolveLink(prefix, item.Target)
+			if err != nil {
+				return nil, "", "", false, err
+			}
+			return s, ref, path.Join(target, rest), false, nil
+		case "submodule":
+			if !opts.IntoSubmodules {
+				return nil, "", "", false, clerrors.NewNotFound(fmt.Sprintf("path %q is inside submodule %q (use --recurse-submodules)", p, prefix), nil)
+			}
+			sub, err := s.submoduleService(ref, item)
+			if err != nil {
+				return nil, "", "", false, err
+			}
+			return sub, item.SHA, rest, false, nil
+		default:
+			return nil, "", "", false, notFound
+		}
+	}
+	return nil, "", "", false, notFound
+}
+
+// contentsItemAt fetches the Contents API object at p. isDir is true when p
+// is a directory, in which case item is nil.
+func (s *RepoService) contentsItemAt(ref, p string) (*contentsItem, bool, error) {
+	raw, err := s.Client.GetContents(s.Owner, s.Repo, p, ref)
+	if err != nil {
+		return nil, false, err
+	}
+	var item contentsItem
+	if err := json.Unmarshal(raw, &item); err == nil && item.SHA != "" {
+		return &item, false, nil
+	}
+	var items []contentsItem
+	if err := json.Unmarshal(raw, &items); err == nil {
+		return nil, true, nil
+	}
+	return nil, false, clerrors.NewTransport("unexpected contents response format", nil)
+}
+
+// submoduleService returns a RepoService for the repository a submodule
+// item points to.
+func (s *RepoService) submoduleService(ref string, item *contentsItem) (*RepoService, error) {
+	url := item.SubmoduleURL
+	if url == "" {
+		url = s.gitmodulesURLs(ref)[item.Path]
+	}
+	host, owner, repo, ok := parseSubmoduleURL(url, s.Owner)
+	if !ok {
+		return nil, clerrors.NewBadArgs(fmt.Sprintf("cannot enter submodule %q: unsupported url %q", item.Path, url), nil)
+	}
+	if !s.sameHost(host) {
+		return nil, clerrors.NewBadArgs(fmt.Sprintf("cannot enter submodule %q: %q is on another host", item.Path, url), nil)
+	}
+	return &RepoService{Client: s.Client, Owner: owner, Repo: repo}, nil
+}
+
+// gitmodulesURLs returns the path -> url map from .gitmodules at ref, or an
+// empty map if the repository has none.
+func (s *RepoService) gitmodulesURLs(ref string) map[string]string {
+	data, err := s.ReadFile(ref, ".gitmodules")
+	if err != nil {
+		return map[string]string{}
+	}
+	return parseGitmodules(string(data))
+}
+
+// AnnotateLinks fills in symlink targets and submodule URLs for listed
+// entries, which the listing APIs do not report. Each symlink costs a blob
+// request, so callers only ask for this when the targets are shown. A target
+// that cannot be read is logged and left empty.
+func (s *RepoService) AnnotateLinks(ref string, entries []Entry) {
+	var urls map[string]string
+	for i := range entries {
+		e := &entries[i]
+		switch {
+		case e.Type == "symlink" && e.Target == "":
+			target, err := s.readSymlinkTarget(e.SHA)
+			if err != nil {
+				slog.Warn("cannot read symlink target", "path", e.Path, "err", err)
+				continue
+			}
+			e.Target = target
+		case e.Type == "submodule" && e.SubmoduleURL == "":
+			if urls == nil {
+				urls = s.gitmodulesURLs(ref)
+			}
+			e.SubmoduleURL = urls[e.Path]
+		}
+	}
+}
+
+// resolveLink resolves a symlink target relative to the link's directory
+// and rejects targets that leave the repository.
+func resolveLink(linkPath, target string) (string, error) {
+	if path.IsAbs(target) {
+		return "", clerrors.NewBadArgs(fmt.Sprintf("symlink %q points to absolute path %q", linkPath, target), nil)
+	}
+	resolved := path.Join(path.Dir(linkPath), target)
+	if resolved == ".." || strings.HasPrefix(resolved, "../") {
+		return "", clerrors.NewBadArgs(fmt.Sprintf("symlink %q points outside the repository: %q", linkPath, target), nil)
+	}
+	return cleanRepoPath(resolved), nil
+}
+
+// cleanRepoPath normalizes a repository path, mapping the root to "".
+func cleanRepoPath(p string) string {
+	p = strings.Trim(path.Clean("/"+p), "/")
+	return p
+}
+
+// isNotFound reports whether err is a CLIError in the not-found category.
+func isNotFound(err error) bool {
+	var ce *clerrors.CLIError
+	return errors.As(err, &ce) && ce.Cat == clerrors.CatNotFound
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	clerrors "githubRAGCli/internal/exitcode"
+)
+
+// newResolveServer serves a repository with a symlinked file, a symlinked
+// directory, a symlink escaping the repository, a submodule and a submodule
+// hosted elsewhere.
+func newResolveServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/contents/README.md":
+			json.NewEncoder(w).Encode(map[string]any{"type": "file", "path": "README.md", "sha": "r1"})
+		case "/repos/owner/repo/contents/docs/readme.md":
+			json.NewEncoder(w).Encode(map[string]any{"type": "symlink", "path": "docs/readme.md", "sha": "l1", "target": "../README.md"})
+		case "/repos/owner/repo/contents/docs/guide.md":
+			json.NewEncoder(w).Encode(map[string]any{"type": "file", "path": "docs/guide.md", "sha": "g1"})
+		case "/repos/owner/repo/contents/manual":
+			json.NewEncoder(w).Encode(map[string]any{"type": "symlink", "path": "manual", "sha": "l2", "target": "docs"})
+		case "/repos/owner/repo/contents/escape":
+			json.NewEncoder(w).Encode(map[string]any{"type": "symlink", "path": "escape", "sha": "l3", "target": "../outside"})
+		case "/repos/owner/repo/contents/lib":
+			json.NewEncoder(w).Encode(map[string]any{
+				"type": "submodule", "path": "lib", "sha": "c0ffee",
+				"submodule_git_url": "../../other/lib.git",
+			})
+		case "/repos/owner/repo/contents/ext":
+			json.NewEncoder(w).Encode(map[string]any{
+				"type": "submodule", "path": "ext", "sha": "c0ffee",
+				"submodule_git_url": "https://gitlab.com/other/ext.git",
+			})
+		case "/repos/other/lib/contents/src/a.go":
+			if r.URL.Query().Get("ref") != "c0ffee" {
+				t.Errorf("submodule ref: got %q, want c0ffee", r.URL.Query().Get("ref"))
+			}
+			json.NewEncoder(w).Encode(map[string]any{"type": "file", "path": "src/a.go", "sha": "a1"})
+		default:
+			w.WriteHeader(404)
+			w.Write([]byte(`{"message":"Not Found"}`))
+		}
+	}))
+}
+
+func TestResolve(t *testing.T) {
+	srv := newResolveServer(t)
+	defer srv.Close()
+	svc := newTestService(srv.URL)
+
+	all := ResolveOptions{FollowSymlinks: true, IntoSubmodules: true}
+	tests := []struct {
+		name     string
+		path     string
+		wantRepo string
+		wantRef  string
+		wantPath string
+	}{
+		{"regular file", "README.md", "repo", "", "README.md"},
+		{"symlinked file", "docs/readme.md", "repo", "", "README.md"},
+		{"symlinked parent", "manual/guide.md", "repo", "", "docs/guide.md"},
+		{"into submodule", "lib/src/a.go", "lib", "c0ffee", "src/a.go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ref, p, err := svc.Resolve("", tt.path, all)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Repo != tt.wantRepo || ref != tt.wantRef || p != tt.wantPath {
+				t.Errorf("got %s@%q:%q, want %s@%q:%q", got.Repo, ref, p, tt.wantRepo, tt.wantRef, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestResolve_Errors(t *testing.T) {
+	srv := newResolveServer(t)
+	defer srv.Close()
+	svc := newTestService(srv.URL)
+
+	tests := []struct {
+		name    string
+		path    string
+		opts    ResolveOptions
+		wantCat clerrors.Category
+	}{
+		{"escaping symlink", "escape", ResolveOptions{FollowSymlinks: true}, clerrors.CatBadArgs},
+		{"below symlink without follow", "manual/guide.md", ResolveOptions{}, clerrors.CatNotFound},
+		{"inside submodule without recursion", "lib/src/a.go", ResolveOptions{FollowSymlinks: true}, clerrors.CatNotFound},
+		{"missing", "nope/x.md", ResolveOptions{FollowSymlinks: true}, clerrors.CatNotFound},
+		{"submodule on another host", "ext/src/a.go", ResolveOptions{IntoSubmodules: true}, clerrors.CatBadArgs},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, err := svc.Resolve("", tt.path, tt.opts)
+			var ce *clerrors.CLIError
+			if !errors.As(err, &ce) || ce.Cat != tt.wantCat {
+				t.Errorf("got err %v, want category %v", err, tt.wantCat)
+			}
+		})
+	}
+}
+
+func TestResolveLink(t *testing.T) {
+	tests := []struct {
+		link, target string
+		want         string
+		wantErr      bool
+	}{
+		{"a/b", "c", "a/c", false},
+		{"a/b", "../c", "c", false},
+		{"a/b", "..", "", false},
+		{"a/b", "../../c", "", true},
+		{"a", "/etc/passwd", "", true},
+	}
+	for _, tt := range tests {
+		got, err := resolveLink(tt.link, tt.target)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("resolveLink(%q, %q): got %q, %v", tt.link, tt.target, got, err)
+		}
+	}
+}
+
+func TestAnnotateLinks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/git/blobs/l1":
+			json.NewEncoder(w).Encode(map[string]any{"sha": "l1", "content": "cnVuLnNo", "encoding": "base64"})
+		case "/repos/owner/repo/contents/.gitmodules":
+			json.NewEncoder(w).Encode(map[string]any{
+				"type": "file", "path": ".gitmodules", "sha": "m1", "encoding": "base64",
+				"content": "W3N1Ym1vZHVsZSAibGliIl0KCXBhdGggPSBsaWIKCXVybCA9IGh0dHBzOi8vZ2l0aHViLmNvbS9vdGhlci9saWIuZ2l0Cg==",
+			})
+		default:
+			w.WriteHeader(500)
+			w.Write([]byte(`{"message":"boom"}`))
+		}
+	}))
+	defer srv.Close()
+	svc := newTestService(srv.URL)
+
+	entries := []Entry{
+		{Type: "symlink", Path: "link", SHA: "l1"},
+		{Type: "symlink", Path: "broken", SHA: "l2"},
+		{Type: "submodule", Path: "lib", SHA: "c0ffee"},
+		{Type: "file", Path: "a.md", SHA: "a1"},
+	}
+	svc.AnnotateLinks("", entries)
+	if entries[0].Target != "run.sh" {
+		t.Errorf("target: got %q, want run.sh", entries[0].Target)
+	}
+	if entries[1].Target != "" {
+		t.Errorf("unreadable target: got %q, want empty", entries[1].Target)
+	}
+	if entries[2].SubmoduleURL != "https://github.com/other/lib.git" {
+		t.Errorf("submodule url: got %q", entries[2].SubmoduleURL)
+	}
+}